@@ -1,12 +1,14 @@
+import type {LocalParams} from "@/types/local-ipc.generated"
+
 export const localIpcClient = {
     folder: {
-        create(payload: { name: string; parent_id: string | null }) {
+        create(payload: LocalParams<"folder.create">) {
             return window.noteblock.local.folder.create(payload)
         },
         get(id: string) {
             return window.noteblock.local.folder.get(id)
         },
-        update(payload: { current_id: string; name?: string; parent_id?: string | null }) {
+        update(payload: LocalParams<"folder.update">) {
             return window.noteblock.local.folder.update(payload)
         },
         delete(id: string) {
@@ -14,13 +16,13 @@ export const localIpcClient = {
         },
     },
     note: {
-        create(payload: { title: string; folder_id: string }) {
+        create(payload: LocalParams<"note.create">) {
             return window.noteblock.local.note.create(payload)
         },
         get(id: string) {
             return window.noteblock.local.note.get(id)
         },
-        update(payload: { id: string; title?: string; folder_id?: string; blocks?: Array<{ id: string; index: number }> }) {
+        update(payload: LocalParams<"note.update">) {
             return window.noteblock.local.note.update(payload)
         },
         delete(id: string) {
@@ -28,10 +30,10 @@ export const localIpcClient = {
         },
     },
     block: {
-        create(noteId: string, payload: { type: "text" | "canvas" | "image"; index: number; content: unknown }) {
+        create(noteId: string, payload: Omit<LocalParams<"block.create">, "note_id">) {
             return window.noteblock.local.block.create(noteId, payload)
         },
-        update(noteId: string, blockId: string, payload: { type: "text" | "canvas" | "image"; content: unknown }) {
+        update(noteId: string, blockId: string, payload: Omit<LocalParams<"block.update">, "note_id" | "block_id">) {
             return window.noteblock.local.block.update(noteId, blockId, payload)
         },
         delete(noteId: string, blockId: string) {
@@ -39,7 +41,7 @@ export const localIpcClient = {
         },
     },
     asset: {
-        uploadImage(payload: { filename: string; data_base64: string }) {
+        uploadImage(payload: LocalParams<"asset.uploadImage">) {
             return window.noteblock.local.asset.uploadImage(payload)
         },
     },
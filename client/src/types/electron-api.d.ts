@@ -1,30 +1,33 @@
+import type {LocalParams, LocalResult} from "./local-ipc.generated"
+
 export {}
 
-type BlockType = "text" | "canvas" | "image"
+type BlockCreatePayload = Omit<LocalParams<"block.create">, "note_id">
+type BlockUpdatePayload = Omit<LocalParams<"block.update">, "note_id" | "block_id">
 
 declare global {
     interface Window {
         noteblock: {
             local: {
                 folder: {
-                    create: (payload: { name: string; parent_id: string | null }) => Promise<any>
+                    create: (payload: LocalParams<"folder.create">) => Promise<any>
                     get: (id: string) => Promise<any>
-                    update: (payload: { current_id: string; name?: string; parent_id?: string | null }) => Promise<any>
+                    update: (payload: LocalParams<"folder.update">) => Promise<any>
                     delete: (id: string) => Promise<any>
                 }
                 note: {
-                    create: (payload: { title: string; folder_id: string }) => Promise<any>
+                    create: (payload: LocalParams<"note.create">) => Promise<any>
                     get: (id: string) => Promise<any>
-                    update: (payload: { id: string; title?: string; folder_id?: string; blocks?: Array<{ id: string; index: number }> }) => Promise<any>
+                    update: (payload: LocalParams<"note.update">) => Promise<any>
                     delete: (id: string) => Promise<any>
                 }
                 block: {
-                    create: (noteId: string, payload: { type: BlockType; index: number; content: unknown }) => Promise<any>
-                    update: (noteId: string, blockId: string, payload: { type: BlockType; content: unknown }) => Promise<any>
+                    create: (noteId: string, payload: BlockCreatePayload) => Promise<any>
+                    update: (noteId: string, blockId: string, payload: BlockUpdatePayload) => Promise<any>
                     delete: (noteId: string, blockId: string) => Promise<any>
                 }
                 asset: {
-                    uploadImage: (payload: { filename: string; data_base64: string }) => Promise<{ url: string }>
+                    uploadImage: (payload: LocalParams<"asset.uploadImage">) => Promise<LocalResult<"asset.uploadImage">>
                 }
             }
         }
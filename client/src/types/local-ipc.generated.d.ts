@@ -0,0 +1,646 @@
+// Code generated by noteblock-local-service/cmd/ipcgen. DO NOT EDIT.
+
+export const LOCAL_PROTOCOL_VERSION = "1.17"
+
+export type AssetEntry = {
+    filename: string
+    height?: number
+    mime_type: string
+    modified_at: string
+    references: AssetRef[]
+    sha256: string
+    size: number
+    url: string
+    width?: number
+}
+
+export type AssetGetParams = {
+    length?: number
+    offset?: number
+    url: string
+}
+
+export type AssetGetResult = {
+    data_base64?: string
+    eof: boolean
+    filename: string
+    offset: number
+    size: number
+    total_size: number
+}
+
+export type AssetInfo = {
+    filename: string
+    height?: number
+    mime_type: string
+    modified_at: string
+    sha256: string
+    size: number
+    url: string
+    width?: number
+}
+
+export type AssetListResult = {
+    assets: AssetEntry[]
+}
+
+export type AssetRef = {
+    block_id: string
+    note_id: string
+    note_title: string
+}
+
+export type AssetRefParams = {
+    url: string
+}
+
+export type AssetUploadParams = {
+    data_base64?: string
+    filename: string
+}
+
+export type AssetUploadResult = {
+    url: string
+}
+
+export type BacklinkDTO = {
+    block_id: string
+    label: string
+    note_id: string
+    note_title: string
+    target: string
+}
+
+export type BacklinksResult = {
+    links: BacklinkDTO[]
+}
+
+export type BackupConfig = {
+    keep_daily: number
+    keep_hourly: number
+    keep_weekly: number
+    schedule: string
+}
+
+export type BackupDTO = {
+    created_at: string
+    id: string
+    images: number
+    images_size: number
+    kind: string
+    schema_version: number
+    size: number
+}
+
+export type BackupListResult = {
+    backups: BackupDTO[]
+}
+
+export type BackupRestoreDTO = {
+    restored: BackupDTO
+    safety: BackupDTO
+}
+
+export type BackupRestoreParams = {
+    id: string
+}
+
+export type BlockCreateParams = {
+    content: unknown
+    index: number
+    note_id: string
+    type: string
+}
+
+export type BlockDTO = {
+    content: unknown
+    created_at: string
+    embed?: EmbedDTO | null
+    id: string
+    index: number
+    order_key: string
+    type: string
+    updated_at: string
+}
+
+export type BlockDeleteResult = {
+    orphaned_embeds: number
+}
+
+export type BlockMoveParams = {
+    after_id?: string
+    before_id?: string
+    block_id: string
+    note_id: string
+    target_note_id?: string
+}
+
+export type BlockOrder = {
+    id: string
+    index: number
+}
+
+export type BlockRefParams = {
+    block_id: string
+    note_id: string
+}
+
+export type BlockReferenceDTO = {
+    block_id: string
+    note_id: string
+    note_title: string
+}
+
+export type BlockReferencesParams = {
+    block_id: string
+}
+
+export type BlockReferencesResult = {
+    references: BlockReferenceDTO[]
+}
+
+export type BlockResult = {
+    id: string
+    index: number
+    note_id: string
+    order_key: string
+    type: string
+}
+
+export type BlockUpdateParams = {
+    block_id: string
+    content: unknown
+    note_id: string
+    type: string
+}
+
+export type ChangeDTO = {
+    action: string
+    at: string
+    data: unknown
+    entity: string
+    entity_id: string
+    note_id: string
+    seq: number
+}
+
+export type ChangesListParams = {
+    limit?: number
+    since?: number
+}
+
+export type ChangesListResult = {
+    changes: ChangeDTO[]
+    last_seq: number
+}
+
+export type Description = {
+    $defs: Record<string, Record<string, unknown>>
+    methods: MethodDescription[]
+    protocol_version: string
+}
+
+export type EmbedDTO = {
+    block?: BlockDTO | null
+    block_id: string
+    note_id?: string
+    note_title?: string
+    status: string
+}
+
+export type EmptyParams = Record<string, never>
+
+export type FolderChild = {
+    child_count: number
+    id: string
+    name: string
+    note_count: number
+    parent_id?: string | null
+}
+
+export type FolderChildren = {
+    children: FolderChild[]
+    id: string
+    name: string
+    notes: NoteResponse[]
+    parent_id?: string | null
+    sort_by: string
+    sort_desc: boolean
+}
+
+export type FolderCreateParams = {
+    name?: string | null
+    parent_id?: string | null
+}
+
+export type FolderDeleteResult = {
+    id: string
+    message: string
+}
+
+export type FolderDuplicateParams = {
+    id: string
+    parent_id?: string | null
+}
+
+export type FolderMoveParams = {
+    folder_ids?: string[]
+    note_ids?: string[]
+    target_id: string
+}
+
+export type FolderReorderParams = {
+    folder_ids?: string[]
+    id: string
+    note_ids?: string[]
+}
+
+export type FolderResponse = {
+    children: FolderResponse[]
+    id: string
+    name: string
+    notes: NoteResponse[]
+    parent_id?: string | null
+    sort_by: string
+    sort_desc: boolean
+}
+
+export type FolderResult = {
+    id: string
+    name: string
+    parent_id?: string | null
+}
+
+export type FolderSortParams = {
+    id: string
+    sort_by: string
+    sort_desc?: boolean
+}
+
+export type FolderUpdateParams = {
+    current_id?: string | null
+    name?: string | null
+    parent_id?: string | null
+}
+
+export type Graph = {
+    edges: GraphEdge[]
+    nodes: GraphNode[]
+}
+
+export type GraphEdge = {
+    kind: string
+    source: string
+    target: string
+    weight: number
+}
+
+export type GraphGetParams = {
+    depth?: number
+    folder_id?: string
+    hide_orphans?: boolean
+    include_folders?: boolean
+    include_tags?: boolean
+    note_id?: string
+    tags?: string[]
+}
+
+export type GraphNode = {
+    degree: number
+    distance?: number | null
+    folder_id?: string | null
+    id: string
+    in_degree: number
+    kind: string
+    label: string
+    out_degree: number
+}
+
+export type IdParams = {
+    id: string
+}
+
+export type Info = {
+    commit?: string
+    go_version: string
+    name: string
+    version: string
+}
+
+export type InitializeParams = {
+    client_name?: string
+    client_version?: string
+    framing?: string[]
+    protocol_version?: string
+    token?: string
+}
+
+export type InitializeResult = {
+    capabilities: string[]
+    framing: string
+    methods: string[]
+    protocol_version: string
+    schema_version: number
+    server: Info
+}
+
+export type JournalConfig = {
+    folder_id: string
+    template_id: string
+    title_format: string
+}
+
+export type JournalConfigureParams = {
+    folder_id?: string | null
+    template_id?: string | null
+    title_format?: string | null
+}
+
+export type JournalDayResult = {
+    created: boolean
+    date: string
+    note: NoteDTO
+}
+
+export type JournalEntryDTO = {
+    date: string
+    note_id: string
+    title: string
+}
+
+export type JournalGetParams = {
+    date: string
+}
+
+export type JournalRangeParams = {
+    month: string
+}
+
+export type JournalRangeResult = {
+    entries: JournalEntryDTO[]
+}
+
+export type JournalTodayParams = {
+    timezone?: string
+}
+
+export type LinkDTO = {
+    block_id: string
+    label: string
+    resolved: boolean
+    target: string
+    target_note_id?: string | null
+    target_title?: string | null
+}
+
+export type MessageResult = {
+    message: string
+}
+
+export type MethodDescription = {
+    name: string
+    params: Record<string, unknown>
+    result: Record<string, unknown>
+}
+
+export type NoteCreateParams = {
+    folder_id?: string | null
+    title?: string | null
+}
+
+export type NoteDTO = {
+    blocks: BlockDTO[]
+    folder_id: string
+    id: string
+    is_template: boolean
+    tags: string[]
+    title: string
+}
+
+export type NoteDuplicateParams = {
+    folder_id?: string | null
+    id: string
+}
+
+export type NoteFromTemplateParams = {
+    folder_id?: string | null
+    template_id: string
+    title?: string | null
+    variables?: Record<string, string>
+}
+
+export type NotePlaceParams = {
+    folder_id: string
+    id: string
+}
+
+export type NoteResponse = {
+    id: string
+    primary: boolean
+    title: string
+}
+
+export type NoteResult = {
+    folder_id: string
+    id: string
+    title: string
+}
+
+export type NoteTagDTO = {
+    name: string
+    source: string
+}
+
+export type NoteTagsParams = {
+    id: string
+    tags: string[]
+}
+
+export type NoteTagsResult = {
+    tags: NoteTagDTO[]
+}
+
+export type NoteUpdateParams = {
+    blocks?: BlockOrder[] | null
+    folder_id?: string | null
+    id: string
+    title?: string | null
+}
+
+export type NoteUpdateResult = {
+    folder_id: string
+    id: string
+    message: string
+    title: string
+}
+
+export type NotesByTagsParams = {
+    limit?: number
+    mode?: string
+    tags: string[]
+}
+
+export type NotesByTagsResult = {
+    notes: TaggedNoteDTO[]
+}
+
+export type OutgoingLinksResult = {
+    links: LinkDTO[]
+}
+
+export type PlacementDTO = {
+    folder_id: string
+    folder_name: string
+    primary: boolean
+}
+
+export type PlacementsResult = {
+    placements: PlacementDTO[]
+}
+
+export type TagConfig = {
+    extract_inline: boolean
+}
+
+export type TagDTO = {
+    count: number
+    id: string
+    name: string
+}
+
+export type TagListResult = {
+    tags: TagDTO[]
+}
+
+export type TagMergeParams = {
+    sources: string[]
+    target: string
+}
+
+export type TagRenameParams = {
+    name: string
+    new_name: string
+}
+
+export type TaggedNoteDTO = {
+    folder_id: string
+    id: string
+    tags: string[]
+    title: string
+}
+
+export type TemplateDTO = {
+    block_count: number
+    folder_id: string
+    id: string
+    title: string
+    updated_at: string
+    variables: string[]
+}
+
+export type TemplateListResult = {
+    templates: TemplateDTO[]
+}
+
+export type TemplateSetParams = {
+    id: string
+    is_template: boolean
+}
+
+export type TemplateSetResult = {
+    folder_id: string
+    id: string
+    is_template: boolean
+    title: string
+}
+
+export type UnresolvedLinkDTO = {
+    note_ids: string[]
+    target: string
+}
+
+export type UnresolvedLinksResult = {
+    links: UnresolvedLinkDTO[]
+}
+
+export type VaultCheckDTO = {
+    findings: VaultFinding[]
+    ok: boolean
+    recovered_folder_id?: string | null
+    repaired: boolean
+}
+
+export type VaultCheckParams = {
+    repair: boolean
+}
+
+export type VaultFinding = {
+    detail: string
+    id?: string
+    kind: string
+    repair?: string
+}
+
+export interface LocalMethods {
+    "asset.get": { params: AssetGetParams; result: AssetGetResult }
+    "asset.list": { params: EmptyParams; result: AssetListResult }
+    "asset.stat": { params: AssetRefParams; result: AssetInfo }
+    "asset.uploadImage": { params: AssetUploadParams; result: AssetUploadResult }
+    "backup.config": { params: EmptyParams; result: BackupConfig }
+    "backup.configure": { params: BackupConfig; result: BackupConfig }
+    "backup.create": { params: EmptyParams; result: BackupDTO }
+    "backup.list": { params: EmptyParams; result: BackupListResult }
+    "backup.restore": { params: BackupRestoreParams; result: BackupRestoreDTO }
+    "block.copy": { params: BlockMoveParams; result: BlockResult }
+    "block.create": { params: BlockCreateParams; result: BlockResult }
+    "block.delete": { params: BlockRefParams; result: BlockDeleteResult }
+    "block.move": { params: BlockMoveParams; result: BlockResult }
+    "block.references": { params: BlockReferencesParams; result: BlockReferencesResult }
+    "block.update": { params: BlockUpdateParams; result: BlockResult }
+    "changes.list": { params: ChangesListParams; result: ChangesListResult }
+    "folder.children": { params: IdParams; result: FolderChildren }
+    "folder.create": { params: FolderCreateParams; result: FolderResult }
+    "folder.delete": { params: IdParams; result: FolderDeleteResult }
+    "folder.duplicate": { params: FolderDuplicateParams; result: FolderResponse }
+    "folder.get": { params: IdParams; result: FolderResponse }
+    "folder.move": { params: FolderMoveParams; result: FolderChildren }
+    "folder.reorder": { params: FolderReorderParams; result: FolderChildren }
+    "folder.sort": { params: FolderSortParams; result: FolderChildren }
+    "folder.update": { params: FolderUpdateParams; result: FolderResult }
+    "graph.get": { params: GraphGetParams; result: Graph }
+    "initialize": { params: InitializeParams; result: InitializeResult }
+    "journal.config": { params: EmptyParams; result: JournalConfig }
+    "journal.configure": { params: JournalConfigureParams; result: JournalConfig }
+    "journal.get": { params: JournalGetParams; result: JournalDayResult }
+    "journal.range": { params: JournalRangeParams; result: JournalRangeResult }
+    "journal.today": { params: JournalTodayParams; result: JournalDayResult }
+    "link.unresolved": { params: EmptyParams; result: UnresolvedLinksResult }
+    "note.backlinks": { params: IdParams; result: BacklinksResult }
+    "note.create": { params: NoteCreateParams; result: NoteResult }
+    "note.createFromTemplate": { params: NoteFromTemplateParams; result: NoteDTO }
+    "note.delete": { params: IdParams; result: MessageResult }
+    "note.duplicate": { params: NoteDuplicateParams; result: NoteDTO }
+    "note.get": { params: IdParams; result: NoteDTO }
+    "note.listByTags": { params: NotesByTagsParams; result: NotesByTagsResult }
+    "note.outgoingLinks": { params: IdParams; result: OutgoingLinksResult }
+    "note.place": { params: NotePlaceParams; result: PlacementsResult }
+    "note.placements": { params: IdParams; result: PlacementsResult }
+    "note.tags.add": { params: NoteTagsParams; result: NoteTagsResult }
+    "note.tags.remove": { params: NoteTagsParams; result: NoteTagsResult }
+    "note.tags.set": { params: NoteTagsParams; result: NoteTagsResult }
+    "note.unplace": { params: NotePlaceParams; result: PlacementsResult }
+    "note.update": { params: NoteUpdateParams; result: NoteUpdateResult }
+    "rpc.describe": { params: EmptyParams; result: Description }
+    "tag.config": { params: EmptyParams; result: TagConfig }
+    "tag.configure": { params: TagConfig; result: TagConfig }
+    "tag.list": { params: EmptyParams; result: TagListResult }
+    "tag.merge": { params: TagMergeParams; result: TagDTO }
+    "tag.rename": { params: TagRenameParams; result: TagDTO }
+    "template.list": { params: EmptyParams; result: TemplateListResult }
+    "template.set": { params: TemplateSetParams; result: TemplateSetResult }
+    "vault.check": { params: VaultCheckParams; result: VaultCheckDTO }
+}
+
+export type LocalMethod = keyof LocalMethods
+export type LocalParams<M extends LocalMethod> = LocalMethods[M]["params"]
+export type LocalResult<M extends LocalMethod> = LocalMethods[M]["result"]
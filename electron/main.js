@@ -4,8 +4,16 @@ const { randomUUID } = require("crypto")
 const path = require("path")
 const { pathToFileURL } = require("url")
 
+// Must have the same major version as ipc.ProtocolVersion in noteblock-local-service,
+// and a minor version no higher than the server's.
+const LOCAL_PROTOCOL_VERSION = "1.17"
+
 let goProcess
-let responseBuffer = ""
+let responseBuffer = Buffer.alloc(0)
+// "json" until initialize negotiates "binary"; see ipc/framing.go for the frame layout.
+let framing = "json"
+let backendReady = null
+let initializeRequestId = null
 const pendingRequests = new Map()
 
 class BackendIPCError extends Error {
@@ -16,6 +24,24 @@ class BackendIPCError extends Error {
     }
 }
 
+function handleBackendMessage(payload, body) {
+    const pending = pendingRequests.get(payload.id)
+    if (!pending) return
+
+    pendingRequests.delete(payload.id)
+    if (payload.id === initializeRequestId && payload.result) {
+        // switch before reading on: the server frames everything after this response
+        framing = payload.result.framing || "json"
+    }
+    if (payload.error) {
+        pending.reject(new BackendIPCError(payload.error.message || "IPC request failed", payload.error.code))
+    } else if (body && payload.result) {
+        pending.resolve({ ...payload.result, data: new Uint8Array(body) })
+    } else {
+        pending.resolve(payload.result)
+    }
+}
+
 function parseBackendLine(rawLine) {
     const line = rawLine.trim()
     if (!line) return
@@ -28,35 +54,61 @@ function parseBackendLine(rawLine) {
         console.error("Failed to parse backend response line:", err)
         return
     }
+    handleBackendMessage(payload)
+}
 
-    const pending = pendingRequests.get(payload.id)
-    if (!pending) return
+// readBackendMessage consumes one message from responseBuffer, returning false
+// when it does not hold a complete one yet.
+function readBackendMessage() {
+    if (framing === "binary") {
+        if (responseBuffer.length < 8) return false
+        const headerLength = responseBuffer.readUInt32BE(0)
+        const bodyLength = responseBuffer.readUInt32BE(4)
+        const frameLength = 8 + headerLength + bodyLength
+        if (responseBuffer.length < frameLength) return false
 
-    pendingRequests.delete(payload.id)
-    if (payload.error) {
-        pending.reject(new BackendIPCError(payload.error.message || "IPC request failed", payload.error.code))
-    } else {
-        pending.resolve(payload.result)
+        const header = responseBuffer.subarray(8, 8 + headerLength).toString("utf8")
+        const body = bodyLength > 0 ? Buffer.from(responseBuffer.subarray(8 + headerLength, frameLength)) : null
+        responseBuffer = responseBuffer.subarray(frameLength)
+        try {
+            handleBackendMessage(JSON.parse(header), body)
+        } catch (err) {
+            console.error("Failed to parse backend frame header:", err)
+        }
+        return true
     }
+
+    const newlineIndex = responseBuffer.indexOf(0x0a)
+    if (newlineIndex < 0) return false
+    const line = responseBuffer.subarray(0, newlineIndex).toString("utf8")
+    responseBuffer = responseBuffer.subarray(newlineIndex + 1)
+    parseBackendLine(line)
+    return true
 }
 
 function wireBackendStdout() {
     if (!goProcess || !goProcess.stdout) return
 
-    goProcess.stdout.setEncoding("utf8")
     goProcess.stdout.on("data", (chunk) => {
-        responseBuffer += chunk
-
-        let newlineIndex = responseBuffer.indexOf("\n")
-        while (newlineIndex >= 0) {
-            const line = responseBuffer.slice(0, newlineIndex)
-            responseBuffer = responseBuffer.slice(newlineIndex + 1)
-            parseBackendLine(line)
-            newlineIndex = responseBuffer.indexOf("\n")
+        responseBuffer = Buffer.concat([responseBuffer, chunk])
+        while (readBackendMessage()) {
+            // keep draining complete messages
         }
     })
 }
 
+function writeBackendMessage(request, body) {
+    if (framing !== "binary") {
+        goProcess.stdin.write(`${JSON.stringify(request)}\n`)
+        return
+    }
+    const header = Buffer.from(JSON.stringify(request), "utf8")
+    const lengths = Buffer.alloc(8)
+    lengths.writeUInt32BE(header.length, 0)
+    lengths.writeUInt32BE(body ? body.length : 0, 4)
+    goProcess.stdin.write(body ? Buffer.concat([lengths, header, body]) : Buffer.concat([lengths, header]))
+}
+
 function startBackendProcess() {
     const isWin = process.platform === "win32"
     const backendFile = isWin ? "noteblock-server.exe" : "noteblock-server"
@@ -94,13 +146,16 @@ function startBackendProcess() {
     wireBackendStdout()
 }
 
-function sendBackendRequest(method, params) {
+function sendBackendRequest(method, params, body) {
     if (!goProcess || goProcess.killed || !goProcess.stdin) {
         throw new Error("Local backend process is not running")
     }
 
     const id = randomUUID()
     const request = { id, method, params }
+    if (method === "initialize") {
+        initializeRequestId = id
+    }
 
     return new Promise((resolve, reject) => {
         const timeout = setTimeout(() => {
@@ -119,10 +174,38 @@ function sendBackendRequest(method, params) {
             }
         })
 
-        goProcess.stdin.write(`${JSON.stringify(request)}\n`)
+        writeBackendMessage(request, body)
     })
 }
 
+// callBackend waits for the handshake, since nothing may be written while the
+// framing is being switched, and moves upload bytes out of base64 on binary sessions.
+async function callBackend(method, params) {
+    await backendReady
+    if (framing === "binary" && method === "asset.uploadImage" && params && params.data_base64) {
+        const { data_base64: dataBase64, ...rest } = params
+        return sendBackendRequest(method, rest, Buffer.from(dataBase64, "base64"))
+    }
+    return sendBackendRequest(method, params)
+}
+
+async function initializeBackend() {
+    try {
+        const info = await sendBackendRequest("initialize", {
+            protocol_version: LOCAL_PROTOCOL_VERSION,
+            client_name: "noteblock-electron",
+            client_version: app.getVersion(),
+            framing: ["binary"],
+        })
+        console.log(`[local-backend] ${info.server.name} ${info.server.version} (protocol ${info.protocol_version}, schema ${info.schema_version})`)
+    } catch (err) {
+        console.error("Local backend is incompatible with this app:", err.message)
+        if (goProcess && !goProcess.killed) {
+            goProcess.kill()
+        }
+    }
+}
+
 function registerLocalImageProtocol() {
     protocol.handle("noteblock-image", async (request) => {
         const url = new URL(request.url)
@@ -144,7 +227,7 @@ function registerRendererHandlers() {
             throw new Error("Invalid local IPC payload")
         }
         try {
-            return await sendBackendRequest(payload.method, payload.params || {})
+            return await callBackend(payload.method, payload.params || {})
         } catch (err) {
             // Expected during debounced autosave when a block is already deleted.
             if (payload.method === "block.update" && err && err.code === "NOT_FOUND") {
@@ -187,6 +270,7 @@ function createWindow() {
 app.whenReady().then(() => {
     registerLocalImageProtocol()
     startBackendProcess()
+    backendReady = initializeBackend()
     registerRendererHandlers()
     createWindow()
 })
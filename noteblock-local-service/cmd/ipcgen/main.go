@@ -0,0 +1,141 @@
+// Command ipcgen emits TypeScript definitions for the local IPC protocol from
+// the same handler table the server dispatches on, so the renderer's
+// LocalIpcClient is type-checked against what the Go side actually accepts.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"server/internal/ipc"
+	"server/internal/jsonschema"
+)
+
+func main() {
+	out := flag.String("out", "", "file to write (defaults to stdout)")
+	flag.Parse()
+
+	src := render(ipc.Describe())
+	if *out == "" {
+		fmt.Print(src)
+		return
+	}
+	if err := os.WriteFile(*out, []byte(src), 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+}
+
+func render(desc ipc.Description) string {
+	var b bytes.Buffer
+	b.WriteString("// Code generated by noteblock-local-service/cmd/ipcgen. DO NOT EDIT.\n")
+	fmt.Fprintf(&b, "\nexport const LOCAL_PROTOCOL_VERSION = %q\n", desc.ProtocolVersion)
+
+	names := make([]string, 0, len(desc.Defs))
+	for name := range desc.Defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "\nexport type %s = %s\n", name, tsObject(desc.Defs[name], ""))
+	}
+
+	b.WriteString("\nexport interface LocalMethods {\n")
+	for _, m := range desc.Methods {
+		fmt.Fprintf(&b, "    %q: { params: %s; result: %s }\n", m.Name, tsType(m.Params, "    "), tsType(m.Result, "    "))
+	}
+	b.WriteString("}\n")
+	b.WriteString("\nexport type LocalMethod = keyof LocalMethods\n")
+	b.WriteString("export type LocalParams<M extends LocalMethod> = LocalMethods[M][\"params\"]\n")
+	b.WriteString("export type LocalResult<M extends LocalMethod> = LocalMethods[M][\"result\"]\n")
+	return b.String()
+}
+
+func tsType(schema jsonschema.Schema, indent string) string {
+	if ref, ok := schema["$ref"].(string); ok {
+		return strings.TrimPrefix(ref, "#/$defs/")
+	}
+	if anyOf, ok := schema["anyOf"].([]jsonschema.Schema); ok {
+		parts := make([]string, 0, len(anyOf))
+		for _, s := range anyOf {
+			parts = append(parts, tsType(s, indent))
+		}
+		return strings.Join(parts, " | ")
+	}
+
+	switch typ := schema["type"].(type) {
+	case []string:
+		parts := make([]string, 0, len(typ))
+		for _, t := range typ {
+			narrowed := jsonschema.Schema{}
+			for k, v := range schema {
+				narrowed[k] = v
+			}
+			narrowed["type"] = t
+			parts = append(parts, tsType(narrowed, indent))
+		}
+		return strings.Join(parts, " | ")
+	case string:
+		switch typ {
+		case "string":
+			return "string"
+		case "integer", "number":
+			return "number"
+		case "boolean":
+			return "boolean"
+		case "null":
+			return "null"
+		case "array":
+			item := tsType(schema["items"].(jsonschema.Schema), indent)
+			if strings.ContainsAny(item, " |") {
+				return "Array<" + item + ">"
+			}
+			return item + "[]"
+		case "object":
+			return tsObject(schema, indent)
+		}
+	}
+	return "unknown"
+}
+
+func tsObject(schema jsonschema.Schema, indent string) string {
+	properties, _ := schema["properties"].(jsonschema.Schema)
+	if properties == nil {
+		if extra, ok := schema["additionalProperties"].(jsonschema.Schema); ok {
+			return "Record<string, " + tsType(extra, indent) + ">"
+		}
+		return "Record<string, unknown>"
+	}
+	if len(properties) == 0 {
+		return "Record<string, never>"
+	}
+
+	required := map[string]bool{}
+	if list, ok := schema["required"].([]string); ok {
+		for _, name := range list {
+			required[name] = true
+		}
+	}
+
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString("{\n")
+	for _, key := range keys {
+		optional := ""
+		if !required[key] {
+			optional = "?"
+		}
+		fmt.Fprintf(&b, "%s    %s%s: %s\n", indent, key, optional, tsType(properties[key].(jsonschema.Schema), indent+"    "))
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
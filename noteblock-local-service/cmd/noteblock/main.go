@@ -1,20 +1,170 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
+	"server/internal/api"
+	"server/internal/auth"
+	"server/internal/cli"
+	"server/internal/command"
 	"server/internal/db"
 	"server/internal/ipc"
+	"server/internal/model/dto"
+	"server/internal/routes"
 	"server/internal/service"
+	"strings"
+	"time"
+	// journal.today resolves IANA zones, which Windows does not ship
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
 )
 
+const httpTokenFileName = "http.token"
+
+// backupCheckInterval is how often the server looks for a scheduled backup
+// that is due; the finest schedule is hourly.
+const backupCheckInterval = 5 * time.Minute
+
 func main() {
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
+	socket := flag.Bool("socket", os.Getenv("NOTEBLOCK_SOCKET") == "1", "also accept clients on a unix socket in the data dir")
+	httpEnabled := flag.Bool("http", os.Getenv("NOTEBLOCK_HTTP") == "1", "also serve the HTTP API on localhost")
+	httpAddr := flag.String("http-addr", "127.0.0.1:7474", "loopback address for the HTTP API")
+	httpOrigins := flag.String("http-origins", os.Getenv("NOTEBLOCK_HTTP_ORIGINS"), "comma separated browser origins allowed to call the HTTP API")
+	record := flag.Bool("record", os.Getenv("NOTEBLOCK_RECORD") == "1", "record ipc traffic to the recordings dir for noteblock replay")
+	recordRedact := flag.Bool("record-redact", os.Getenv("NOTEBLOCK_RECORD_REDACT") == "1", "leave block content and image data out of recordings")
+	flag.Parse()
+
 	dbConn := db.InitDb()
+	dataDir := db.DataDir()
+
+	cmds := command.New(dbConn)
+	if err := cmds.IndexLinks(); err != nil {
+		log.Fatalf("failed to index links: %v", err)
+	}
+	server := ipc.NewServer(cmds)
+
+	if *record {
+		recorder, err := ipc.NewRecorder(filepath.Join(dataDir, ipc.RecordingsDirName), ipc.RecorderOptions{Redact: *recordRedact})
+		if err != nil {
+			log.Fatalf("failed to start recording: %v", err)
+		}
+		defer recorder.Close()
+		server.SetRecorder(recorder)
+		log.Println("Recording ipc traffic to:", recorder.Path())
+	}
+
+	if *socket {
+		if err := server.WriteToken(filepath.Join(dataDir, ipc.TokenFileName)); err != nil {
+			log.Fatalf("failed to write ipc token: %v", err)
+		}
+		socketPath := filepath.Join(dataDir, ipc.SocketFileName)
+		ln, err := ipc.ListenUnix(socketPath)
+		if err != nil {
+			log.Fatalf("failed to listen on %s: %v", socketPath, err)
+		}
+		defer os.Remove(socketPath)
+		defer ln.Close()
+		log.Println("Accepting socket clients at:", socketPath)
+
+		go func() {
+			if err := server.ServeListener(ln); err != nil {
+				log.Println("socket listener stopped:", err)
+			}
+		}()
+	}
 
-	// services
-	nSvc := &service.NoteService{DB: dbConn}
-	fSvc := &service.FolderService{DB: dbConn, NoteService: nSvc}
-	bSvc := &service.BlockService{DB: dbConn}
+	if *httpEnabled {
+		if err := checkLoopback(*httpAddr); err != nil {
+			log.Fatalf("refusing to serve HTTP API: %v", err)
+		}
+		token, err := auth.GenerateTokenFile(filepath.Join(dataDir, httpTokenFileName))
+		if err != nil {
+			log.Fatalf("failed to write http token: %v", err)
+		}
 
-	server := ipc.NewServer(nSvc, fSvc, bSvc)
+		gin.SetMode(gin.ReleaseMode)
+		router := routes.Setup(routes.Config{
+			Token:        token,
+			AllowOrigins: splitOrigins(*httpOrigins),
+			ImagesDir:    service.ImagesDir(),
+		},
+			&api.FolderHandler{Cmds: cmds},
+			&api.NoteHandler{Cmds: cmds},
+			&api.BlockHandler{Cmds: cmds},
+			&api.RPCHandler{Server: server},
+		)
+		log.Println("Serving HTTP API at:", *httpAddr)
+
+		go func() {
+			if err := http.ListenAndServe(*httpAddr, router); err != nil {
+				log.Println("http server stopped:", err)
+			}
+		}()
+	}
+
+	// started once the socket is bound; see ipc.ListenUnix
+	go runBackupSchedule(cmds, server)
 	_ = server.Run(os.Stdin, os.Stdout)
 }
+
+// runBackupSchedule takes the scheduled backups for as long as the server
+// runs, starting with one that fell due while it was not running. Each check
+// runs under the dispatch lock, so it never lands in the middle of an IPC
+// request such as backup.restore.
+func runBackupSchedule(cmds *command.Commands, server *ipc.Server) {
+	check := func(now time.Time) {
+		var (
+			backup *dto.BackupDTO
+			err    error
+		)
+		server.Exclusive(func() { backup, err = cmds.RunScheduledBackup(now) })
+		if err != nil {
+			log.Println("scheduled backup failed:", err)
+		} else if backup != nil {
+			log.Println("Took scheduled backup:", backup.ID)
+		}
+	}
+	check(time.Now())
+	for now := range time.Tick(backupCheckInterval) {
+		check(now)
+	}
+}
+
+// checkLoopback keeps the API off the network; the bearer token is the only
+// thing protecting the vault and it is not meant to cross machines.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+		return fmt.Errorf("%s is not a loopback address", addr)
+	}
+	return nil
+}
+
+func splitOrigins(value string) []string {
+	var origins []string
+	for _, origin := range strings.Split(value, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
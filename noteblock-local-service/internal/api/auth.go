@@ -0,0 +1,25 @@
+package api
+
+import (
+	"net/http"
+	"server/internal/auth"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BearerAuth rejects requests that do not carry the token from the data dir.
+// Image requests may pass it as ?access_token= since <img> cannot set headers.
+func BearerAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if given == "" && c.Request.Method == http.MethodGet {
+			given = c.Query("access_token")
+		}
+		if !auth.TokensEqual(token, given) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid bearer token"})
+			return
+		}
+		c.Next()
+	}
+}
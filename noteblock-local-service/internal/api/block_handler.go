@@ -3,11 +3,13 @@ package api
 import (
 	"encoding/json"
 	"github.com/gin-gonic/gin"
-	"server/internal/service"
+	"io"
+	"server/internal/command"
+	"strings"
 )
 
 type BlockHandler struct {
-	Svc *service.BlockService
+	Cmds *command.Commands
 }
 
 func (b *BlockHandler) UploadImage(c *gin.Context) {
@@ -16,19 +18,32 @@ func (b *BlockHandler) UploadImage(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "No image is received: " + err.Error()})
 		return
 	}
+	src, err := file.Open()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to read image: " + err.Error()})
+		return
+	}
+	defer src.Close()
+	data, err := io.ReadAll(src)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Failed to read image: " + err.Error()})
+		return
+	}
 
-	publicPath, err := b.Svc.SaveImage(file)
+	publicPath, err := b.Cmds.UploadImage(file.Filename, data)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to save image: " + err.Error()})
+		WriteCommandError(c, err)
 		return
 	}
 
-	// TODO: instead of localhost, use unix socket or env variable for domain of server (dev, staging, prod)
-	c.JSON(200, gin.H{"url": "http://localhost:7474" + publicPath})
+	// publicPath is the noteblock-image:/// url stored in blocks; http_url is where this API serves it
+	c.JSON(200, gin.H{
+		"url":      publicPath,
+		"http_url": "http://" + c.Request.Host + "/uploads/images/" + strings.TrimPrefix(publicPath, "noteblock-image:///"),
+	})
 }
 
 func (b *BlockHandler) Create(c *gin.Context) {
-	noteId := c.Param("id")
 	var body struct {
 		Type    string           `json:"type"`
 		Index   int              `json:"index"`
@@ -39,21 +54,17 @@ func (b *BlockHandler) Create(c *gin.Context) {
 		return
 	}
 
-	block, err := b.Svc.CreateNewBlock(noteId, body.Type, body.Index, body.Content)
+	block, err := b.Cmds.CreateBlock(c.Param("id"), body.Type, body.Index, body.Content)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to create block: " + err.Error()})
+		WriteCommandError(c, err)
 		return
 	}
 
-	c.JSON(201, gin.H{"id": block.ID, "note_id": block.NoteID, "type": block.Type, "index": block.Index})
+	c.JSON(201, gin.H{"id": block.ID, "note_id": block.NoteID, "type": block.Type, "index": block.Index, "order_key": block.OrderKey})
 }
 
-// TODO: updating order of blocks given one is moved or removed should be handled by the client which will use a list to represent blocks, and then send the new order to the noteblock-local-service with the natural indexes of the list
-
 // TODO: Update content specifically updates one of two things: either changes the content of the block and not the type, or changes both type and content at the same time.
 func (b *BlockHandler) UpdateContent(c *gin.Context) {
-	noteId := c.Param("id")
-	blockId := c.Param("block_id")
 	var body struct {
 		Type    string           `json:"type"`
 		Content *json.RawMessage `json:"content"`
@@ -63,22 +74,56 @@ func (b *BlockHandler) UpdateContent(c *gin.Context) {
 		return
 	}
 
-	block, err := b.Svc.UpdateBlockContent(noteId, blockId, body.Type, body.Content)
+	block, err := b.Cmds.UpdateBlock(c.Param("id"), c.Param("block_id"), body.Type, body.Content)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to update block: " + err.Error()})
+		WriteCommandError(c, err)
 		return
 	}
 
-	c.JSON(200, gin.H{"id": block.ID, "note_id": block.NoteID, "type": block.Type, "index": block.Index})
+	c.JSON(200, gin.H{"id": block.ID, "note_id": block.NoteID, "type": block.Type, "index": block.Index, "order_key": block.OrderKey})
 }
 
-func (b *BlockHandler) Delete(c *gin.Context) {
-	noteId := c.Param("id")
-	blockId := c.Param("block_id")
+type placeBlockBody struct {
+	TargetNoteID string `json:"target_note_id"`
+	AfterID      string `json:"after_id"`
+	BeforeID     string `json:"before_id"`
+}
+
+func (b *BlockHandler) Move(c *gin.Context) {
+	var body placeBlockBody
+	err := ValidateAndSetJsonBody(&body, c)
+	if err != nil {
+		return
+	}
 
-	err := b.Svc.DeleteBlock(noteId, blockId)
+	block, err := b.Cmds.MoveBlock(c.Param("id"), c.Param("block_id"), body.TargetNoteID, body.AfterID, body.BeforeID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "Failed to delete block: " + err.Error()})
+		WriteCommandError(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{"id": block.ID, "note_id": block.NoteID, "type": block.Type, "index": block.Index, "order_key": block.OrderKey})
+}
+
+func (b *BlockHandler) Copy(c *gin.Context) {
+	var body placeBlockBody
+	err := ValidateAndSetJsonBody(&body, c)
+	if err != nil {
+		return
+	}
+
+	block, err := b.Cmds.CopyBlock(c.Param("id"), c.Param("block_id"), body.TargetNoteID, body.AfterID, body.BeforeID)
+	if err != nil {
+		WriteCommandError(c, err)
+		return
+	}
+
+	c.JSON(201, gin.H{"id": block.ID, "note_id": block.NoteID, "type": block.Type, "index": block.Index, "order_key": block.OrderKey})
+}
+
+func (b *BlockHandler) Delete(c *gin.Context) {
+	if _, err := b.Cmds.DeleteBlock(c.Param("id"), c.Param("block_id")); err != nil {
+		WriteCommandError(c, err)
 		return
 	}
 
@@ -1,17 +1,14 @@
 package api
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"regexp"
+	"server/internal/command"
 	"server/internal/model"
 	"server/internal/model/dto"
-	"server/internal/service"
-	"strconv"
 )
 
-type FolderHandler struct{ Svc *service.FolderService }
+type FolderHandler struct{ Cmds *command.Commands }
 
 type FolderRequest struct {
 	Name      *string `json:"name"`
@@ -25,35 +22,10 @@ func (h *FolderHandler) Create(c *gin.Context) {
 	if err != nil {
 		return
 	}
-	if body.ParentID == nil || *body.ParentID == "" {
-		root := "root"
-		body.ParentID = &root
-	} else {
-		if h.getFolder(body.ParentID, c) == nil {
-			return
-		}
-	}
-	existingFolders, err := h.Svc.ListChildrenByParentId(body.ParentID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query folders"})
-		return
-	}
-
-	if body.Name == nil || *body.Name == "" {
-		newName := generateUniqueFolderName(existingFolders)
-		body.Name = &newName
-	} else {
-		for _, f := range existingFolders {
-			if f.Name == *body.Name {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Folder with that name already exists"})
-				return
-			}
-		}
-	}
 
-	folder, err := h.Svc.CreateNewFolder(*body.Name, body.ParentID)
+	folder, err := h.Cmds.CreateFolder(body.Name, body.ParentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error() + " - failed to create new folder"})
+		WriteCommandError(c, err)
 		return
 	}
 
@@ -61,138 +33,121 @@ func (h *FolderHandler) Create(c *gin.Context) {
 }
 
 func (h *FolderHandler) Retrieve(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" || &id == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing folder ID"})
-		return
-	}
-
-	folder, err := h.Svc.GetFolderDtoById(id)
+	folder, err := h.Cmds.GetFolderTree(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve folder"})
-		return
-	}
-	if folder == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Folder not found"})
+		WriteCommandError(c, err)
 		return
 	}
 
 	getFolderToResponse(c, folder, http.StatusOK)
 }
 
-func (h *FolderHandler) Update(c *gin.Context) {
-	var body FolderRequest
-	err := ValidateAndSetJsonBody(&body, c)
+func (h *FolderHandler) Children(c *gin.Context) {
+	folder, err := h.Cmds.FolderChildren(c.Param("id"))
 	if err != nil {
+		WriteCommandError(c, err)
 		return
 	}
 
-	if body.CurrentID == nil || *body.CurrentID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing current folder ID"})
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *FolderHandler) Sort(c *gin.Context) {
+	var body struct {
+		SortBy   string `json:"sort_by"`
+		SortDesc bool   `json:"sort_desc"`
+	}
+	if err := ValidateAndSetJsonBody(&body, c); err != nil {
 		return
 	}
 
-	currentFolder := h.getFolder(body.CurrentID, c)
-	if currentFolder == nil {
+	folder, err := h.Cmds.SetFolderSort(c.Param("id"), body.SortBy, body.SortDesc)
+	if err != nil {
+		WriteCommandError(c, err)
 		return
 	}
 
-	targetName := currentFolder.Name
-	if body.Name != nil && *body.Name != "" {
-		targetName = *body.Name
-	}
+	c.JSON(http.StatusOK, folder)
+}
 
-	targetParentID := currentFolder.ParentID
-	if body.ParentID != nil && *body.ParentID != "" {
-		targetParentID = body.ParentID
+func (h *FolderHandler) Reorder(c *gin.Context) {
+	var body struct {
+		FolderIDs []string `json:"folder_ids"`
+		NoteIDs   []string `json:"note_ids"`
 	}
-
-	if h.getFolder(targetParentID, c) == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Parent folder does not exist"})
+	if err := ValidateAndSetJsonBody(&body, c); err != nil {
 		return
 	}
 
-	siblings, err := h.Svc.ListChildrenByParentId(targetParentID)
+	folder, err := h.Cmds.ReorderFolder(c.Param("id"), body.FolderIDs, body.NoteIDs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check sibling folders"})
+		WriteCommandError(c, err)
 		return
 	}
-	for _, f := range siblings {
-		if f.Name == targetName && f.ID != currentFolder.ID {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Folder with that name already exists in the target parent"})
-			return
-		}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *FolderHandler) Move(c *gin.Context) {
+	var body struct {
+		FolderIDs []string `json:"folder_ids"`
+		NoteIDs   []string `json:"note_ids"`
+	}
+	if err := ValidateAndSetJsonBody(&body, c); err != nil {
+		return
 	}
 
-	updatedFolder, err := h.Svc.UpdateFolder(currentFolder.ID, targetName, targetParentID)
+	folder, err := h.Cmds.MoveItems(c.Param("id"), body.FolderIDs, body.NoteIDs)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update folder"})
+		WriteCommandError(c, err)
 		return
 	}
 
-	createOrUpdateFolderToResponse(c, updatedFolder, http.StatusOK)
+	c.JSON(http.StatusOK, folder)
 }
 
-func (h *FolderHandler) Delete(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" || &id == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing folder ID from request"})
-		return
-	} else if id == "root" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot delete root folder"})
+func (h *FolderHandler) Update(c *gin.Context) {
+	var body FolderRequest
+	err := ValidateAndSetJsonBody(&body, c)
+	if err != nil {
 		return
 	}
 
-	folder, err := h.Svc.GetFolderByID(id)
+	updatedFolder, err := h.Cmds.UpdateFolder(body.CurrentID, body.Name, body.ParentID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong when trying to retrieve folder from database"})
-		return
-	}
-	if folder == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Folder was not found or does not exist"})
+		WriteCommandError(c, err)
 		return
 	}
 
-	err = h.Svc.DeleteFolderAndContents(folder.ID)
+	createOrUpdateFolderToResponse(c, updatedFolder, http.StatusOK)
+}
+
+func (h *FolderHandler) Delete(c *gin.Context) {
+	folder, err := h.Cmds.DeleteFolder(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong in the deletion process of the folder"})
+		WriteCommandError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"id": folder.ID, "message": "Folder deleted successfully"})
 }
 
-func (h *FolderHandler) getFolder(parentId *string, c *gin.Context) *model.Folder {
-	folder, err := h.Svc.GetFolderByID(*parentId)
+func (h *FolderHandler) Duplicate(c *gin.Context) {
+	var body struct {
+		ParentID *string `json:"parent_id"`
+	}
+	err := ValidateAndSetJsonBody(&body, c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query parent folder"})
-		return nil
+		return
 	}
-	return folder
-}
-
-func generateUniqueFolderName(folders []model.Folder) string {
-	base := "New Folder"
-	maxIndex := 0
-	pattern := regexp.MustCompile(`^New Folder(?: (\d+))?$`)
 
-	for _, f := range folders {
-		matches := pattern.FindStringSubmatch(f.Name)
-		if len(matches) > 1 && matches[1] != "" {
-			if idx, err := strconv.Atoi(matches[1]); err == nil {
-				if idx > maxIndex {
-					maxIndex = idx
-				}
-			}
-		} else if f.Name == base && maxIndex == 0 {
-			maxIndex = 1
-		}
+	folder, err := h.Cmds.DuplicateFolder(c.Param("id"), body.ParentID)
+	if err != nil {
+		WriteCommandError(c, err)
+		return
 	}
 
-	if maxIndex == 0 {
-		return base
-	}
-	return fmt.Sprintf("%s %d", base, maxIndex+1)
+	c.JSON(http.StatusCreated, folder)
 }
 
 func createOrUpdateFolderToResponse(c *gin.Context, folder *model.Folder, status int) {
@@ -17,39 +17,112 @@ func ToBlockModel(blockDTO dto.BlockDTO, noteID string) (*model.Block, error) {
 		NoteID:    noteID,
 		Type:      blockDTO.Type,
 		Index:     blockDTO.Index,
+		OrderKey:  blockDTO.OrderKey,
 		Content:   string(contentBytes),
 		CreatedAt: blockDTO.CreatedAt,
 		UpdatedAt: blockDTO.UpdatedAt,
 	}, nil
 }
 
-func ToNoteDTO(note *model.Note) (*dto.NoteDTO, error) {
+// MaxEmbedDepth caps how many embeds deep a note is resolved; deeper embeds
+// come back with status depth_limit.
+const MaxEmbedDepth = 3
+
+// EmbedLookup finds the block an embed points at and the title of its note.
+// It returns a nil block when there is none.
+type EmbedLookup func(blockID string) (*model.Block, string, error)
+
+// ToNoteDTO maps a note and its blocks. Embed blocks are resolved through
+// lookup; a nil lookup leaves them as stored, e.g. for export.
+func ToNoteDTO(note *model.Note, lookup EmbedLookup) (*dto.NoteDTO, error) {
 	var blocks []dto.BlockDTO
 
 	if len(note.Blocks) == 0 || note.Blocks == nil {
 		blocks = []dto.BlockDTO{}
 	} else {
 		for _, b := range note.Blocks {
-			var raw json.RawMessage
-			if err := json.Unmarshal([]byte(b.Content), &raw); err != nil {
+			block, err := toBlockDTO(b, lookup, map[string]bool{}, 0)
+			if err != nil {
 				return nil, err
 			}
-
-			blocks = append(blocks, dto.BlockDTO{
-				ID:        b.ID,
-				Type:      b.Type,
-				Index:     b.Index,
-				Content:   raw,
-				CreatedAt: b.CreatedAt,
-				UpdatedAt: b.UpdatedAt,
-			})
+			blocks = append(blocks, *block)
 		}
 	}
 
 	return &dto.NoteDTO{
-		ID:       note.ID,
-		Title:    note.Title,
-		FolderID: note.FolderID,
-		Blocks:   blocks,
+		ID:         note.ID,
+		Title:      note.Title,
+		FolderID:   note.FolderID,
+		IsTemplate: note.IsTemplate,
+		Blocks:     blocks,
 	}, nil
 }
+
+// toBlockDTO maps b, resolving it if it is an embed. path holds the blocks
+// being resolved above b so a cycle stops instead of recursing forever.
+func toBlockDTO(b model.Block, lookup EmbedLookup, path map[string]bool, depth int) (*dto.BlockDTO, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal([]byte(b.Content), &raw); err != nil {
+		// show a corrupt block the way vault.check --repair stores it rather
+		// than failing the whole note
+		if raw, err = json.Marshal(dto.QuarantinedContent{Type: b.Type, Raw: b.Content}); err != nil {
+			return nil, err
+		}
+		b.Type = dto.QuarantinedBlockType
+	}
+	block := &dto.BlockDTO{
+		ID:        b.ID,
+		Type:      b.Type,
+		Index:     b.Index,
+		OrderKey:  b.OrderKey,
+		Content:   raw,
+		CreatedAt: b.CreatedAt,
+		UpdatedAt: b.UpdatedAt,
+	}
+	if b.Type != dto.EmbedBlockType || lookup == nil {
+		return block, nil
+	}
+
+	var content dto.EmbedContent
+	_ = json.Unmarshal(raw, &content)
+	embed := &dto.EmbedDTO{BlockID: content.BlockID}
+	block.Embed = embed
+	switch {
+	case content.BlockID == b.ID || path[content.BlockID]:
+		embed.Status = dto.EmbedCycle
+		return block, nil
+	case depth >= MaxEmbedDepth:
+		embed.Status = dto.EmbedDepthLimit
+		return block, nil
+	}
+
+	target, title, err := lookup(content.BlockID)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		embed.Status = dto.EmbedMissing
+		return block, nil
+	}
+	path[b.ID] = true
+	defer delete(path, b.ID)
+	if embed.Block, err = toBlockDTO(*target, lookup, path, depth+1); err != nil {
+		return nil, err
+	}
+	embed.Status = dto.EmbedOK
+	embed.NoteID = target.NoteID
+	embed.NoteTitle = title
+	return block, nil
+}
+
+func ToChangeDTO(change model.Change) dto.ChangeDTO {
+	return dto.ChangeDTO{
+		Seq:      change.Seq,
+		At:       change.CreatedAt,
+		Action:   change.Action,
+		Entity:   change.Entity,
+		EntityID: change.EntityID,
+		NoteID:   change.NoteID,
+		Data:     json.RawMessage(change.Data),
+	}
+}
@@ -1,22 +1,15 @@
 package api
 
 import (
-	"fmt"
 	"github.com/gin-gonic/gin"
-	"gorm.io/gorm"
 	"net/http"
-	"regexp"
-	"server/internal/api/mapper"
-	"server/internal/model"
+	"server/internal/command"
 	"server/internal/model/dto"
 	"server/internal/service"
-	"strconv"
 )
 
-// TODO: this handler is too fat, consider moving more down to services
 type NoteHandler struct {
-	Svc      *service.NoteService
-	BlockSvc *service.BlockService
+	Cmds *command.Commands
 }
 
 func (h *NoteHandler) Create(c *gin.Context) {
@@ -29,168 +22,124 @@ func (h *NoteHandler) Create(c *gin.Context) {
 		return
 	}
 
-	if body.FolderID == nil || *body.FolderID == "" {
-		root := "root"
-		body.FolderID = &root
-	}
-
-	existingNotesInFolder, err := h.Svc.ListNotesByFolderId(body.FolderID)
+	note, err := h.Cmds.CreateNote(body.Title, body.FolderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query notes in current folder"})
-		return
-	}
-
-	if body.Title == nil || *body.Title == "" {
-		newName := generateUniqueNoteName(existingNotesInFolder)
-		body.Title = &newName
-	} else {
-		for _, n := range existingNotesInFolder {
-			if n.Title == *body.Title {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "Note with that title already exists in this folder"})
-				return
-			}
-		}
-	}
-
-	note, err := h.Svc.NewNote(*body.Title, *body.FolderID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error() + " - failed to create new note"})
+		WriteCommandError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"id": note.ID, "title": note.Title, "folder_id": note.FolderID})
 }
 
 func (h *NoteHandler) Get(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing note ID"})
+	dtoNote, err := h.Cmds.GetNote(c.Param("id"))
+	if err != nil {
+		WriteCommandError(c, err)
 		return
 	}
 
-	note, err := h.Svc.GetNote(id)
+	c.JSON(http.StatusOK, dtoNote)
+}
+
+func (h *NoteHandler) Update(c *gin.Context) {
+	var body struct {
+		Title    *string         `json:"title"`
+		FolderID *string         `json:"folder_id"`
+		Blocks   *[]dto.BlockDTO `json:"blocks"`
+	}
+	err := ValidateAndSetJsonBody(&body, c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if note == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
-		return
+
+	var order *[]service.BlockIndex
+	if body.Blocks != nil {
+		indexes := make([]service.BlockIndex, 0, len(*body.Blocks))
+		for _, block := range *body.Blocks {
+			indexes = append(indexes, service.BlockIndex{ID: block.ID, Index: block.Index})
+		}
+		order = &indexes
 	}
 
-	dtoNote, err := mapper.ToNoteDTO(note)
+	data, err := h.Cmds.UpdateNote(c.Param("id"), body.Title, body.FolderID, order)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to map note to DTO"})
+		WriteCommandError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, dtoNote)
+	c.JSON(http.StatusOK, gin.H{"id": data.ID, "title": data.Title, "folder_id": data.FolderID, "message": "Note and blocks updated successfully"})
 }
 
-func (h *NoteHandler) Update(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing note ID"})
-		return
-	}
+func (h *NoteHandler) Duplicate(c *gin.Context) {
 	var body struct {
-		Title    *string         `json:"title"`
-		FolderID *string         `json:"folder_id"`
-		Blocks   *[]dto.BlockDTO `json:"blocks"`
+		FolderID *string `json:"folder_id"`
 	}
 	err := ValidateAndSetJsonBody(&body, c)
 	if err != nil {
 		return
 	}
-	existingNote, err := h.Svc.GetNoteMetaData(id)
+
+	note, err := h.Cmds.DuplicateNote(c.Param("id"), body.FolderID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve note metadata, double check id"})
+		WriteCommandError(c, err)
 		return
 	}
 
-	targetTitle := existingNote.Title
-	if body.Title != nil && *body.Title != "" {
-		targetTitle = *body.Title
-	}
+	c.JSON(http.StatusCreated, note)
+}
 
-	targetFolderId := existingNote.FolderID
-	if body.FolderID != nil && *body.FolderID != "" {
-		targetFolderId = *body.FolderID
+func (h *NoteHandler) Delete(c *gin.Context) {
+	if err := h.Cmds.DeleteNote(c.Param("id")); err != nil {
+		WriteCommandError(c, err)
+		return
 	}
 
-	if body.Blocks != nil {
-		// TODO: database logic in handler = bad
-		err := h.BlockSvc.DB.Transaction(func(tx *gorm.DB) error {
-			for _, block := range *body.Blocks {
-				if err := tx.Model(&model.Block{}).Where("id = ? AND note_id = ?", block.ID, id).
-					Update("index", block.Index).Error; err != nil {
-					return err
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update blocks"})
-			return
-		}
-	}
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+}
 
-	folder, err := h.Svc.ListNotesByFolderId(&targetFolderId)
+func (h *NoteHandler) SetTemplate(c *gin.Context) {
+	var body struct {
+		IsTemplate bool `json:"is_template"`
+	}
+	err := ValidateAndSetJsonBody(&body, c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve notes in new folder, double check id"})
 		return
 	}
-	for _, n := range folder {
-		if n.Title == targetTitle && n.ID != id {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Note with that title already exists in the destination folder"})
-			return
-		}
-	}
 
-	data, err := h.Svc.UpdateNoteMetaData(id, targetTitle, targetFolderId)
+	note, err := h.Cmds.SetTemplate(c.Param("id"), body.IsTemplate)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error() + " - failed to update note metadata"})
+		WriteCommandError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"id": data.ID, "title": data.Title, "folder_id": data.FolderID, "message": "Note and blocks updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"id": note.ID, "title": note.Title, "folder_id": note.FolderID, "is_template": note.IsTemplate})
 }
 
-func generateUniqueNoteName(folders []model.Note) string {
-	base := "New Note"
-	maxIndex := 0
-	pattern := regexp.MustCompile(`^New Note(?: (\d+))?$`)
-
-	for _, n := range folders {
-		matches := pattern.FindStringSubmatch(n.Title)
-		if len(matches) > 1 && matches[1] != "" {
-			if idx, err := strconv.Atoi(matches[1]); err == nil {
-				if idx > maxIndex {
-					maxIndex = idx
-				}
-			}
-		} else if n.Title == base && maxIndex == 0 {
-			maxIndex = 1
-		}
+func (h *NoteHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.Cmds.ListTemplates()
+	if err != nil {
+		WriteCommandError(c, err)
+		return
 	}
 
-	if maxIndex == 0 {
-		return base
-	}
-	return fmt.Sprintf("%s %d", base, maxIndex+1)
+	c.JSON(http.StatusOK, gin.H{"templates": templates})
 }
 
-func (h *NoteHandler) Delete(c *gin.Context) {
-	id := c.Param("id")
-	if id == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing note ID"})
+func (h *NoteHandler) CreateFromTemplate(c *gin.Context) {
+	var body struct {
+		FolderID  *string           `json:"folder_id"`
+		Title     *string           `json:"title"`
+		Variables map[string]string `json:"variables"`
+	}
+	err := ValidateAndSetJsonBody(&body, c)
+	if err != nil {
 		return
 	}
 
-	err := h.Svc.DeleteNote(id)
+	note, err := h.Cmds.CreateNoteFromTemplate(c.Param("id"), body.FolderID, body.Title, body.Variables)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error() + " - failed to delete note"})
+		WriteCommandError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+	c.JSON(http.StatusCreated, note)
 }
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"server/internal/ipc"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RPCHandler exposes every IPC method over HTTP so the REST surface never
+// lags behind the protocol Electron speaks.
+type RPCHandler struct {
+	Server *ipc.Server
+}
+
+// Exclusive runs each request it guards under the IPC dispatch lock, so REST
+// handlers, which call commands directly, take turns with IPC requests. The
+// RPC route must not use it: Server.Call takes the same lock.
+func Exclusive(server *ipc.Server) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		server.Exclusive(c.Next)
+	}
+}
+
+// Mutation is Exclusive for a REST route that writes. Once the handler
+// succeeds, connected IPC sessions get event.changed for method, the IPC
+// method the route mirrors, just as if it had been called over IPC.
+func Mutation(server *ipc.Server, method string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		params, err := restParams(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		w := &capturingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		server.ExclusiveMutation(method, params, func() (any, bool) {
+			c.Next()
+			if w.Status() >= http.StatusBadRequest || !json.Valid(w.body.Bytes()) {
+				return nil, false
+			}
+			return json.RawMessage(w.body.Bytes()), true
+		})
+	}
+}
+
+// restParams merges the path parameters of c into its JSON body, leaving the
+// body readable for the handler. Multipart uploads are not read.
+func restParams(c *gin.Context) (json.RawMessage, error) {
+	params := map[string]any{}
+	if c.Request.Body != nil && !strings.HasPrefix(c.ContentType(), "multipart/") {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return nil, err
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		// a body that is not an object is the handler's to reject
+		_ = json.Unmarshal(body, &params)
+	}
+	for _, p := range c.Params {
+		params[p.Key] = p.Value
+	}
+	if len(params) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(params)
+}
+
+// capturingWriter keeps a copy of the response body for the change event.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Call runs POST /api/rpc/:method with the request body as the method params.
+func (h *RPCHandler) Call(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+	if len(body) > 0 && !json.Valid(body) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON in body"})
+		return
+	}
+
+	res := h.Server.Call(ipc.Request{
+		ID:     c.GetHeader("X-Request-ID"),
+		Method: c.Param("method"),
+		Params: body,
+	})
+	if res.Error != nil {
+		c.JSON(rpcStatus(res.Error.Code), gin.H{"error": res.Error.Message, "code": res.Error.Code})
+		return
+	}
+	c.JSON(http.StatusOK, res.Result)
+}
+
+func rpcStatus(code string) int {
+	switch code {
+	case "BAD_REQUEST", "INCOMPATIBLE_PROTOCOL", "INCOMPATIBLE_SCHEMA":
+		return http.StatusBadRequest
+	case "UNAUTHORIZED":
+		return http.StatusUnauthorized
+	case "NOT_FOUND", "METHOD_NOT_FOUND":
+		return http.StatusNotFound
+	case "CONFLICT":
+		return http.StatusConflict
+	case "INVALID_MOVE":
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"server/internal/command"
 )
 
 func ValidateAndSetJsonBody[T any](body *T, c *gin.Context) error {
@@ -12,3 +13,24 @@ func ValidateAndSetJsonBody[T any](body *T, c *gin.Context) error {
 	}
 	return nil
 }
+
+// WriteCommandError reports a command failure with the status for its kind.
+func WriteCommandError(c *gin.Context, err error) {
+	c.JSON(StatusFor(command.KindOf(err)), gin.H{"error": command.MessageOf(err)})
+}
+
+// StatusFor is the HTTP status for a command error kind. It mirrors
+// ipc.ErrorCode so both transports agree on what each failure means.
+func StatusFor(kind command.Kind) int {
+	switch kind {
+	case command.KindValidation:
+		return http.StatusBadRequest
+	case command.KindNotFound:
+		return http.StatusNotFound
+	case command.KindConflict:
+		return http.StatusConflict
+	case command.KindInvalidMove:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"os"
+)
+
+// GenerateTokenFile creates a random token and stores it at path readable only
+// by the current user, which is what lets local clients prove they are that user.
+func GenerateTokenFile(path string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// TokensEqual compares tokens in constant time. An empty expected token never matches.
+func TokensEqual(expected, given string) bool {
+	if expected == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(given)) == 1
+}
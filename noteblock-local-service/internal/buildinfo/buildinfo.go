@@ -0,0 +1,41 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Version and Commit are stamped at build time, e.g.
+// go build -ldflags "-X server/internal/buildinfo.Version=0.2.0 -X server/internal/buildinfo.Commit=abc123"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+type Info struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+func Get() Info {
+	commit := Commit
+	if commit == "" {
+		// fall back to the vcs stamp go build embeds when run from a checkout
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				if setting.Key == "vcs.revision" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+
+	return Info{
+		Name:      "noteblock-server",
+		Version:   Version,
+		Commit:    commit,
+		GoVersion: runtime.Version(),
+	}
+}
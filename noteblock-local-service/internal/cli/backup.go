@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"server/internal/db"
+	"server/internal/service"
+)
+
+func runBackup(a *app, args []string) error {
+	fs := a.flags("backup")
+	out := fs.String("o", "", "write just the database to this file instead of a full backup in DATA_DIR/backups")
+	list := fs.Bool("list", false, "list the backups instead of taking one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	if *list {
+		backups, err := a.cmds.ListBackups()
+		if err != nil {
+			return err
+		}
+		if a.json {
+			return a.printJSON(backups)
+		}
+		w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d images\n", b.ID, b.Kind, b.CreatedAt.Local().Format(time.DateTime), b.Images)
+		}
+		return w.Flush()
+	}
+
+	if *out != "" {
+		if err := db.VacuumInto(a.notes.DB, *out); err != nil {
+			return err
+		}
+		if _, err := db.Verify(*out); err != nil {
+			return fmt.Errorf("backup %s failed verification: %w", *out, err)
+		}
+		if a.json {
+			return a.printJSON(map[string]string{"path": *out})
+		}
+		fmt.Fprintln(a.stdout, *out)
+		return nil
+	}
+
+	backup, err := a.cmds.CreateBackup()
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(backup)
+	}
+	fmt.Fprintln(a.stdout, filepath.Join(a.dataDir, service.BackupsDirName, backup.ID))
+	return nil
+}
+
+func runRestore(a *app, args []string) error {
+	fs := a.flags("restore")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one backup")
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	res, err := a.cmds.RestoreBackup(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(res)
+	}
+	fmt.Fprintf(a.stdout, "restored %s; the vault before it is backup %s\n", res.Restored.ID, res.Safety.ID)
+	return nil
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"text/tabwriter"
+)
+
+func runCheck(a *app, args []string) error {
+	fs := a.flags("check")
+	repair := fs.Bool("repair", false, "fix what can be fixed: reattach orphans under Recovered, quarantine corrupt blocks, delete dangling rows")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	res, err := a.cmds.CheckVault(*repair)
+	if err != nil {
+		return err
+	}
+
+	if a.json {
+		if err := a.printJSON(res); err != nil {
+			return err
+		}
+	} else if res.OK {
+		fmt.Fprintln(a.stdout, "vault ok")
+	} else {
+		w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+		for _, f := range res.Findings {
+			line := fmt.Sprintf("%s\t%s\t%s", f.Kind, f.ID, f.Detail)
+			if f.Repair != "" {
+				line += "\t(" + f.Repair + ")"
+			}
+			fmt.Fprintln(w, line)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	// like fsck, problems left in place fail the command so scripts notice
+	if !res.OK && !*repair {
+		return fmt.Errorf("%d problem(s) found; run check --repair to fix them", len(res.Findings))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"server/internal/command"
+	"server/internal/db"
+	"server/internal/service"
+)
+
+type subcommand struct {
+	name    string
+	usage   string
+	summary string
+	run     func(a *app, args []string) error
+}
+
+var commands []subcommand
+
+func init() {
+	// assigned here rather than in the declaration because help refers back to commands
+	commands = []subcommand{
+		{"ls", "ls [folder]", "list the folders and notes in a folder (default: root)", runLs},
+		{"cat", "cat <note>", "print a note as markdown", runCat},
+		{"new", "new [--folder F] [--stdin] [title]", "create a note, optionally with a text block read from stdin", runNew},
+		{"search", "search [--limit N] <query>", "find notes whose title or blocks contain query", runSearch},
+		{"export", "export [--format md|json] [-o path] <note|folder>", "export a note or folder subtree", runExport},
+		{"import", "import [--folder F] <file|dir>", "import a json export, a markdown file or a directory of them", runImport},
+		{"backup", "backup [--list] [-o path]", "take a verified backup of the vault and its images, or list them", runBackup},
+		{"restore", "restore <backup>", "replace the vault with a backup, keeping the current one as a backup", runRestore},
+		{"check", "check [--repair]", "check the vault for orphaned, cyclic or corrupt rows and optionally repair them", runCheck},
+		{"replay", "replay [--copy] <recording>...", "replay a recorded IPC session against a scratch vault and diff the responses", runReplay},
+		{"help", "help", "show this help", runHelp},
+	}
+}
+
+// IsCommand reports whether name is a CLI subcommand. Anything else makes the
+// binary fall back to serving IPC, which is how Electron launches it.
+func IsCommand(name string) bool {
+	for _, c := range commands {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes one subcommand and returns the process exit code.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		args = []string{"help"}
+	}
+	a := &app{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(a, args[1:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return 0
+			}
+			fmt.Fprintf(stderr, "noteblock %s: %v\n", c.name, err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(stderr, "noteblock: unknown command %q\n", args[0])
+	return 2
+}
+
+func runHelp(a *app, _ []string) error {
+	fmt.Fprintln(a.stdout, "usage: noteblock <command> [--json] [--data-dir DIR] [args]")
+	fmt.Fprintln(a.stdout, "       noteblock [serve] [--socket] [--record]   (run the IPC server)")
+	fmt.Fprintln(a.stdout)
+	usages := make([]string, 0, len(commands))
+	for _, c := range commands {
+		usages = append(usages, fmt.Sprintf("  %-52s %s", c.usage, c.summary))
+	}
+	sort.Strings(usages)
+	fmt.Fprintln(a.stdout, strings.Join(usages, "\n"))
+	return nil
+}
+
+// app is the state shared by one CLI invocation.
+type app struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	json    bool
+	dataDir string
+
+	cmds    *command.Commands
+	notes   *service.NoteService
+	folders *service.FolderService
+}
+
+// flags returns a FlagSet with the options every subcommand accepts.
+func (a *app) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.BoolVar(&a.json, "json", false, "print machine-readable json")
+	fs.StringVar(&a.dataDir, "data-dir", db.DataDir(), "vault directory (defaults to NOTE_DB_PATH)")
+	return fs
+}
+
+// open connects the services to the vault once flags have been parsed.
+func (a *app) open() error {
+	conn, err := db.Open(a.dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to open vault in %s: %w", a.dataDir, err)
+	}
+	a.cmds = command.New(conn)
+	if err := a.cmds.IndexLinks(); err != nil {
+		return fmt.Errorf("failed to index links: %w", err)
+	}
+	a.notes = a.cmds.Notes
+	a.folders = a.cmds.Folders
+	return nil
+}
+
+func (a *app) printJSON(v any) error {
+	enc := json.NewEncoder(a.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,111 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"server/internal/command"
+	"server/internal/db"
+	"server/internal/model/dto"
+)
+
+func runCLI(t *testing.T, stdin string, args ...string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	if code := Run(args, strings.NewReader(stdin), &stdout, &stderr); code != 0 {
+		t.Fatalf("noteblock %v exited %d: %s", args, code, stderr.String())
+	}
+	return stdout.String()
+}
+
+func TestCLI_NewCatExportImportRoundTrip(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	runCLI(t, "", "new", "--data-dir", src, "Meetings")
+	runCLI(t, "hello **world**\n", "new", "--data-dir", src, "--stdin", "Standup")
+
+	out := runCLI(t, "", "cat", "--data-dir", src, "Standup")
+	if out != "# Standup\n\nhello **world**\n" {
+		t.Fatalf("unexpected cat output: %q", out)
+	}
+
+	hits := runCLI(t, "", "search", "--data-dir", src, "--json", "world")
+	var decoded []searchHit
+	if err := json.Unmarshal([]byte(hits), &decoded); err != nil {
+		t.Fatalf("search output is not json: %v", err)
+	}
+	if len(decoded) != 1 || decoded[0].Title != "Standup" {
+		t.Fatalf("expected one search hit for Standup, got %+v", decoded)
+	}
+
+	exportPath := filepath.Join(t.TempDir(), "vault.json")
+	runCLI(t, "", "export", "--data-dir", src, "-o", exportPath, "/")
+	runCLI(t, "", "import", "--data-dir", dst, exportPath)
+
+	out = runCLI(t, "", "cat", "--data-dir", dst, "Root/Standup")
+	if out != "# Standup\n\nhello **world**\n" {
+		t.Fatalf("unexpected cat output after import: %q", out)
+	}
+
+	var stderr bytes.Buffer
+	if code := Run([]string{"new", "--data-dir", src, "Standup"}, strings.NewReader(""), &bytes.Buffer{}, &stderr); code == 0 {
+		t.Fatalf("expected duplicate note title to fail")
+	}
+}
+
+func TestCLI_BackupRestore(t *testing.T) {
+	dir := t.TempDir()
+
+	runCLI(t, "", "new", "--data-dir", dir, "Before")
+	var backup dto.BackupDTO
+	if err := json.Unmarshal([]byte(runCLI(t, "", "backup", "--data-dir", dir, "--json")), &backup); err != nil {
+		t.Fatalf("backup output is not json: %v", err)
+	}
+	runCLI(t, "", "new", "--data-dir", dir, "After")
+
+	runCLI(t, "", "restore", "--data-dir", dir, backup.ID)
+	if out := runCLI(t, "", "ls", "--data-dir", dir); !strings.Contains(out, "Before") || strings.Contains(out, "After") {
+		t.Fatalf("expected only Before after restore, got %q", out)
+	}
+	if out := runCLI(t, "", "backup", "--data-dir", dir, "--list"); !strings.Contains(out, backup.ID) || !strings.Contains(out, "pre_restore") {
+		t.Fatalf("expected the backup and a pre_restore backup, got %q", out)
+	}
+}
+
+func TestCLI_CatPlacedNote(t *testing.T) {
+	dir := t.TempDir()
+	runCLI(t, "placed\n", "new", "--data-dir", dir, "--stdin", "Spec")
+
+	conn, err := db.Open(dir)
+	if err != nil {
+		t.Fatalf("open vault: %v", err)
+	}
+	cmds := command.New(conn)
+	folder, err := cmds.CreateFolder(strPtr("Projects"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	spec, err := cmds.Notes.ListNotesByFolderId(strPtr("root"))
+	if err != nil || len(spec) != 1 {
+		t.Fatalf("expected the new note in root, got %+v (%v)", spec, err)
+	}
+	if _, err := cmds.PlaceNote(spec[0].ID, folder.ID); err != nil {
+		t.Fatalf("PlaceNote: %v", err)
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+
+	if out := runCLI(t, "", "ls", "--data-dir", dir, "Projects"); !strings.Contains(out, "Spec") {
+		t.Fatalf("expected ls to list the placed note, got %q", out)
+	}
+	if out := runCLI(t, "", "cat", "--data-dir", dir, "Projects/Spec"); out != "# Spec\n\nplaced\n" {
+		t.Fatalf("unexpected cat output for a placed note: %q", out)
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"server/internal/api/mapper"
+	"server/internal/model/dto"
+)
+
+func runLs(a *app, args []string) error {
+	fs := a.flags("ls")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	folder, err := a.resolveFolder(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	// listed in the folder's own sort order, placed notes included
+	level, err := a.cmds.FolderChildren(folder.ID)
+	if err != nil {
+		return err
+	}
+
+	if a.json {
+		res := dto.FolderResponse{
+			ID:       level.ID,
+			Name:     level.Name,
+			ParentID: level.ParentID,
+			SortBy:   level.SortBy,
+			SortDesc: level.SortDesc,
+			Children: make([]dto.FolderResponse, 0, len(level.Children)),
+			Notes:    level.Notes,
+		}
+		for _, c := range level.Children {
+			res.Children = append(res.Children, dto.FolderResponse{ID: c.ID, Name: c.Name, ParentID: c.ParentID})
+		}
+		return a.printJSON(res)
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	for _, c := range level.Children {
+		fmt.Fprintf(w, "%s/\t%s\n", c.Name, c.ID)
+	}
+	for _, n := range level.Notes {
+		fmt.Fprintf(w, "%s\t%s\n", n.Title, n.ID)
+	}
+	return w.Flush()
+}
+
+func runCat(a *app, args []string) error {
+	fs := a.flags("cat")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one note")
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	note, err := a.resolveNote(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	noteDTO, err := mapper.ToNoteDTO(note, a.cmds.Blocks.FindBlock)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		return a.printJSON(noteDTO)
+	}
+	_, err = io.WriteString(a.stdout, renderMarkdown(noteDTO))
+	return err
+}
+
+func runNew(a *app, args []string) error {
+	fs := a.flags("new")
+	folderRef := fs.String("folder", "", "folder ID or path to create the note in")
+	fromStdin := fs.Bool("stdin", false, "read markdown for a text block from stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	title := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if title == "" {
+		return errors.New("missing note title")
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	folder, err := a.resolveFolder(*folderRef)
+	if err != nil {
+		return err
+	}
+
+	note, err := a.cmds.CreateNote(&title, &folder.ID)
+	if err != nil {
+		return err
+	}
+	if *fromStdin {
+		text, err := io.ReadAll(a.stdin)
+		if err != nil {
+			return err
+		}
+		if _, err := a.cmds.CreateBlock(note.ID, "text", 0, map[string]string{"text": string(text)}); err != nil {
+			return err
+		}
+	}
+
+	if a.json {
+		return a.printJSON(dto.NoteResponse{ID: note.ID, Title: note.Title, Primary: true})
+	}
+	fmt.Fprintln(a.stdout, note.ID)
+	return nil
+}
+
+type searchHit struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Folder string `json:"folder"`
+}
+
+func runSearch(a *app, args []string) error {
+	fs := a.flags("search")
+	limit := fs.Int("limit", 50, "maximum number of results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		return errors.New("missing search query")
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	notes, err := a.notes.SearchNotes(query, *limit)
+	if err != nil {
+		return err
+	}
+	hits := make([]searchHit, 0, len(notes))
+	for _, n := range notes {
+		path, err := a.folderPath(n.FolderID)
+		if err != nil {
+			return err
+		}
+		hits = append(hits, searchHit{ID: n.ID, Title: n.Title, Folder: path})
+	}
+
+	if a.json {
+		return a.printJSON(hits)
+	}
+	w := tabwriter.NewWriter(a.stdout, 0, 4, 2, ' ', 0)
+	for _, h := range hits {
+		fmt.Fprintf(w, "%s\t%s\n", strings.TrimSuffix(h.Folder, "/")+"/"+h.Title, h.ID)
+	}
+	return w.Flush()
+}
+
+// renderMarkdown flattens a note into markdown. Text blocks already hold
+// markdown; other block types are reduced to something readable in a terminal.
+func renderMarkdown(note *dto.NoteDTO) string {
+	blocks := append([]dto.BlockDTO(nil), note.Blocks...)
+	sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Index < blocks[j].Index })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", note.Title)
+	for _, block := range blocks {
+		b.WriteString("\n")
+		b.WriteString(renderBlock(block))
+	}
+	return b.String()
+}
+
+func renderBlock(block dto.BlockDTO) string {
+	switch block.Type {
+	case "text":
+		var content struct {
+			Text string `json:"text"`
+		}
+		_ = json.Unmarshal(block.Content, &content)
+		return strings.TrimRight(content.Text, "\n") + "\n"
+	case "image":
+		var content struct {
+			URL string `json:"url"`
+		}
+		_ = json.Unmarshal(block.Content, &content)
+		return fmt.Sprintf("![](%s)\n", content.URL)
+	case dto.EmbedBlockType:
+		// embeds render as a quote of the block they transclude
+		if block.Embed != nil && block.Embed.Block != nil {
+			lines := strings.Split(strings.TrimRight(renderBlock(*block.Embed.Block), "\n"), "\n")
+			return "> " + strings.Join(lines, "\n> ") + "\n"
+		}
+		if block.Embed != nil {
+			return fmt.Sprintf("<!-- embed of %s: %s -->\n", block.Embed.BlockID, block.Embed.Status)
+		}
+	}
+	return fmt.Sprintf("<!-- %s block %s -->\n", block.Type, block.ID)
+}
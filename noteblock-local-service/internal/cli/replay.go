@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"server/internal/command"
+	"server/internal/db"
+	"server/internal/ipc"
+)
+
+type replayReport struct {
+	Replayed   int            `json:"replayed"`
+	Mismatches []ipc.Mismatch `json:"mismatches"`
+}
+
+func runReplay(a *app, args []string) error {
+	fs := a.flags("replay")
+	copyVault := fs.Bool("copy", false, "replay against a copy of the --data-dir vault instead of an empty one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("at least one recording is required")
+	}
+
+	var records []ipc.Record
+	for _, path := range fs.Args() {
+		recs, err := ipc.ReadRecording(path)
+		if err != nil {
+			return err
+		}
+		records = append(records, recs...)
+	}
+
+	scratch, err := os.MkdirTemp("", "noteblock-replay-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(scratch)
+
+	if *copyVault {
+		if err := a.open(); err != nil {
+			return err
+		}
+		if err := db.VacuumInto(a.notes.DB, filepath.Join(scratch, db.FileName)); err != nil {
+			return err
+		}
+	}
+	conn, err := db.Open(scratch)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	// uploads land next to the scratch vault, not in the real one
+	prev, hadPrev := os.LookupEnv("NOTE_DB_PATH")
+	os.Setenv("NOTE_DB_PATH", scratch)
+	defer func() {
+		if hadPrev {
+			os.Setenv("NOTE_DB_PATH", prev)
+		} else {
+			os.Unsetenv("NOTE_DB_PATH")
+		}
+	}()
+
+	mismatches, err := ipc.NewServer(command.New(conn)).Replay(records)
+	if err != nil {
+		return err
+	}
+
+	if a.json {
+		if err := a.printJSON(replayReport{Replayed: len(records), Mismatches: mismatches}); err != nil {
+			return err
+		}
+	} else {
+		for _, m := range mismatches {
+			fmt.Fprintf(a.stdout, "#%d %s\n  want: %s\n  got:  %s\n", m.Index+1, m.Method, m.Want, m.Got)
+		}
+		fmt.Fprintf(a.stdout, "replayed %d requests, %d differed\n", len(records), len(mismatches))
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("%d of %d responses differed", len(mismatches), len(records))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// resolveFolder accepts a folder ID or a slash separated path of folder names
+// starting at root, e.g. "Projects/Specs". An empty ref or "/" is root.
+func (a *app) resolveFolder(ref string) (*model.Folder, error) {
+	trimmed := strings.Trim(ref, "/")
+	if trimmed == "" {
+		return a.folders.GetFolderByID("root")
+	}
+
+	if folder, err := a.folders.GetFolderByID(ref); err == nil {
+		return folder, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	current, err := a.folders.GetFolderByID("root")
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range strings.Split(trimmed, "/") {
+		children, err := a.folders.ListChildrenByParentId(&current.ID)
+		if err != nil {
+			return nil, err
+		}
+		var next *model.Folder
+		for i := range children {
+			if children[i].Name == name {
+				next = &children[i]
+				break
+			}
+		}
+		if next == nil {
+			return nil, fmt.Errorf("folder not found: %s", ref)
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// resolveNote accepts a note ID or a path whose last segment is the note title.
+func (a *app) resolveNote(ref string) (*model.Note, error) {
+	if note, err := a.notes.GetNote(ref); err == nil {
+		return note, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	trimmed := strings.Trim(ref, "/")
+	folderRef, title := "", trimmed
+	if idx := strings.LastIndex(trimmed, "/"); idx >= 0 {
+		folderRef, title = trimmed[:idx], trimmed[idx+1:]
+	}
+	folder, err := a.resolveFolder(folderRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// the notes ls lists, placed ones included
+	level, err := a.cmds.FolderChildren(folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range level.Notes {
+		if n.Title == title {
+			return a.notes.GetNote(n.ID)
+		}
+	}
+	return nil, fmt.Errorf("note not found: %s", ref)
+}
+
+// folderPath renders a folder as the path resolveFolder would accept.
+func (a *app) folderPath(folderID string) (string, error) {
+	var names []string
+	for id := folderID; id != "root"; {
+		folder, err := a.folders.GetFolderByID(id)
+		if err != nil {
+			return "", err
+		}
+		names = append([]string{folder.Name}, names...)
+		if folder.ParentID == nil {
+			break
+		}
+		id = *folder.ParentID
+	}
+	return "/" + strings.Join(names, "/"), nil
+}
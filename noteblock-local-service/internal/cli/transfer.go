@@ -0,0 +1,333 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"server/internal/api/mapper"
+	"server/internal/command"
+	"server/internal/model"
+	"server/internal/model/dto"
+)
+
+// exportFormatVersion guards import against documents from a future format.
+const exportFormatVersion = 1
+
+// exportDocument is the json export format. Exactly one of Note or Folder is set.
+type exportDocument struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Note       *dto.NoteDTO    `json:"note,omitempty"`
+	Folder     *exportedFolder `json:"folder,omitempty"`
+}
+
+type exportedFolder struct {
+	Name     string           `json:"name"`
+	Notes    []dto.NoteDTO    `json:"notes"`
+	Children []exportedFolder `json:"children"`
+}
+
+func runExport(a *app, args []string) error {
+	fs := a.flags("export")
+	format := fs.String("format", "json", "md or json")
+	out := fs.String("o", "", "output file (json) or directory (md); json defaults to stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one note or folder")
+	}
+	if *format != "json" && *format != "md" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *format == "md" && *out == "" {
+		return errors.New("markdown export needs -o DIR")
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+
+	doc := exportDocument{Version: exportFormatVersion, ExportedAt: time.Now().UTC()}
+	if note, err := a.resolveNote(fs.Arg(0)); err == nil {
+		if doc.Note, err = mapper.ToNoteDTO(note, nil); err != nil {
+			return err
+		}
+	} else {
+		folder, folderErr := a.resolveFolder(fs.Arg(0))
+		if folderErr != nil {
+			return fmt.Errorf("no note or folder matches %s", fs.Arg(0))
+		}
+		if doc.Folder, err = a.exportFolder(folder); err != nil {
+			return err
+		}
+	}
+
+	if *format == "md" {
+		return writeMarkdownExport(doc, *out)
+	}
+	if *out == "" {
+		return a.printJSON(doc)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(*out, data, 0o644)
+}
+
+func (a *app) exportFolder(folder *model.Folder) (*exportedFolder, error) {
+	res := &exportedFolder{Name: folder.Name, Notes: []dto.NoteDTO{}, Children: []exportedFolder{}}
+
+	notes, err := a.notes.ListNotesByFolderId(&folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(notes, func(i, j int) bool { return notes[i].Title < notes[j].Title })
+	for _, n := range notes {
+		full, err := a.notes.GetNote(n.ID)
+		if err != nil {
+			return nil, err
+		}
+		noteDTO, err := mapper.ToNoteDTO(full, nil)
+		if err != nil {
+			return nil, err
+		}
+		res.Notes = append(res.Notes, *noteDTO)
+	}
+
+	children, err := a.folders.ListChildrenByParentId(&folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	for i := range children {
+		child, err := a.exportFolder(&children[i])
+		if err != nil {
+			return nil, err
+		}
+		res.Children = append(res.Children, *child)
+	}
+	return res, nil
+}
+
+func writeMarkdownExport(doc exportDocument, dir string) error {
+	if doc.Note != nil {
+		if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+			return err
+		}
+		return writeMarkdownNote(doc.Note, dir)
+	}
+	return writeMarkdownFolder(doc.Folder, filepath.Join(dir, safeFileName(doc.Folder.Name)))
+}
+
+func writeMarkdownFolder(folder *exportedFolder, dir string) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	for i := range folder.Notes {
+		if err := writeMarkdownNote(&folder.Notes[i], dir); err != nil {
+			return err
+		}
+	}
+	for i := range folder.Children {
+		if err := writeMarkdownFolder(&folder.Children[i], filepath.Join(dir, safeFileName(folder.Children[i].Name))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdownNote(note *dto.NoteDTO, dir string) error {
+	return os.WriteFile(filepath.Join(dir, safeFileName(note.Title)+".md"), []byte(renderMarkdown(note)), 0o644)
+}
+
+var unsafeFileChars = strings.NewReplacer("/", "-", "\\", "-", ":", "-", "*", "-", "?", "-", "\"", "-", "<", "-", ">", "-", "|", "-")
+
+func safeFileName(name string) string {
+	name = strings.TrimSpace(unsafeFileChars.Replace(name))
+	if name == "" || name == "." || name == ".." {
+		return "untitled"
+	}
+	return name
+}
+
+func runImport(a *app, args []string) error {
+	fs := a.flags("import")
+	folderRef := fs.String("folder", "", "folder ID or path to import into (default: root)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("expected exactly one file or directory")
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+	target, err := a.resolveFolder(*folderRef)
+	if err != nil {
+		return err
+	}
+
+	src := fs.Arg(0)
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	var created importCounts
+	err = a.cmds.Transaction(func(tx *command.Commands) error {
+		imp := &importer{cmds: tx}
+		defer func() { created = imp.counts }()
+
+		switch {
+		case info.IsDir():
+			return imp.markdownDir(src, target.ID)
+		case strings.EqualFold(filepath.Ext(src), ".md"):
+			return imp.markdownFile(src, target.ID)
+		default:
+			return imp.jsonFile(src, target.ID)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.json {
+		return a.printJSON(created)
+	}
+	fmt.Fprintf(a.stdout, "imported %d folders, %d notes, %d blocks\n", created.Folders, created.Notes, created.Blocks)
+	return nil
+}
+
+type importCounts struct {
+	Folders int `json:"folders"`
+	Notes   int `json:"notes"`
+	Blocks  int `json:"blocks"`
+}
+
+// importer writes everything through commands bound to one transaction so a
+// failed import leaves the vault untouched and obeys the same naming rules as
+// the app.
+type importer struct {
+	cmds   *command.Commands
+	counts importCounts
+}
+
+func (imp *importer) jsonFile(path, folderID string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var doc exportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s is not a noteblock json export: %w", path, err)
+	}
+	if doc.Version > exportFormatVersion {
+		return fmt.Errorf("%s was exported by a newer noteblock (format %d)", path, doc.Version)
+	}
+
+	switch {
+	case doc.Note != nil:
+		return imp.note(doc.Note, folderID)
+	case doc.Folder != nil:
+		return imp.folder(doc.Folder, folderID)
+	}
+	return fmt.Errorf("%s contains neither a note nor a folder", path)
+}
+
+func (imp *importer) folder(folder *exportedFolder, parentID string) error {
+	created, err := imp.createFolder(folder.Name, parentID)
+	if err != nil {
+		return err
+	}
+
+	for i := range folder.Notes {
+		if err := imp.note(&folder.Notes[i], created.ID); err != nil {
+			return err
+		}
+	}
+	for i := range folder.Children {
+		if err := imp.folder(&folder.Children[i], created.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (imp *importer) createFolder(name, parentID string) (*model.Folder, error) {
+	created, err := imp.cmds.CreateFolder(&name, &parentID)
+	if err != nil {
+		return nil, err
+	}
+	imp.counts.Folders++
+	return created, nil
+}
+
+func (imp *importer) note(note *dto.NoteDTO, folderID string) error {
+	created, err := imp.cmds.CreateNote(&note.Title, &folderID)
+	if err != nil {
+		return err
+	}
+	imp.counts.Notes++
+
+	for _, block := range note.Blocks {
+		content := block.Content
+		if _, err := imp.cmds.CreateBlock(created.ID, block.Type, block.Index, &content); err != nil {
+			return err
+		}
+		imp.counts.Blocks++
+	}
+	return nil
+}
+
+func (imp *importer) markdownFile(path, folderID string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	title := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	// markdown exports lead with the title as a heading; do not duplicate it
+	text := strings.TrimPrefix(string(data), "# "+title+"\n")
+	text = strings.TrimLeft(text, "\n")
+	content, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return imp.note(&dto.NoteDTO{
+		Title:  title,
+		Blocks: []dto.BlockDTO{{Type: "text", Index: 0, Content: content}},
+	}, folderID)
+}
+
+func (imp *importer) markdownDir(dir, parentID string) error {
+	folder, err := imp.createFolder(filepath.Base(dir), parentID)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		switch {
+		case entry.IsDir():
+			err = imp.markdownDir(path, folder.ID)
+		case strings.EqualFold(filepath.Ext(entry.Name()), ".md"):
+			err = imp.markdownFile(path, folder.ID)
+		default:
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
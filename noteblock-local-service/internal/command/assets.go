@@ -0,0 +1,76 @@
+package command
+
+import (
+	"errors"
+	"os"
+
+	"server/internal/model/dto"
+	"server/internal/service"
+)
+
+// MaxAssetChunk caps how much of an asset one ReadAsset call returns.
+const MaxAssetChunk = 8 << 20
+
+// AssetChunk is a slice of an asset's bytes and where it sits in the file.
+type AssetChunk struct {
+	Filename  string
+	Offset    int64
+	TotalSize int64
+	Data      []byte
+}
+
+// EOF reports whether the chunk reaches the end of the asset.
+func (c *AssetChunk) EOF() bool {
+	return c.Offset+int64(len(c.Data)) >= c.TotalSize
+}
+
+// ReadAsset returns up to length bytes from offset. A length of zero, or one
+// over MaxAssetChunk, reads MaxAssetChunk.
+func (c *Commands) ReadAsset(url string, offset, length int64) (*AssetChunk, error) {
+	if url == "" {
+		return nil, validation("Missing asset url")
+	}
+	if offset < 0 || length < 0 {
+		return nil, validation("Offset and length must not be negative")
+	}
+	if length == 0 || length > MaxAssetChunk {
+		length = MaxAssetChunk
+	}
+
+	data, total, err := c.Assets.ReadRange(url, offset, length)
+	if err != nil {
+		return nil, assetErr(err, "Failed to read asset")
+	}
+	return &AssetChunk{
+		Filename:  service.ImageName(url),
+		Offset:    offset,
+		TotalSize: total,
+		Data:      data,
+	}, nil
+}
+
+func (c *Commands) StatAsset(url string) (*dto.AssetInfo, error) {
+	if url == "" {
+		return nil, validation("Missing asset url")
+	}
+	info, err := c.Assets.Stat(url)
+	if err != nil {
+		return nil, assetErr(err, "Failed to read asset")
+	}
+	return info, nil
+}
+
+func (c *Commands) ListAssets() ([]dto.AssetEntry, error) {
+	assets, err := c.Assets.List()
+	if err != nil {
+		return nil, internal("Failed to list assets", err)
+	}
+	return assets, nil
+}
+
+func assetErr(err error, msg string) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return notFound("Asset not found")
+	}
+	return internal(msg, err)
+}
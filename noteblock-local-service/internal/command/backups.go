@@ -0,0 +1,189 @@
+package command
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	localdb "server/internal/db"
+	"server/internal/model/dto"
+	"server/internal/service"
+)
+
+const (
+	settingBackupSchedule   = "backup.schedule"
+	settingBackupKeepHourly = "backup.keep_hourly"
+	settingBackupKeepDaily  = "backup.keep_daily"
+	settingBackupKeepWeekly = "backup.keep_weekly"
+)
+
+// DefaultBackupConfig backs the vault up daily and keeps a day of hourly, a
+// week of daily and a month of weekly backups.
+var DefaultBackupConfig = dto.BackupConfig{
+	Schedule:   dto.BackupDaily,
+	KeepHourly: 24,
+	KeepDaily:  7,
+	KeepWeekly: 4,
+}
+
+var backupPeriods = map[string]time.Duration{
+	dto.BackupHourly: time.Hour,
+	dto.BackupDaily:  24 * time.Hour,
+	dto.BackupWeekly: 7 * 24 * time.Hour,
+}
+
+// BackupConfig returns the backup schedule and retention with defaults
+// filled in.
+func (c *Commands) BackupConfig() (*dto.BackupConfig, error) {
+	cfg := DefaultBackupConfig
+	schedule, err := c.Settings.Get(settingBackupSchedule)
+	if err != nil {
+		return nil, internal("Failed to read backup settings", err)
+	}
+	if schedule != "" {
+		cfg.Schedule = schedule
+	}
+	for key, value := range map[string]*int{
+		settingBackupKeepHourly: &cfg.KeepHourly,
+		settingBackupKeepDaily:  &cfg.KeepDaily,
+		settingBackupKeepWeekly: &cfg.KeepWeekly,
+	} {
+		v, err := c.Settings.Get(key)
+		if err != nil {
+			return nil, internal("Failed to read backup settings", err)
+		}
+		if n, err := strconv.Atoi(v); err == nil {
+			*value = n
+		}
+	}
+	return &cfg, nil
+}
+
+// ConfigureBackups stores the backup schedule and retention and rotates the
+// existing scheduled backups by it straight away.
+func (c *Commands) ConfigureBackups(cfg dto.BackupConfig) (*dto.BackupConfig, error) {
+	if cfg.Schedule != dto.BackupOff && backupPeriods[cfg.Schedule] == 0 {
+		return nil, validation("Schedule must be off, hourly, daily or weekly")
+	}
+	if cfg.KeepHourly < 0 || cfg.KeepDaily < 0 || cfg.KeepWeekly < 0 {
+		return nil, validation("Backup retention cannot be negative")
+	}
+	if err := c.saveBackupConfig(cfg); err != nil {
+		return nil, err
+	}
+	if _, err := c.Backups.Rotate(cfg); err != nil {
+		return nil, internal("Failed to rotate backups", err)
+	}
+	return &cfg, nil
+}
+
+func (c *Commands) saveBackupConfig(cfg dto.BackupConfig) error {
+	return c.Transaction(func(tx *Commands) error {
+		for key, value := range map[string]string{
+			settingBackupSchedule:   cfg.Schedule,
+			settingBackupKeepHourly: strconv.Itoa(cfg.KeepHourly),
+			settingBackupKeepDaily:  strconv.Itoa(cfg.KeepDaily),
+			settingBackupKeepWeekly: strconv.Itoa(cfg.KeepWeekly),
+		} {
+			if err := tx.Settings.Set(key, value); err != nil {
+				return internal("Failed to save backup settings", err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateBackup takes a manual backup, which rotation never deletes.
+func (c *Commands) CreateBackup() (*dto.BackupDTO, error) {
+	backup, err := c.Backups.Create(dto.BackupManual, time.Now())
+	if err != nil {
+		return nil, internal("Failed to create backup", err)
+	}
+	return backup, nil
+}
+
+// ListBackups returns the verified backups, newest first.
+func (c *Commands) ListBackups() ([]dto.BackupDTO, error) {
+	backups, err := c.Backups.List()
+	if err != nil {
+		return nil, internal("Failed to list backups", err)
+	}
+	return backups, nil
+}
+
+// RestoreBackup swaps the live vault for backup id while the server keeps
+// running, after backing up the current vault so the restore can be undone.
+// The backup settings are the vault's, not the backup's, and survive the
+// restore; the change log keeps counting up from where it was so clients
+// following it see a backup.restore entry and reload.
+func (c *Commands) RestoreBackup(id string) (*dto.BackupRestoreDTO, error) {
+	if id == "" {
+		return nil, validation("Missing backup ID")
+	}
+	cfg, err := c.BackupConfig()
+	if err != nil {
+		return nil, err
+	}
+	lastSeq, err := c.Changes.LastSeq()
+	if err != nil {
+		return nil, internal("Failed to read change log", err)
+	}
+
+	restored, safety, err := c.Backups.Restore(id, time.Now())
+	switch {
+	case errors.Is(err, service.ErrBackupNotFound):
+		return nil, notFound("Backup not found")
+	case errors.Is(err, localdb.ErrNewerSchema):
+		return nil, validation("Backup was written by a newer version of noteblock")
+	case errors.Is(err, service.ErrBackupUnverified):
+		return nil, conflict("Backup failed verification and was not restored")
+	case err != nil:
+		return nil, internal("Failed to restore backup", err)
+	}
+
+	if err := localdb.Migrate(c.Notes.DB); err != nil {
+		return nil, internal("Failed to migrate restored vault", err)
+	}
+	if err := c.saveBackupConfig(*cfg); err != nil {
+		return nil, err
+	}
+	if err := c.IndexLinks(); err != nil {
+		return nil, internal("Failed to index links", err)
+	}
+	if _, err := c.Changes.AppendAfter(lastSeq, "backup.restore", "backup", restored.ID, "", map[string]string{"safety_id": safety.ID}); err != nil {
+		return nil, internal("Failed to record restore", err)
+	}
+	return &dto.BackupRestoreDTO{Restored: *restored, Safety: *safety}, nil
+}
+
+// RunScheduledBackup takes a scheduled backup when the newest one is a full
+// period older than now, then rotates. It returns nil when none was due.
+func (c *Commands) RunScheduledBackup(now time.Time) (*dto.BackupDTO, error) {
+	cfg, err := c.BackupConfig()
+	if err != nil {
+		return nil, err
+	}
+	period := backupPeriods[cfg.Schedule]
+	if period == 0 {
+		return nil, nil
+	}
+
+	backups, err := c.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(backups, func(b dto.BackupDTO) bool { return b.Kind == dto.BackupScheduled })
+	if i >= 0 && now.Sub(backups[i].CreatedAt) < period {
+		return nil, nil
+	}
+
+	backup, err := c.Backups.Create(dto.BackupScheduled, now)
+	if err != nil {
+		return nil, internal("Failed to create backup", err)
+	}
+	if _, err := c.Backups.Rotate(*cfg); err != nil {
+		return nil, internal("Failed to rotate backups", err)
+	}
+	return backup, nil
+}
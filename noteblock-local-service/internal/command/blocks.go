@@ -0,0 +1,209 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+
+	"server/internal/model"
+	"server/internal/model/dto"
+	"server/internal/service"
+)
+
+var errInvalidContent = errors.New("invalid block content")
+
+func (c *Commands) CreateBlock(noteID, blockType string, index int, content any) (*model.Block, error) {
+	if noteID == "" {
+		return nil, validation("Missing note ID")
+	}
+	raw, err := toRawMessage(content)
+	if err != nil {
+		return nil, validation("Invalid block content")
+	}
+	if blockType == dto.EmbedBlockType {
+		if err := checkEmbed("", raw); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := c.Notes.GetNoteMetaData(noteID); err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+
+	var block *model.Block
+	err = c.Transaction(func(tx *Commands) error {
+		var err error
+		block, err = tx.Blocks.CreateNewBlock(noteID, blockType, index, raw)
+		if err != nil {
+			return internal("Failed to create block", err)
+		}
+		return tx.contentChanged(noteID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+func (c *Commands) UpdateBlock(noteID, blockID, blockType string, content any) (*model.Block, error) {
+	if noteID == "" || blockID == "" {
+		return nil, validation("Missing note ID or block ID")
+	}
+	raw, err := toRawMessage(content)
+	if err != nil {
+		return nil, validation("Invalid block content")
+	}
+	if blockType == dto.EmbedBlockType {
+		if err := checkEmbed(blockID, raw); err != nil {
+			return nil, err
+		}
+	}
+
+	var block *model.Block
+	err = c.Transaction(func(tx *Commands) error {
+		var err error
+		block, err = tx.Blocks.UpdateBlockContent(noteID, blockID, blockType, raw)
+		if err != nil {
+			return lookupErr(err, "Block not found", "Failed to update block")
+		}
+		return tx.contentChanged(noteID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// MoveBlock places a block between two neighbours in targetNoteID, or in its
+// own note when targetNoteID is empty, and logs the move.
+func (c *Commands) MoveBlock(noteID, blockID, targetNoteID, afterID, beforeID string) (*model.Block, error) {
+	return c.placeBlock("block.move", noteID, blockID, targetNoteID, afterID, beforeID)
+}
+
+// CopyBlock duplicates a block into targetNoteID, or its own note when
+// targetNoteID is empty, and logs the copy.
+func (c *Commands) CopyBlock(noteID, blockID, targetNoteID, afterID, beforeID string) (*model.Block, error) {
+	return c.placeBlock("block.copy", noteID, blockID, targetNoteID, afterID, beforeID)
+}
+
+func (c *Commands) placeBlock(action, noteID, blockID, targetNoteID, afterID, beforeID string) (*model.Block, error) {
+	if noteID == "" || blockID == "" {
+		return nil, validation("Missing note ID or block ID")
+	}
+	if blockID == afterID || blockID == beforeID {
+		return nil, validation("A block cannot be placed next to itself")
+	}
+	if targetNoteID == "" {
+		targetNoteID = noteID
+	}
+	if targetNoteID != noteID {
+		if _, err := c.Notes.GetNoteMetaData(targetNoteID); err != nil {
+			return nil, lookupErr(err, "Target note not found", "Failed to query target note")
+		}
+	}
+
+	var block *model.Block
+	err := c.Transaction(func(tx *Commands) error {
+		var err error
+		if action == "block.copy" {
+			block, err = tx.Blocks.CopyBlock(noteID, blockID, targetNoteID, afterID, beforeID)
+		} else {
+			block, err = tx.Blocks.MoveBlock(noteID, blockID, targetNoteID, afterID, beforeID)
+		}
+		switch {
+		case errors.Is(err, service.ErrBlockNotFound):
+			return notFound("Neighbour block not found in note")
+		case errors.Is(err, service.ErrNotAdjacent):
+			return validation("after_id and before_id must be adjacent")
+		case err != nil:
+			return lookupErr(err, "Block not found", "Failed to place block")
+		}
+
+		if _, err := tx.Changes.Append(action, "block", block.ID, targetNoteID, map[string]string{
+			"source_block_id": blockID,
+			"source_note_id":  noteID,
+			"order_key":       block.OrderKey,
+		}); err != nil {
+			return internal("Failed to log block change", err)
+		}
+		return tx.contentChanged(noteID, targetNoteID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// DeleteBlock deletes a block and returns how many embeds of it are left
+// behind. Those stay in place and resolve with status missing.
+func (c *Commands) DeleteBlock(noteID, blockID string) (int, error) {
+	if noteID == "" || blockID == "" {
+		return 0, validation("Missing note ID or block ID")
+	}
+	refs, err := c.Blocks.EmbedsOf(blockID)
+	if err != nil {
+		return 0, internal("Failed to query block references", err)
+	}
+	err = c.Transaction(func(tx *Commands) error {
+		if err := tx.Blocks.DeleteBlock(noteID, blockID); err != nil {
+			return internal("Failed to delete block", err)
+		}
+		return tx.contentChanged(noteID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(refs), nil
+}
+
+// contentChanged refreshes what is derived from the text of noteIDs after
+// their blocks were written: links always, and tags when extraction is on.
+func (c *Commands) contentChanged(noteIDs ...string) error {
+	extract, err := c.extractInlineTags()
+	if err != nil {
+		return err
+	}
+	synced := make(map[string]bool, len(noteIDs))
+	for _, noteID := range noteIDs {
+		if synced[noteID] {
+			continue
+		}
+		synced[noteID] = true
+		if err := c.syncLinks(noteID); err != nil {
+			return internal("Failed to sync links", err)
+		}
+		if !extract {
+			continue
+		}
+		if err := c.syncInlineTags(noteID); err != nil {
+			return internal("Failed to sync inline tags", err)
+		}
+	}
+	return nil
+}
+
+func (c *Commands) UploadImage(filename string, data []byte) (string, error) {
+	if filename == "" || len(data) == 0 {
+		return "", validation("Missing filename or image data")
+	}
+	url, err := c.Blocks.SaveImageBytes(filename, data)
+	if err != nil {
+		return "", internal("Failed to save image", err)
+	}
+	return url, nil
+}
+
+// toRawMessage accepts content already decoded from a request (any) or still
+// raw, and normalizes it to the json the block table stores.
+func toRawMessage(content any) (*json.RawMessage, error) {
+	if raw, ok := content.(*json.RawMessage); ok {
+		if raw == nil || !json.Valid(*raw) {
+			return nil, errInvalidContent
+		}
+		return raw, nil
+	}
+	b, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(b)
+	return &raw, nil
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"server/internal/api/mapper"
+	"server/internal/model/dto"
+)
+
+// MaxChanges caps how many change log entries one ListChanges call returns.
+const MaxChanges = 500
+
+// ListChanges returns change log entries after seq, oldest first.
+func (c *Commands) ListChanges(since uint64, limit int) ([]dto.ChangeDTO, error) {
+	if limit <= 0 || limit > MaxChanges {
+		limit = MaxChanges
+	}
+	changes, err := c.Changes.Since(since, limit)
+	if err != nil {
+		return nil, internal("Failed to read change log", err)
+	}
+
+	result := make([]dto.ChangeDTO, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, mapper.ToChangeDTO(change))
+	}
+	return result, nil
+}
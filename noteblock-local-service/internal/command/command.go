@@ -0,0 +1,49 @@
+// Package command holds the rules shared by every transport: defaulting,
+// validation and conflict checks live here once, and failures come back as
+// *Error so IPC, HTTP and the CLI report them the same way.
+package command
+
+import (
+	"server/internal/service"
+
+	"gorm.io/gorm"
+)
+
+type Commands struct {
+	Notes    *service.NoteService
+	Folders  *service.FolderService
+	Blocks   *service.BlockService
+	Assets   *service.AssetService
+	Changes  *service.ChangeService
+	Settings *service.SettingsService
+	Journal  *service.JournalService
+	Tags     *service.TagService
+	Links    *service.LinkService
+	Vault    *service.VaultService
+	Backups  *service.BackupService
+}
+
+// New wires commands to db, which may be a transaction.
+func New(db *gorm.DB) *Commands {
+	notes := &service.NoteService{DB: db}
+	return &Commands{
+		Notes:    notes,
+		Folders:  &service.FolderService{DB: db, NoteService: notes},
+		Blocks:   &service.BlockService{DB: db},
+		Assets:   &service.AssetService{DB: db},
+		Changes:  &service.ChangeService{DB: db},
+		Settings: &service.SettingsService{DB: db},
+		Journal:  &service.JournalService{DB: db},
+		Tags:     &service.TagService{DB: db},
+		Links:    &service.LinkService{DB: db},
+		Vault:    &service.VaultService{DB: db},
+		Backups:  &service.BackupService{DB: db},
+	}
+}
+
+// Transaction runs fn with commands bound to a single database transaction.
+func (c *Commands) Transaction(fn func(tx *Commands) error) error {
+	return c.Notes.DB.Transaction(func(tx *gorm.DB) error {
+		return fn(New(tx))
+	})
+}
@@ -0,0 +1,1322 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	localdb "server/internal/db"
+	"server/internal/model"
+	"server/internal/model/dto"
+	"server/internal/service"
+)
+
+func setupTestCommands(t *testing.T) *Commands {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "command_test.sqlite")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test sqlite db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql db handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := localdb.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+	return New(db)
+}
+
+func strPtr(s string) *string { return &s }
+
+func expectKind(t *testing.T, err error, kind Kind) {
+	t.Helper()
+	if err == nil {
+		t.Fatalf("expected %s error, got nil", kind)
+	}
+	if got := KindOf(err); got != kind {
+		t.Fatalf("expected %s error, got %s (%v)", kind, got, err)
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	cases := []struct {
+		existing []string
+		want     string
+	}{
+		{nil, "New Note"},
+		{[]string{"Other"}, "New Note"},
+		{[]string{"New Note"}, "New Note 2"},
+		{[]string{"New Note", "New Note 7", "New Note 3"}, "New Note 8"},
+		{[]string{"New Note 2"}, "New Note 3"},
+	}
+	for _, c := range cases {
+		if got := uniqueName("New Note", c.existing); got != c.want {
+			t.Errorf("uniqueName(%v) = %q, want %q", c.existing, got, c.want)
+		}
+	}
+}
+
+func TestFolderRules(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	first, err := cmds.CreateFolder(nil, nil)
+	if err != nil {
+		t.Fatalf("CreateFolder failed: %v", err)
+	}
+	if first.Name != "New Folder" || *first.ParentID != RootFolderID {
+		t.Fatalf("expected default folder under root, got %+v", first)
+	}
+	second, err := cmds.CreateFolder(nil, strPtr(""))
+	if err != nil {
+		t.Fatalf("CreateFolder failed: %v", err)
+	}
+	if second.Name != "New Folder 2" {
+		t.Fatalf("expected New Folder 2, got %s", second.Name)
+	}
+
+	_, err = cmds.CreateFolder(strPtr("New Folder"), nil)
+	expectKind(t, err, KindConflict)
+	_, err = cmds.CreateFolder(strPtr("Orphan"), strPtr("missing"))
+	expectKind(t, err, KindNotFound)
+	_, err = cmds.UpdateFolder(nil, strPtr("x"), nil)
+	expectKind(t, err, KindValidation)
+	_, err = cmds.UpdateFolder(&second.ID, strPtr("New Folder"), nil)
+	expectKind(t, err, KindConflict)
+	_, err = cmds.DeleteFolder(RootFolderID)
+	expectKind(t, err, KindValidation)
+	_, err = cmds.DeleteFolder("missing")
+	expectKind(t, err, KindNotFound)
+	_, err = cmds.GetFolderTree("missing")
+	expectKind(t, err, KindNotFound)
+}
+
+func TestNoteRules(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	note, err := cmds.CreateNote(strPtr("Spec"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	other, err := cmds.CreateNote(nil, nil)
+	if err != nil {
+		t.Fatalf("CreateNote failed: %v", err)
+	}
+	if other.Title != "New Note" {
+		t.Fatalf("expected New Note, got %s", other.Title)
+	}
+
+	_, err = cmds.CreateNote(strPtr("Spec"), nil)
+	expectKind(t, err, KindConflict)
+	_, err = cmds.CreateNote(strPtr("Lost"), strPtr("missing"))
+	expectKind(t, err, KindNotFound)
+	_, err = cmds.GetNote("missing")
+	expectKind(t, err, KindNotFound)
+	expectKind(t, cmds.DeleteNote("missing"), KindNotFound)
+	_, err = cmds.UpdateBlock(note.ID, "missing", "text", map[string]string{"text": "x"})
+	expectKind(t, err, KindNotFound)
+
+	block, err := cmds.CreateBlock(other.ID, "text", 0, map[string]string{"text": "x"})
+	if err != nil {
+		t.Fatalf("CreateBlock failed: %v", err)
+	}
+
+	// a title conflict must not leave the requested reorder half applied
+	_, err = cmds.UpdateNote(other.ID, strPtr("Spec"), nil, &[]service.BlockIndex{{ID: block.ID, Index: 5}})
+	expectKind(t, err, KindConflict)
+	got, err := cmds.GetNote(other.ID)
+	if err != nil {
+		t.Fatalf("GetNote failed: %v", err)
+	}
+	if got.Blocks[0].Index != 0 {
+		t.Fatalf("expected block index to stay 0 after a rejected update, got %d", got.Blocks[0].Index)
+	}
+}
+
+func TestCopyName(t *testing.T) {
+	cases := []struct {
+		name     string
+		existing []string
+		want     string
+	}{
+		{"Spec", []string{"Spec"}, "Spec (copy)"},
+		{"Spec", []string{"Spec", "Spec (copy)"}, "Spec (copy 2)"},
+		{"Spec (copy)", []string{"Spec", "Spec (copy)", "Spec (copy 2)"}, "Spec (copy 3)"},
+	}
+	for _, c := range cases {
+		if got := copyName(c.name, c.existing); got != c.want {
+			t.Fatalf("copyName(%q, %v) = %q, want %q", c.name, c.existing, got, c.want)
+		}
+	}
+}
+
+func TestDuplicateFolderTree(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	skeleton, _ := cmds.CreateFolder(strPtr("Skeleton"), nil)
+	docs, _ := cmds.CreateFolder(strPtr("Docs"), &skeleton.ID)
+	spec, _ := cmds.CreateNote(strPtr("Spec"), &docs.ID)
+	if _, err := cmds.CreateBlock(spec.ID, "text", 0, map[string]string{"text": "one"}); err != nil {
+		t.Fatalf("create block failed: %v", err)
+	}
+	if _, err := cmds.CreateBlock(spec.ID, "image", 1, map[string]string{"url": "noteblock-image:///a.png"}); err != nil {
+		t.Fatalf("create block failed: %v", err)
+	}
+
+	// a note placed in the tree from elsewhere is placed in the copy, not forked
+	outside, _ := cmds.CreateNote(strPtr("Outside"), nil)
+	if _, err := cmds.PlaceNote(outside.ID, docs.ID); err != nil {
+		t.Fatalf("PlaceNote: %v", err)
+	}
+
+	copied, err := cmds.DuplicateFolder(skeleton.ID, nil)
+	if err != nil {
+		t.Fatalf("duplicate folder failed: %v", err)
+	}
+	if copied.Name != "Skeleton (copy)" || copied.ID == skeleton.ID {
+		t.Fatalf("unexpected copy: %+v", copied)
+	}
+	if len(copied.Children) != 1 || copied.Children[0].Name != "Docs" || len(copied.Children[0].Notes) != 2 {
+		t.Fatalf("expected the Docs subtree to be copied, got %+v", copied.Children)
+	}
+	var copiedSpec dto.NoteResponse
+	for _, n := range copied.Children[0].Notes {
+		switch {
+		case n.Title == "Spec" && n.Primary:
+			copiedSpec = n
+		case n.Title == "Outside" && (n.Primary || n.ID != outside.ID):
+			t.Fatalf("expected Outside to be placed in the copy, got %+v", n)
+		}
+	}
+	if placements, _ := cmds.NotePlacements(outside.ID); len(placements) != 3 {
+		t.Fatalf("expected Outside in root, Docs and the copy of Docs, got %+v", placements)
+	}
+	if copiedSpec.ID == spec.ID {
+		t.Fatalf("expected the note copy to get a new ID")
+	}
+	note, err := cmds.GetNote(copiedSpec.ID)
+	if err != nil || len(note.Blocks) != 2 || string(note.Blocks[1].Content) != `{"url":"noteblock-image:///a.png"}` {
+		t.Fatalf("expected both blocks copied in order, got %+v (%v)", note, err)
+	}
+
+	again, err := cmds.DuplicateFolder(skeleton.ID, nil)
+	if err != nil || again.Name != "Skeleton (copy 2)" {
+		t.Fatalf("expected Skeleton (copy 2), got %+v (%v)", again, err)
+	}
+
+	_, err = cmds.DuplicateFolder(skeleton.ID, &docs.ID)
+	expectKind(t, err, KindInvalidMove)
+	_, err = cmds.DuplicateFolder(RootFolderID, nil)
+	expectKind(t, err, KindValidation)
+
+	noteCopy, err := cmds.DuplicateNote(spec.ID, nil)
+	if err != nil || noteCopy.Title != "Spec (copy)" || noteCopy.FolderID != docs.ID || len(noteCopy.Blocks) != 2 {
+		t.Fatalf("unexpected note duplicate: %+v (%v)", noteCopy, err)
+	}
+	moved, err := cmds.DuplicateNote(spec.ID, strPtr(RootFolderID))
+	if err != nil || moved.Title != "Spec" {
+		t.Fatalf("expected the original title in a folder without a clash, got %+v (%v)", moved, err)
+	}
+}
+
+func TestPlacedTitleConflicts(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	inbox, _ := cmds.CreateFolder(strPtr("Inbox"), nil)
+	archive, _ := cmds.CreateFolder(strPtr("Archive"), nil)
+	plan, _ := cmds.CreateNote(strPtr("Plan"), &archive.ID)
+	if _, err := cmds.PlaceNote(plan.ID, inbox.ID); err != nil {
+		t.Fatalf("PlaceNote: %v", err)
+	}
+
+	// Plan is listed in Inbox, so Inbox has no room for another Plan
+	_, err := cmds.CreateNote(strPtr("Plan"), &inbox.ID)
+	expectKind(t, err, KindConflict)
+	untitled, err := cmds.CreateNote(nil, &inbox.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	_, err = cmds.UpdateNote(untitled.ID, strPtr("Plan"), nil, nil)
+	expectKind(t, err, KindConflict)
+
+	dup, err := cmds.DuplicateNote(plan.ID, &inbox.ID)
+	if err != nil || dup.Title != "Plan (copy)" {
+		t.Fatalf("expected a copy title next to the placed Plan, got %+v (%v)", dup, err)
+	}
+
+	// renaming Plan must keep its title free in Inbox, where it is placed
+	_, err = cmds.UpdateNote(plan.ID, strPtr(untitled.Title), nil, nil)
+	expectKind(t, err, KindConflict)
+	if _, err := cmds.UpdateNote(plan.ID, strPtr("Roadmap"), nil, nil); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+}
+
+func TestCreateNoteFromTemplate(t *testing.T) {
+	cmds := setupTestCommands(t)
+	now = func() time.Time { return time.Date(2026, 3, 4, 9, 30, 0, 0, time.Local) }
+	t.Cleanup(func() { now = time.Now })
+
+	folder, err := cmds.CreateFolder(strPtr("Meetings"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	tmpl, err := cmds.CreateNote(strPtr("Meeting {{date}}"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	blocks := []struct {
+		typ     string
+		content map[string]string
+	}{
+		{"text", map[string]string{"text": "# {{title}} in {{folder}} at {{time}} with {{ attendees }} {{unknown}}"}},
+		{"image", map[string]string{"url": "noteblock-image:///{{date}}.png"}},
+		{"text", map[string]string{"text": "Notes"}},
+	}
+	for i, b := range blocks {
+		if _, err := cmds.CreateBlock(tmpl.ID, b.typ, i, b.content); err != nil {
+			t.Fatalf("CreateBlock: %v", err)
+		}
+	}
+
+	_, err = cmds.CreateNoteFromTemplate(tmpl.ID, nil, nil, nil)
+	expectKind(t, err, KindValidation)
+
+	if _, err := cmds.SetTemplate(tmpl.ID, true); err != nil {
+		t.Fatalf("SetTemplate: %v", err)
+	}
+	templates, err := cmds.ListTemplates()
+	if err != nil {
+		t.Fatalf("ListTemplates: %v", err)
+	}
+	if len(templates) != 1 || templates[0].BlockCount != 3 {
+		t.Fatalf("unexpected templates: %+v", templates)
+	}
+	if got := strings.Join(templates[0].Variables, ","); got != "attendees,date,folder,time,title,unknown" {
+		t.Fatalf("unexpected variables %q", got)
+	}
+
+	vars := map[string]string{"attendees": "Ana"}
+	note, err := cmds.CreateNoteFromTemplate(tmpl.ID, &folder.ID, nil, vars)
+	if err != nil {
+		t.Fatalf("CreateNoteFromTemplate: %v", err)
+	}
+	if note.Title != "Meeting 2026-03-04" || note.FolderID != folder.ID || note.IsTemplate {
+		t.Fatalf("unexpected note %+v", note)
+	}
+	if len(note.Blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(note.Blocks))
+	}
+	want := []string{
+		`{"text":"# Meeting 2026-03-04 in Meetings at 09:30 with Ana {{unknown}}"}`,
+		`{"url":"noteblock-image:///{{date}}.png"}`,
+		`{"text":"Notes"}`,
+	}
+	for i, b := range note.Blocks {
+		if b.Type != blocks[i].typ || string(b.Content) != want[i] {
+			t.Fatalf("block %d = %s %s, want %s %s", i, b.Type, b.Content, blocks[i].typ, want[i])
+		}
+	}
+
+	again, err := cmds.CreateNoteFromTemplate(tmpl.ID, &folder.ID, nil, vars)
+	if err != nil {
+		t.Fatalf("CreateNoteFromTemplate again: %v", err)
+	}
+	if again.Title != "Meeting 2026-03-04 2" {
+		t.Fatalf("expected numbered title, got %q", again.Title)
+	}
+	_, err = cmds.CreateNoteFromTemplate(tmpl.ID, &folder.ID, strPtr("Meeting {{date}}"), vars)
+	expectKind(t, err, KindConflict)
+}
+
+func TestJournalDays(t *testing.T) {
+	cmds := setupTestCommands(t)
+	now = func() time.Time { return time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC) }
+	t.Cleanup(func() { now = time.Now })
+
+	date, march, created, err := cmds.JournalToday("UTC")
+	if err != nil {
+		t.Fatalf("JournalToday: %v", err)
+	}
+	if date != "2026-03-31" || !created || march.Title != "2026-03-31" {
+		t.Fatalf("unexpected entry %s %v %+v", date, created, march)
+	}
+	folder, err := cmds.Folders.GetFolderByID(march.FolderID)
+	if err != nil || folder.Name != DefaultJournalFolderName {
+		t.Fatalf("expected note in journal folder, got %+v (%v)", folder, err)
+	}
+	_, again, created, err := cmds.JournalToday("UTC")
+	if err != nil || created || again.ID != march.ID {
+		t.Fatalf("expected the same entry, got %+v created=%v (%v)", again, created, err)
+	}
+
+	date, _, _, err = cmds.JournalToday("Asia/Tokyo")
+	if err != nil || date != "2026-04-01" {
+		t.Fatalf("expected the next day in Tokyo, got %q (%v)", date, err)
+	}
+	_, _, _, err = cmds.JournalToday("Mars/Olympus")
+	expectKind(t, err, KindValidation)
+	_, _, err = cmds.JournalDay("31/03/2026")
+	expectKind(t, err, KindValidation)
+
+	_, err = cmds.ConfigureJournal(nil, strPtr("Monday"), nil)
+	expectKind(t, err, KindValidation)
+	tmpl, err := cmds.CreateNote(strPtr("Standup"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if _, err := cmds.CreateBlock(tmpl.ID, "text", 0, map[string]string{"text": "Standup {{date}}"}); err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+	_, err = cmds.ConfigureJournal(nil, nil, &tmpl.ID)
+	expectKind(t, err, KindValidation)
+	if _, err := cmds.SetTemplate(tmpl.ID, true); err != nil {
+		t.Fatalf("SetTemplate: %v", err)
+	}
+	cfg, err := cmds.ConfigureJournal(nil, strPtr("Monday, January 2 2006"), &tmpl.ID)
+	if err != nil {
+		t.Fatalf("ConfigureJournal: %v", err)
+	}
+	if cfg.TitleFormat != "Monday, January 2 2006" || cfg.TemplateID != tmpl.ID {
+		t.Fatalf("unexpected config %+v", cfg)
+	}
+
+	fromTemplate, created, err := cmds.JournalDay("2026-03-02")
+	if err != nil || !created {
+		t.Fatalf("JournalDay: created=%v (%v)", created, err)
+	}
+	if fromTemplate.Title != "Monday, March 2 2026" || len(fromTemplate.Blocks) != 1 ||
+		string(fromTemplate.Blocks[0].Content) != `{"text":"Standup 2026-03-02"}` {
+		t.Fatalf("unexpected templated entry %+v", fromTemplate)
+	}
+
+	manual, err := cmds.CreateNote(strPtr("Tuesday, March 3 2026"), &folder.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	adopted, created, err := cmds.JournalDay("2026-03-03")
+	if err != nil || created || adopted.ID != manual.ID {
+		t.Fatalf("expected the hand-made note to be adopted, got %+v created=%v (%v)", adopted, created, err)
+	}
+
+	entries, err := cmds.JournalRange("2026-03")
+	if err != nil {
+		t.Fatalf("JournalRange: %v", err)
+	}
+	var dates []string
+	for _, e := range entries {
+		dates = append(dates, e.Date)
+	}
+	if got := strings.Join(dates, ","); got != "2026-03-02,2026-03-03,2026-03-31" {
+		t.Fatalf("unexpected range %q", got)
+	}
+
+	if err := cmds.DeleteNote(march.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	recreated, created, err := cmds.JournalDay("2026-03-31")
+	if err != nil || !created || recreated.ID == march.ID {
+		t.Fatalf("expected a new entry after deletion, got %+v created=%v (%v)", recreated, created, err)
+	}
+}
+
+func TestInlineTags(t *testing.T) {
+	text := "#Plan for #q3-roadmap/ and #q3-Roadmap, not #123, a&#39;b, x#y, http://a.b/#frag, `#code` or\n```\n#fenced\n```\n## heading #done"
+	blocks := []model.Block{
+		{Type: "text", Content: mustJSON(t, map[string]string{"text": text})},
+		{Type: "image", Content: mustJSON(t, map[string]string{"alt": "#ignored"})},
+	}
+	if got := strings.Join(inlineTags(blocks), ","); got != "done,plan,q3-roadmap" {
+		t.Fatalf("inlineTags = %q", got)
+	}
+	if got := renameHashtags("#Plan and #planning and `#plan`", "plan", "goals"); got != "#goals and #planning and `#plan`" {
+		t.Fatalf("renameHashtags = %q", got)
+	}
+}
+
+func TestTags(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	first, err := cmds.CreateNote(strPtr("First"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	second, err := cmds.CreateNote(strPtr("Second"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	block, err := cmds.CreateBlock(first.ID, "text", 0, map[string]string{"text": "ship #Release & #docs"})
+	if err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+
+	_, err = cmds.SetNoteTags(first.ID, []string{"has space"})
+	expectKind(t, err, KindValidation)
+	if _, err := cmds.SetNoteTags(first.ID, []string{"#Work", "urgent"}); err != nil {
+		t.Fatalf("SetNoteTags: %v", err)
+	}
+	if _, err := cmds.AddNoteTags(second.ID, []string{"work"}); err != nil {
+		t.Fatalf("AddNoteTags: %v", err)
+	}
+
+	// extraction is off until configured
+	tags, err := cmds.NoteTags(first.ID)
+	if err != nil || len(tags) != 2 {
+		t.Fatalf("expected only manual tags, got %+v (%v)", tags, err)
+	}
+	if _, err := cmds.ConfigureTags(true); err != nil {
+		t.Fatalf("ConfigureTags: %v", err)
+	}
+	assertTags := func(noteID, want string) {
+		t.Helper()
+		tags, err := cmds.NoteTags(noteID)
+		if err != nil {
+			t.Fatalf("NoteTags: %v", err)
+		}
+		var got []string
+		for _, tag := range tags {
+			got = append(got, tag.Name+":"+tag.Source)
+		}
+		if strings.Join(got, ",") != want {
+			t.Fatalf("tags = %v, want %s", got, want)
+		}
+	}
+	assertTags(first.ID, "docs:inline,release:inline,urgent:manual,work:manual")
+
+	if _, err := cmds.UpdateBlock(first.ID, block.ID, "text", map[string]string{"text": "ship #release #urgent"}); err != nil {
+		t.Fatalf("UpdateBlock: %v", err)
+	}
+	assertTags(first.ID, "release:inline,urgent:manual,work:manual")
+	if _, err := cmds.RemoveNoteTags(first.ID, []string{"urgent", "work"}); err != nil {
+		t.Fatalf("RemoveNoteTags: %v", err)
+	}
+	assertTags(first.ID, "release:inline,urgent:inline")
+
+	_, err = cmds.RenameTag("release", "urgent")
+	expectKind(t, err, KindConflict)
+	renamed, err := cmds.RenameTag("Release", "launch")
+	if err != nil || renamed.Name != "launch" || renamed.Count != 1 {
+		t.Fatalf("RenameTag: %+v (%v)", renamed, err)
+	}
+	note, err := cmds.GetNote(first.ID)
+	if err != nil {
+		t.Fatalf("GetNote: %v", err)
+	}
+	if string(note.Blocks[0].Content) != `{"text":"ship #launch #urgent"}` {
+		t.Fatalf("inline tag not rewritten: %s", note.Blocks[0].Content)
+	}
+	assertTags(first.ID, "launch:inline,urgent:inline")
+
+	if _, err := cmds.AddNoteTags(second.ID, []string{"launch"}); err != nil {
+		t.Fatalf("AddNoteTags: %v", err)
+	}
+	merged, err := cmds.MergeTags([]string{"launch", "urgent"}, "work")
+	if err != nil || merged.Count != 2 {
+		t.Fatalf("MergeTags: %+v (%v)", merged, err)
+	}
+	assertTags(first.ID, "work:inline")
+	assertTags(second.ID, "work:manual")
+	_, err = cmds.MergeTags([]string{"missing"}, "work")
+	expectKind(t, err, KindNotFound)
+
+	list, err := cmds.ListTags()
+	if err != nil || len(list) != 1 || list[0].Name != "work" || list[0].Count != 2 {
+		t.Fatalf("ListTags: %+v (%v)", list, err)
+	}
+
+	if _, err := cmds.AddNoteTags(second.ID, []string{"extra"}); err != nil {
+		t.Fatalf("AddNoteTags: %v", err)
+	}
+	all, err := cmds.ListNotesByTags([]string{"work", "extra"}, "all", 0)
+	if err != nil || len(all) != 1 || all[0].ID != second.ID {
+		t.Fatalf("all: %+v (%v)", all, err)
+	}
+	anyOf, err := cmds.ListNotesByTags([]string{"extra", "work", "missing"}, "any", 0)
+	if err != nil || len(anyOf) != 2 {
+		t.Fatalf("any: %+v (%v)", anyOf, err)
+	}
+	_, err = cmds.ListNotesByTags([]string{"work"}, "some", 0)
+	expectKind(t, err, KindValidation)
+
+	if _, err := cmds.ConfigureTags(false); err != nil {
+		t.Fatalf("ConfigureTags: %v", err)
+	}
+	assertTags(first.ID, "")
+}
+
+func mustJSON(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	return string(b)
+}
+
+func TestWikiLinks(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	source, err := cmds.CreateNote(strPtr("Source"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	plan, err := cmds.CreateNote(strPtr("Project Plan"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	text := "see [[project plan|the plan]], [[Missing Note]], `[[code]]` and [[" + plan.ID + "]]"
+	block, err := cmds.CreateBlock(source.ID, "text", 0, map[string]string{"text": text})
+	if err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+
+	outgoing, err := cmds.OutgoingLinks(source.ID)
+	if err != nil {
+		t.Fatalf("OutgoingLinks: %v", err)
+	}
+	if len(outgoing) != 3 || outgoing[0].Label != "the plan" || !outgoing[0].Resolved || *outgoing[0].TargetNoteID != plan.ID ||
+		outgoing[1].Resolved || !outgoing[2].Resolved || *outgoing[2].TargetTitle != "Project Plan" {
+		t.Fatalf("unexpected outgoing links %+v", outgoing)
+	}
+	backlinks, err := cmds.Backlinks(plan.ID)
+	if err != nil || len(backlinks) != 2 || backlinks[0].NoteID != source.ID || backlinks[0].BlockID != block.ID {
+		t.Fatalf("unexpected backlinks %+v (%v)", backlinks, err)
+	}
+	unresolved, err := cmds.UnresolvedLinks()
+	if err != nil || len(unresolved) != 1 || unresolved[0].Target != "Missing Note" || unresolved[0].NoteIDs[0] != source.ID {
+		t.Fatalf("unexpected unresolved links %+v (%v)", unresolved, err)
+	}
+
+	created, err := cmds.CreateNote(strPtr("missing note"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if unresolved, _ := cmds.UnresolvedLinks(); len(unresolved) != 0 {
+		t.Fatalf("expected the new note to resolve the link, got %+v", unresolved)
+	}
+	if backlinks, _ := cmds.Backlinks(created.ID); len(backlinks) != 1 {
+		t.Fatalf("expected a backlink to the new note, got %+v", backlinks)
+	}
+
+	if _, err := cmds.UpdateNote(plan.ID, strPtr("Roadmap"), nil, nil); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	note, err := cmds.GetNote(source.ID)
+	if err != nil {
+		t.Fatalf("GetNote: %v", err)
+	}
+	want := mustJSON(t, map[string]string{"text": "see [[Roadmap|the plan]], [[Missing Note]], `[[code]]` and [[" + plan.ID + "]]"})
+	if string(note.Blocks[0].Content) != want {
+		t.Fatalf("links not rewritten: %s", note.Blocks[0].Content)
+	}
+	if backlinks, _ := cmds.Backlinks(plan.ID); len(backlinks) != 2 {
+		t.Fatalf("expected links to survive the rename, got %+v", backlinks)
+	}
+
+	if err := cmds.DeleteNote(plan.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	if unresolved, _ := cmds.UnresolvedLinks(); len(unresolved) != 2 {
+		t.Fatalf("expected links to the deleted note to dangle, got %+v", unresolved)
+	}
+
+	// a vault from before links were tracked is indexed once on open
+	cmds.Links.DB.Where("1 = 1").Delete(&model.Link{})
+	if err := cmds.Settings.Set(settingLinkIndex, ""); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := cmds.IndexLinks(); err != nil {
+		t.Fatalf("IndexLinks: %v", err)
+	}
+	if outgoing, _ := cmds.OutgoingLinks(source.ID); len(outgoing) != 3 {
+		t.Fatalf("expected links to be reindexed, got %+v", outgoing)
+	}
+}
+
+func TestRetitleLinksSharedTitle(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	sub, err := cmds.CreateFolder(strPtr("Sub"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	create := func(title string, folderID *string) *model.Note {
+		t.Helper()
+		note, err := cmds.CreateNote(strPtr(title), folderID)
+		if err != nil {
+			t.Fatalf("CreateNote(%q): %v", title, err)
+		}
+		return note
+	}
+	rootPlan := create("Plan", nil)
+	subPlan := create("Plan", &sub.ID)
+	// SQLite only folds ASCII case, so these are two different link targets
+	create("Été", nil)
+	subSummer := create("été", &sub.ID)
+
+	rootSource := create("Root Source", nil)
+	subSource := create("Sub Source", &sub.ID)
+	for id, text := range map[string]string{
+		rootSource.ID: "[[Plan]], [[Été]] and [[été|summer]]",
+		subSource.ID:  "[[Plan]]",
+	} {
+		if _, err := cmds.CreateBlock(id, "text", 0, map[string]string{"text": text}); err != nil {
+			t.Fatalf("CreateBlock: %v", err)
+		}
+	}
+	if backlinks, _ := cmds.Backlinks(rootPlan.ID); len(backlinks) != 1 || backlinks[0].NoteID != rootSource.ID {
+		t.Fatalf("expected the root source to link the root plan, got %+v", backlinks)
+	}
+
+	if _, err := cmds.UpdateNote(subPlan.ID, strPtr("Roadmap"), nil, nil); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if _, err := cmds.UpdateNote(subSummer.ID, strPtr("Summer"), nil, nil); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+
+	text := func(id string) string {
+		t.Helper()
+		note, err := cmds.GetNote(id)
+		if err != nil {
+			t.Fatalf("GetNote: %v", err)
+		}
+		return string(note.Blocks[0].Content)
+	}
+	if got, want := text(rootSource.ID), mustJSON(t, map[string]string{"text": "[[Plan]], [[Été]] and [[Summer|summer]]"}); got != want {
+		t.Fatalf("links to other notes were rewritten: %s", got)
+	}
+	if got, want := text(subSource.ID), mustJSON(t, map[string]string{"text": "[[Roadmap]]"}); got != want {
+		t.Fatalf("link to the renamed note not rewritten: %s", got)
+	}
+	if backlinks, _ := cmds.Backlinks(rootPlan.ID); len(backlinks) != 1 {
+		t.Fatalf("expected the root plan to keep its backlink, got %+v", backlinks)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	sub, err := cmds.CreateFolder(strPtr("Sub"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	notes := map[string]string{}
+	for _, title := range []string{"A", "B", "C"} {
+		note, err := cmds.CreateNote(strPtr(title), nil)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		notes[title] = note.ID
+	}
+	d, err := cmds.CreateNote(strPtr("D"), &sub.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	notes["D"] = d.ID
+	for title, text := range map[string]string{"A": "[[B]] and [[b|again]] #x", "B": "[[C]]"} {
+		if _, err := cmds.CreateBlock(notes[title], "text", 0, map[string]string{"text": text}); err != nil {
+			t.Fatalf("CreateBlock: %v", err)
+		}
+	}
+	if _, err := cmds.AddNoteTags(notes["A"], []string{"x"}); err != nil {
+		t.Fatalf("AddNoteTags: %v", err)
+	}
+
+	labels := func(g *dto.Graph) string {
+		var out []string
+		for _, n := range g.Nodes {
+			out = append(out, fmt.Sprintf("%s:%d/%d", n.Label, n.InDegree, n.OutDegree))
+		}
+		return strings.Join(out, ",")
+	}
+	graph := func(opts GraphOptions) *dto.Graph {
+		t.Helper()
+		g, err := cmds.Graph(opts)
+		if err != nil {
+			t.Fatalf("Graph(%+v): %v", opts, err)
+		}
+		return g
+	}
+
+	whole := graph(GraphOptions{})
+	if got := labels(whole); got != "A:0/1,B:1/1,C:1/0,D:0/0" {
+		t.Fatalf("whole graph = %s", got)
+	}
+	weights := map[string]int{}
+	for _, e := range whole.Edges {
+		weights[e.Source+">"+e.Target] = e.Weight
+	}
+	if weights[notes["A"]+">"+notes["B"]] != 2 || weights[notes["B"]+">"+notes["C"]] != 1 {
+		t.Fatalf("expected a weighted A->B edge, got %+v", whole.Edges)
+	}
+	if got := labels(graph(GraphOptions{NoteID: notes["A"]})); got != "A:0/1,B:1/0" {
+		t.Fatalf("one hop = %s", got)
+	}
+	twoHops := graph(GraphOptions{NoteID: notes["C"], Depth: 2})
+	if got := labels(twoHops); got != "A:0/1,B:1/1,C:1/0" || *twoHops.Nodes[0].Distance != 2 {
+		t.Fatalf("two hops = %s", got)
+	}
+	if got := labels(graph(GraphOptions{HideOrphans: true})); got != "A:0/1,B:1/1,C:1/0" {
+		t.Fatalf("without orphans = %s", got)
+	}
+	if got := labels(graph(GraphOptions{FolderID: sub.ID})); got != "D:0/0" {
+		t.Fatalf("folder filter = %s", got)
+	}
+	if got := labels(graph(GraphOptions{Tags: []string{"#X"}, IncludeTags: true})); got != "A:1/0,x:0/1" {
+		t.Fatalf("tag filter = %s", got)
+	}
+	if got := labels(graph(GraphOptions{FolderID: sub.ID, IncludeFolders: true})); got != "D:1/0,Sub:1/1,Root:0/1" {
+		t.Fatalf("folders = %s", got)
+	}
+
+	// a placed note belongs to the folder it is placed in as well as its own
+	if _, err := cmds.PlaceNote(notes["A"], sub.ID); err != nil {
+		t.Fatalf("PlaceNote: %v", err)
+	}
+	if got := labels(graph(GraphOptions{FolderID: sub.ID})); got != "A:0/0,D:0/0" {
+		t.Fatalf("folder filter with a placed note = %s", got)
+	}
+	if got := labels(graph(GraphOptions{FolderID: sub.ID, IncludeFolders: true})); got != "A:2/0,D:1/0,Root:0/2,Sub:1/2" {
+		t.Fatalf("folders with a placed note = %s", got)
+	}
+
+	_, err = cmds.Graph(GraphOptions{NoteID: notes["A"], Depth: MaxGraphDepth + 1})
+	expectKind(t, err, KindValidation)
+	_, err = cmds.Graph(GraphOptions{NoteID: "missing"})
+	expectKind(t, err, KindNotFound)
+}
+
+func TestEmbedBlocks(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	source, err := cmds.CreateNote(strPtr("Source"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	host, err := cmds.CreateNote(strPtr("Host"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	quote, err := cmds.CreateBlock(source.ID, "text", 0, map[string]string{"text": "original"})
+	if err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+	if _, err := cmds.CreateBlock(host.ID, dto.EmbedBlockType, 0, map[string]string{}); err == nil {
+		t.Fatal("expected an embed without block_id to be rejected")
+	}
+	embed, err := cmds.CreateBlock(host.ID, dto.EmbedBlockType, 0, dto.EmbedContent{BlockID: quote.ID})
+	if err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+	if _, err := cmds.UpdateBlock(host.ID, embed.ID, dto.EmbedBlockType, dto.EmbedContent{BlockID: embed.ID}); err == nil {
+		t.Fatal("expected a self embed to be rejected")
+	}
+
+	if _, err := cmds.UpdateBlock(source.ID, quote.ID, "text", map[string]string{"text": "edited"}); err != nil {
+		t.Fatalf("UpdateBlock: %v", err)
+	}
+	note, err := cmds.GetNote(host.ID)
+	if err != nil {
+		t.Fatalf("GetNote: %v", err)
+	}
+	resolved := note.Blocks[0].Embed
+	if resolved == nil || resolved.Status != dto.EmbedOK || resolved.NoteTitle != "Source" || !strings.Contains(string(resolved.Block.Content), "edited") {
+		t.Fatalf("unexpected embed %+v", resolved)
+	}
+
+	// an embed of the host embed from the source closes a cycle
+	back, err := cmds.CreateBlock(source.ID, dto.EmbedBlockType, 1, dto.EmbedContent{BlockID: embed.ID})
+	if err != nil {
+		t.Fatalf("CreateBlock: %v", err)
+	}
+	if _, err := cmds.UpdateBlock(source.ID, quote.ID, dto.EmbedBlockType, dto.EmbedContent{BlockID: back.ID}); err != nil {
+		t.Fatalf("UpdateBlock: %v", err)
+	}
+	note, _ = cmds.GetNote(host.ID)
+	inner := note.Blocks[0].Embed.Block.Embed.Block.Embed
+	if inner == nil || inner.Status != dto.EmbedCycle || inner.Block != nil {
+		t.Fatalf("expected a cycle, got %+v", inner)
+	}
+
+	refs, err := cmds.BlockReferences(back.ID)
+	if err != nil || len(refs) != 1 || refs[0].BlockID != quote.ID || refs[0].NoteTitle != "Source" {
+		t.Fatalf("unexpected references %+v (%v)", refs, err)
+	}
+	orphaned, err := cmds.DeleteBlock(source.ID, quote.ID)
+	if err != nil || orphaned != 1 {
+		t.Fatalf("DeleteBlock = %d, %v", orphaned, err)
+	}
+	note, _ = cmds.GetNote(host.ID)
+	if tombstone := note.Blocks[0].Embed; tombstone.Status != dto.EmbedMissing || tombstone.Block != nil {
+		t.Fatalf("expected a tombstone, got %+v", tombstone)
+	}
+}
+
+func TestNotePlacements(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	projects, err := cmds.CreateFolder(strPtr("Projects"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	alpha, err := cmds.CreateFolder(strPtr("Alpha"), &projects.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	beta, err := cmds.CreateFolder(strPtr("Beta"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	shared, err := cmds.CreateNote(strPtr("Shared"), &alpha.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	local, err := cmds.CreateNote(strPtr("Local"), &alpha.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	placements, err := cmds.PlaceNote(shared.ID, beta.ID)
+	if err != nil || len(placements) != 2 || !placements[0].Primary || placements[1].FolderID != beta.ID || placements[1].FolderName != "Beta" {
+		t.Fatalf("unexpected placements %+v (%v)", placements, err)
+	}
+	if _, err := cmds.CreateNote(strPtr("Local"), &beta.ID); err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	_, err = cmds.PlaceNote(local.ID, beta.ID)
+	expectKind(t, err, KindConflict)
+	_, err = cmds.UnplaceNote(shared.ID, alpha.ID)
+	expectKind(t, err, KindValidation)
+
+	tree, err := cmds.GetFolderTree(beta.ID)
+	if err != nil {
+		t.Fatalf("GetFolderTree: %v", err)
+	}
+	listed := map[string]bool{}
+	for _, n := range tree.Notes {
+		listed[n.Title] = n.Primary
+	}
+	if primary, ok := listed["Shared"]; !ok || primary || !listed["Local"] {
+		t.Fatalf("unexpected notes in Beta %+v", tree.Notes)
+	}
+
+	// deleting the primary folder keeps the note in its other placement
+	if _, err := cmds.DeleteFolder(projects.ID); err != nil {
+		t.Fatalf("DeleteFolder: %v", err)
+	}
+	if _, err := cmds.GetNote(local.ID); err == nil {
+		t.Fatal("expected the unplaced note to be deleted with its folder")
+	}
+	placements, err = cmds.NotePlacements(shared.ID)
+	if err != nil || len(placements) != 1 || placements[0].FolderID != beta.ID || !placements[0].Primary {
+		t.Fatalf("expected Beta to become the primary folder, got %+v (%v)", placements, err)
+	}
+
+	if _, err := cmds.DeleteFolder(beta.ID); err != nil {
+		t.Fatalf("DeleteFolder: %v", err)
+	}
+	if _, err := cmds.GetNote(shared.ID); err == nil {
+		t.Fatal("expected the note to go with its last placement")
+	}
+}
+
+func TestFolderChildren(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	top, err := cmds.CreateFolder(strPtr("Top"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	mid, err := cmds.CreateFolder(strPtr("Mid"), &top.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	if _, err := cmds.CreateFolder(strPtr("Leaf"), &mid.ID); err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	deep, err := cmds.CreateNote(strPtr("Deep"), &mid.ID)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if _, err := cmds.PlaceNote(deep.ID, top.ID); err != nil {
+		t.Fatalf("PlaceNote: %v", err)
+	}
+
+	tree, err := cmds.GetFolderTree(RootFolderID)
+	if err != nil {
+		t.Fatalf("GetFolderTree: %v", err)
+	}
+	if len(tree.Children) != 1 || len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].Children[0].Name != "Leaf" ||
+		len(tree.Children[0].Children[0].Notes) != 1 || !tree.Children[0].Children[0].Notes[0].Primary {
+		t.Fatalf("unexpected tree %s", mustJSON(t, tree))
+	}
+
+	level, err := cmds.FolderChildren(top.ID)
+	if err != nil {
+		t.Fatalf("FolderChildren: %v", err)
+	}
+	if len(level.Children) != 1 || level.Children[0].ID != mid.ID || level.Children[0].ChildCount != 1 || level.Children[0].NoteCount != 1 {
+		t.Fatalf("unexpected children %+v", level.Children)
+	}
+	if len(level.Notes) != 1 || level.Notes[0].ID != deep.ID || level.Notes[0].Primary {
+		t.Fatalf("unexpected notes %+v", level.Notes)
+	}
+	_, err = cmds.FolderChildren("missing")
+	expectKind(t, err, KindNotFound)
+}
+
+func TestFolderSortOrder(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	box, err := cmds.CreateFolder(strPtr("Box"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	ids := map[string]string{}
+	for _, title := range []string{"Note 10", "note 2", "Note 1"} {
+		note, err := cmds.CreateNote(strPtr(title), &box.ID)
+		if err != nil {
+			t.Fatalf("CreateNote: %v", err)
+		}
+		ids[title] = note.ID
+	}
+	titles := func(notes []dto.NoteResponse) string {
+		var out []string
+		for _, n := range notes {
+			out = append(out, n.Title)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tree, err := cmds.GetFolderTree(box.ID)
+	if err != nil {
+		t.Fatalf("GetFolderTree: %v", err)
+	}
+	if got := titles(tree.Notes); got != "Note 1,note 2,Note 10" || tree.SortBy != "name" {
+		t.Fatalf("natural order = %s (%s)", got, tree.SortBy)
+	}
+	level, err := cmds.SetFolderSort(box.ID, "name", true)
+	if err != nil || titles(level.Notes) != "Note 10,note 2,Note 1" {
+		t.Fatalf("descending order = %+v (%v)", level, err)
+	}
+	_, err = cmds.SetFolderSort(box.ID, "size", false)
+	expectKind(t, err, KindValidation)
+
+	_, err = cmds.ReorderFolder(box.ID, nil, []string{ids["note 2"], ids["Note 1"]})
+	expectKind(t, err, KindValidation)
+	level, err = cmds.ReorderFolder(box.ID, nil, []string{ids["note 2"], ids["Note 10"], ids["Note 1"]})
+	if err != nil || level.SortBy != "manual" || titles(level.Notes) != "note 2,Note 10,Note 1" {
+		t.Fatalf("manual order = %+v (%v)", level, err)
+	}
+
+	// notes without a key, new or moved in, follow the keyed ones oldest first
+	if _, err := cmds.CreateNote(strPtr("Fresh"), &box.ID); err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if _, err := cmds.UpdateNote(ids["note 2"], nil, strPtr(RootFolderID), nil); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	if _, err := cmds.UpdateNote(ids["note 2"], nil, &box.ID, nil); err != nil {
+		t.Fatalf("UpdateNote: %v", err)
+	}
+	tree, _ = cmds.GetFolderTree(box.ID)
+	if got := titles(tree.Notes); got != "Note 10,Note 1,note 2,Fresh" {
+		t.Fatalf("manual order after changes = %s", got)
+	}
+}
+
+func TestMoveFolders(t *testing.T) {
+	cmds := setupTestCommands(t)
+
+	outer, err := cmds.CreateFolder(strPtr("Outer"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	inner, err := cmds.CreateFolder(strPtr("Inner"), &outer.ID)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	other, err := cmds.CreateFolder(strPtr("Other"), nil)
+	if err != nil {
+		t.Fatalf("CreateFolder: %v", err)
+	}
+	note, err := cmds.CreateNote(strPtr("Loose"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+
+	root := RootFolderID
+	_, err = cmds.UpdateFolder(&outer.ID, nil, &inner.ID)
+	expectKind(t, err, KindInvalidMove)
+	_, err = cmds.UpdateFolder(&outer.ID, nil, &outer.ID)
+	expectKind(t, err, KindInvalidMove)
+	_, err = cmds.UpdateFolder(&root, nil, &other.ID)
+	expectKind(t, err, KindInvalidMove)
+	renamed, err := cmds.UpdateFolder(&root, strPtr("Vault"), nil)
+	if err != nil || renamed.Name != "Vault" || renamed.ParentID != nil {
+		t.Fatalf("renaming root = %+v (%v)", renamed, err)
+	}
+
+	// one bad move undoes the whole batch
+	_, err = cmds.MoveItems(inner.ID, []string{other.ID, outer.ID}, []string{note.ID})
+	expectKind(t, err, KindInvalidMove)
+	if meta, _ := cmds.Folders.GetFolderByID(other.ID); *meta.ParentID != RootFolderID {
+		t.Fatalf("expected Other to stay in root, got %v", *meta.ParentID)
+	}
+
+	level, err := cmds.MoveItems(inner.ID, []string{other.ID}, []string{note.ID})
+	if err != nil || len(level.Children) != 1 || level.Children[0].ID != other.ID || len(level.Notes) != 1 || level.Notes[0].ID != note.ID {
+		t.Fatalf("MoveItems = %+v (%v)", level, err)
+	}
+	_, err = cmds.MoveItems(other.ID, []string{outer.ID}, nil)
+	expectKind(t, err, KindInvalidMove)
+}
+
+func TestVaultCheck(t *testing.T) {
+	cmds := setupTestCommands(t)
+	db := cmds.Notes.DB
+
+	clean, err := cmds.CheckVault(false)
+	if err != nil || !clean.OK || len(clean.Findings) != 0 {
+		t.Fatalf("fresh vault check = %+v (%v)", clean, err)
+	}
+
+	kept, err := cmds.CreateNote(strPtr("Kept"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	// the test connection leaves foreign keys off, so damage can be planted
+	gone := "00000000-gone"
+	for _, stmt := range []string{
+		`INSERT INTO folders (id, name, parent_id, sort_by) VALUES ('lost', 'Lost', '` + gone + `', 'name')`,
+		`INSERT INTO folders (id, name, parent_id, sort_by) VALUES ('loop-a', 'A', 'loop-b', 'name'), ('loop-b', 'B', 'loop-a', 'name')`,
+		`INSERT INTO notes (id, title, folder_id) VALUES ('stray', 'Stray', '` + gone + `')`,
+		`INSERT INTO blocks (id, note_id, type, content, order_key) VALUES ('orphan', '` + gone + `', 'text', '{"text":"hi"}', 'a0')`,
+		`INSERT INTO blocks (id, note_id, type, content, order_key) VALUES ('broken', '` + kept.ID + `', 'text', '{"text":', 'a0')`,
+		`INSERT INTO placements (note_id, folder_id) VALUES ('` + gone + `', 'root')`,
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	kinds := func(res *dto.VaultCheckDTO) map[string]int {
+		got := map[string]int{}
+		for _, f := range res.Findings {
+			got[f.Kind]++
+		}
+		return got
+	}
+	want := map[string]int{
+		dto.FindingOrphanFolder: 1,
+		dto.FindingFolderCycle:  1,
+		dto.FindingOrphanNote:   1,
+		dto.FindingOrphanBlock:  1,
+		dto.FindingCorruptBlock: 1,
+		dto.FindingDanglingRow:  1,
+	}
+
+	// a corrupt block no longer keeps its note from loading
+	if note, err := cmds.GetNote(kept.ID); err != nil || note.Blocks[0].Type != dto.QuarantinedBlockType {
+		t.Fatalf("note with corrupt block = %+v (%v)", note, err)
+	}
+
+	report, err := cmds.CheckVault(false)
+	if err != nil || report.OK || report.Repaired {
+		t.Fatalf("check = %+v (%v)", report, err)
+	}
+	if got := kinds(report); !reflect.DeepEqual(got, want) {
+		t.Fatalf("findings = %v, want %v", got, want)
+	}
+	if report.RecoveredFolderID != nil {
+		t.Fatal("a check without repair must not create Recovered")
+	}
+
+	repaired, err := cmds.CheckVault(true)
+	if err != nil || !repaired.Repaired || repaired.RecoveredFolderID == nil {
+		t.Fatalf("repair = %+v (%v)", repaired, err)
+	}
+	if got := kinds(repaired); !reflect.DeepEqual(got, want) {
+		t.Fatalf("repaired findings = %v, want %v", got, want)
+	}
+	for _, f := range repaired.Findings {
+		if f.Repair == "" {
+			t.Fatalf("finding %+v was not repaired", f)
+		}
+	}
+
+	after, err := cmds.CheckVault(false)
+	if err != nil || !after.OK {
+		t.Fatalf("check after repair = %+v (%v)", after, err)
+	}
+
+	recovered, err := cmds.FolderChildren(*repaired.RecoveredFolderID)
+	if err != nil {
+		t.Fatalf("FolderChildren: %v", err)
+	}
+	var folders, notes []string
+	for _, c := range recovered.Children {
+		folders = append(folders, c.Name)
+	}
+	for _, n := range recovered.Notes {
+		notes = append(notes, n.Title)
+	}
+	if !reflect.DeepEqual(folders, []string{"A", "Lost"}) {
+		t.Fatalf("recovered folders = %v", folders)
+	}
+	if !reflect.DeepEqual(notes, []string{"Recovered note 00000000", "Stray"}) {
+		t.Fatalf("recovered notes = %v", notes)
+	}
+
+	note, err := cmds.GetNote(kept.ID)
+	if err != nil || len(note.Blocks) != 1 || note.Blocks[0].Type != dto.QuarantinedBlockType {
+		t.Fatalf("kept note = %+v (%v)", note, err)
+	}
+	var quarantined dto.QuarantinedContent
+	if err := json.Unmarshal(note.Blocks[0].Content, &quarantined); err != nil || quarantined != (dto.QuarantinedContent{Type: "text", Raw: `{"text":`}) {
+		t.Fatalf("quarantined content = %s (%v)", note.Blocks[0].Content, err)
+	}
+
+	// a second repair reuses the Recovered folder
+	again, err := cmds.CheckVault(true)
+	if err != nil || !again.OK || again.RecoveredFolderID != nil {
+		t.Fatalf("second repair = %+v (%v)", again, err)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	cmds := setupTestCommands(t)
+	var file string
+	if err := cmds.Notes.DB.Raw("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file).Error; err != nil {
+		t.Fatalf("database_list: %v", err)
+	}
+	dataDir := filepath.Dir(file)
+	images := filepath.Join(dataDir, "uploads", "images")
+	if err := os.MkdirAll(images, os.ModePerm); err != nil {
+		t.Fatalf("MkdirAll: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(images, "kept.png"), []byte("png"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	kept, err := cmds.CreateNote(strPtr("Kept"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	backup, err := cmds.CreateBackup()
+	if err != nil || backup.Kind != dto.BackupManual || backup.Images != 1 || backup.SchemaVersion != localdb.SchemaVersion {
+		t.Fatalf("CreateBackup = %+v (%v)", backup, err)
+	}
+
+	// change the vault after the backup, then roll it back
+	if err := cmds.DeleteNote(kept.ID); err != nil {
+		t.Fatalf("DeleteNote: %v", err)
+	}
+	later, err := cmds.CreateNote(strPtr("Later"), nil)
+	if err != nil {
+		t.Fatalf("CreateNote: %v", err)
+	}
+	if err := os.Remove(filepath.Join(images, "kept.png")); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := cmds.ConfigureBackups(dto.BackupConfig{Schedule: dto.BackupHourly, KeepHourly: 2}); err != nil {
+		t.Fatalf("ConfigureBackups: %v", err)
+	}
+	lastSeq, _ := cmds.Changes.LastSeq()
+
+	res, err := cmds.RestoreBackup(backup.ID)
+	if err != nil || res.Restored.ID != backup.ID || res.Safety.Kind != dto.BackupPreRestore {
+		t.Fatalf("RestoreBackup = %+v (%v)", res, err)
+	}
+	if _, err := cmds.GetNote(kept.ID); err != nil {
+		t.Fatalf("restored note is missing: %v", err)
+	}
+	_, err = cmds.GetNote(later.ID)
+	expectKind(t, err, KindNotFound)
+	if _, err := os.Stat(filepath.Join(images, "kept.png")); err != nil {
+		t.Fatalf("restored image is missing: %v", err)
+	}
+	if cfg, _ := cmds.BackupConfig(); cfg.Schedule != dto.BackupHourly {
+		t.Fatalf("restore rolled back the backup settings to %+v", cfg)
+	}
+	changes, err := cmds.ListChanges(lastSeq, 10)
+	if err != nil || len(changes) != 1 || changes[0].Action != "backup.restore" {
+		t.Fatalf("changes after restore = %+v (%v)", changes, err)
+	}
+
+	// the safety backup undoes the restore
+	if _, err := cmds.RestoreBackup(res.Safety.ID); err != nil {
+		t.Fatalf("undoing restore: %v", err)
+	}
+	if _, err := cmds.GetNote(later.ID); err != nil {
+		t.Fatalf("undo lost the newer note: %v", err)
+	}
+
+	_, err = cmds.RestoreBackup("../" + backup.ID)
+	expectKind(t, err, KindNotFound)
+
+	// a backup that rotted on disk is refused and the vault left alone
+	if err := os.WriteFile(filepath.Join(dataDir, service.BackupsDirName, backup.ID, localdb.FileName), []byte("not a database"), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	_, err = cmds.RestoreBackup(backup.ID)
+	expectKind(t, err, KindConflict)
+	if _, err := cmds.GetNote(later.ID); err != nil {
+		t.Fatalf("failed restore touched the vault: %v", err)
+	}
+}
+
+func TestScheduledBackups(t *testing.T) {
+	cmds := setupTestCommands(t)
+	if _, err := cmds.ConfigureBackups(dto.BackupConfig{Schedule: dto.BackupHourly, KeepHourly: 2, KeepDaily: 1}); err != nil {
+		t.Fatalf("ConfigureBackups: %v", err)
+	}
+	_, err := cmds.ConfigureBackups(dto.BackupConfig{Schedule: "monthly"})
+	expectKind(t, err, KindValidation)
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.Local)
+	var taken []string
+	for _, at := range []time.Duration{0, time.Hour, 90 * time.Minute, 2 * time.Hour, 3 * time.Hour, 24 * time.Hour} {
+		backup, err := cmds.RunScheduledBackup(start.Add(at))
+		if err != nil {
+			t.Fatalf("RunScheduledBackup(+%v): %v", at, err)
+		}
+		if backup != nil {
+			taken = append(taken, backup.ID)
+		}
+	}
+	if len(taken) != 5 {
+		t.Fatalf("expected a backup every hour but not at +90m, got %v", taken)
+	}
+	if _, err := cmds.CreateBackup(); err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+
+	backups, err := cmds.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups: %v", err)
+	}
+	var kept []string
+	for _, b := range backups {
+		if b.Kind == dto.BackupScheduled {
+			kept = append(kept, b.ID)
+		}
+	}
+	// the two newest hours; the one newest day adds nothing to them
+	if want := []string{taken[4], taken[3]}; !reflect.DeepEqual(kept, want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+	if len(backups) != 3 || backups[0].Kind != dto.BackupManual {
+		t.Fatalf("manual backups must survive rotation, got %+v", backups)
+	}
+}
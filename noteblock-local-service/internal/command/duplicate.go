@@ -0,0 +1,175 @@
+package command
+
+import (
+	"slices"
+
+	"server/internal/model/dto"
+)
+
+// DuplicateNote copies a note and its blocks into folderID, or next to the
+// original when folderID is empty. Blocks keep their image URLs, so the copy
+// shares the original's assets.
+func (c *Commands) DuplicateNote(id string, folderID *string) (*dto.NoteDTO, error) {
+	if id == "" {
+		return nil, validation("Missing note ID")
+	}
+	source, err := c.Notes.GetNoteMetaData(id)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to retrieve note metadata")
+	}
+
+	targetFolderID := source.FolderID
+	if folderID != nil && *folderID != "" {
+		targetFolderID = *folderID
+		if _, err := c.Folders.GetFolderByID(targetFolderID); err != nil {
+			return nil, lookupErr(err, "Destination folder does not exist", "Failed to query destination folder")
+		}
+	}
+
+	existing, err := c.notesListedIn(targetFolderID)
+	if err != nil {
+		return nil, internal("Failed to query notes", err)
+	}
+	title := availableName(source.Title, noteTitles(existing))
+
+	var newID string
+	err = c.Transaction(func(tx *Commands) error {
+		var err error
+		newID, err = tx.copyNote(source.ID, title, targetFolderID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Changes.Append("note.duplicate", "note", newID, newID, map[string]string{"source_note_id": source.ID}); err != nil {
+			return internal("Failed to log duplicate", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.GetNote(newID)
+}
+
+// DuplicateFolder recursively copies a folder with its notes, blocks and
+// subfolders into parentID, or next to the original when parentID is empty,
+// and returns the new subtree.
+func (c *Commands) DuplicateFolder(id string, parentID *string) (*dto.FolderResponse, error) {
+	if id == "" {
+		return nil, validation("Missing folder ID")
+	}
+	if id == RootFolderID {
+		return nil, validation("Cannot duplicate root folder")
+	}
+	source, err := c.Folders.GetFolderByID(id)
+	if err != nil {
+		return nil, lookupErr(err, "Folder not found", "Failed to retrieve folder")
+	}
+
+	targetParentID := RootFolderID
+	if source.ParentID != nil && *source.ParentID != "" {
+		targetParentID = *source.ParentID
+	}
+	if parentID != nil && *parentID != "" {
+		targetParentID = *parentID
+	}
+	if _, err := c.Folders.GetFolderByID(targetParentID); err != nil {
+		return nil, lookupErr(err, "Parent folder does not exist", "Failed to query parent folder")
+	}
+	inside, err := c.isWithinFolder(targetParentID, source.ID)
+	if err != nil {
+		return nil, internal("Failed to query folder ancestry", err)
+	}
+	if inside {
+		return nil, invalidMove("Cannot duplicate a folder into itself")
+	}
+
+	// snapshot the subtree before writing so the copy never sees itself
+	tree, err := c.Folders.GetFolderDtoById(source.ID)
+	if err != nil {
+		return nil, internal("Failed to retrieve folder", err)
+	}
+	siblings, err := c.Folders.ListChildrenByParentId(&targetParentID)
+	if err != nil {
+		return nil, internal("Failed to query folders", err)
+	}
+	name := availableName(source.Name, folderNames(siblings))
+
+	var newID string
+	err = c.Transaction(func(tx *Commands) error {
+		var err error
+		newID, err = tx.copyFolderTree(tree, name, targetParentID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Changes.Append("folder.duplicate", "folder", newID, "", map[string]string{"source_folder_id": source.ID}); err != nil {
+			return internal("Failed to log duplicate", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.GetFolderTree(newID)
+}
+
+func (c *Commands) copyFolderTree(src *dto.FolderResponse, name, parentID string) (string, error) {
+	folder, err := c.Folders.CreateNewFolder(name, &parentID)
+	if err != nil {
+		return "", internal("Failed to copy folder", err)
+	}
+	for _, note := range src.Notes {
+		// a note placed here lives elsewhere; the copy lists it too rather
+		// than forking it
+		if !note.Primary {
+			if err := c.Notes.Place(note.ID, folder.ID); err != nil {
+				return "", internal("Failed to place note", err)
+			}
+			continue
+		}
+		if _, err := c.copyNote(note.ID, note.Title, folder.ID); err != nil {
+			return "", err
+		}
+	}
+	for i := range src.Children {
+		if _, err := c.copyFolderTree(&src.Children[i], src.Children[i].Name, folder.ID); err != nil {
+			return "", err
+		}
+	}
+	return folder.ID, nil
+}
+
+func (c *Commands) copyNote(sourceID, title, folderID string) (string, error) {
+	note, err := c.Notes.NewNote(title, folderID)
+	if err != nil {
+		return "", internal("Failed to copy note", err)
+	}
+	if err := c.Blocks.CopyNoteBlocks(sourceID, note.ID); err != nil {
+		return "", internal("Failed to copy blocks", err)
+	}
+	if err := c.contentChanged(note.ID); err != nil {
+		return "", err
+	}
+	if err := c.noteTitled(note.ID, note.Title); err != nil {
+		return "", err
+	}
+	return note.ID, nil
+}
+
+// availableName keeps name when it is free and otherwise picks its copy name.
+func availableName(name string, existing []string) string {
+	for _, n := range existing {
+		if n == name {
+			return copyName(name, existing)
+		}
+	}
+	return name
+}
+
+// isWithinFolder reports whether folderID is ancestorID or one of its descendants.
+func (c *Commands) isWithinFolder(folderID, ancestorID string) (bool, error) {
+	subtree, err := c.Folders.SubtreeIDs(ancestorID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(subtree, folderID), nil
+}
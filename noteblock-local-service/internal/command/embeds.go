@@ -0,0 +1,37 @@
+package command
+
+import (
+	"encoding/json"
+	"server/internal/model/dto"
+)
+
+// checkEmbed validates the content of embed block blockID, empty for a new
+// block. The target need not exist: a missing one resolves as a tombstone,
+// the same as one deleted later, so imports keep their dangling embeds.
+func checkEmbed(blockID string, raw *json.RawMessage) error {
+	var content dto.EmbedContent
+	if err := json.Unmarshal(*raw, &content); err != nil || content.BlockID == "" {
+		return validation("Embed blocks need a block_id")
+	}
+	if content.BlockID == blockID {
+		return validation("A block cannot embed itself")
+	}
+	return nil
+}
+
+// BlockReferences lists the embed blocks that transclude blockID. It also
+// answers for deleted blocks, whose embeds are left as tombstones.
+func (c *Commands) BlockReferences(blockID string) ([]dto.BlockReferenceDTO, error) {
+	if blockID == "" {
+		return nil, validation("Missing block ID")
+	}
+	rows, err := c.Blocks.EmbedsOf(blockID)
+	if err != nil {
+		return nil, internal("Failed to query block references", err)
+	}
+	refs := make([]dto.BlockReferenceDTO, 0, len(rows))
+	for _, r := range rows {
+		refs = append(refs, dto.BlockReferenceDTO{BlockID: r.BlockID, NoteID: r.NoteID, NoteTitle: r.NoteTitle})
+	}
+	return refs, nil
+}
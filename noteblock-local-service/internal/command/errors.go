@@ -0,0 +1,85 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Kind classifies a domain error independently of the transport reporting it.
+type Kind string
+
+const (
+	KindValidation  Kind = "validation"
+	KindNotFound    Kind = "not_found"
+	KindConflict    Kind = "conflict"
+	KindInvalidMove Kind = "invalid_move"
+	KindInternal    Kind = "internal"
+)
+
+// Error is returned by every command. Message is safe to show to users; the
+// wrapped Err keeps the underlying cause for logs.
+type Error struct {
+	Kind    Kind
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func validation(message string) error {
+	return &Error{Kind: KindValidation, Message: message}
+}
+
+func notFound(message string) error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func conflict(message string) error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func invalidMove(message string) error {
+	return &Error{Kind: KindInvalidMove, Message: message}
+}
+
+func internal(message string, err error) error {
+	return &Error{Kind: KindInternal, Message: message, Err: err}
+}
+
+// lookupErr turns a failed lookup into not-found when the record is missing and
+// internal otherwise.
+func lookupErr(err error, notFoundMessage, internalMessage string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Error{Kind: KindNotFound, Message: notFoundMessage, Err: err}
+	}
+	return internal(internalMessage, err)
+}
+
+// KindOf reports the Kind of err, treating anything that is not an *Error as internal.
+func KindOf(err error) Kind {
+	var cmdErr *Error
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Kind
+	}
+	return KindInternal
+}
+
+// MessageOf returns the user-facing message for err.
+func MessageOf(err error) string {
+	var cmdErr *Error
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Message
+	}
+	return "Internal error"
+}
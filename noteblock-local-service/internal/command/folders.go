@@ -0,0 +1,227 @@
+package command
+
+import (
+	"errors"
+
+	"server/internal/model"
+	"server/internal/model/dto"
+	"server/internal/service"
+)
+
+const RootFolderID = "root"
+
+func (c *Commands) CreateFolder(name, parentID *string) (*model.Folder, error) {
+	targetParentID := RootFolderID
+	if parentID != nil && *parentID != "" {
+		targetParentID = *parentID
+	}
+
+	if _, err := c.Folders.GetFolderByID(targetParentID); err != nil {
+		return nil, lookupErr(err, "Parent folder does not exist", "Failed to query parent folder")
+	}
+
+	siblings, err := c.Folders.ListChildrenByParentId(&targetParentID)
+	if err != nil {
+		return nil, internal("Failed to query folders", err)
+	}
+
+	var targetName string
+	if name == nil || *name == "" {
+		targetName = uniqueName("New Folder", folderNames(siblings))
+	} else {
+		targetName = *name
+		for _, f := range siblings {
+			if f.Name == targetName {
+				return nil, conflict("Folder with that name already exists")
+			}
+		}
+	}
+
+	folder, err := c.Folders.CreateNewFolder(targetName, &targetParentID)
+	if err != nil {
+		return nil, internal("Failed to create new folder", err)
+	}
+	return folder, nil
+}
+
+func (c *Commands) GetFolderTree(id string) (*dto.FolderResponse, error) {
+	if id == "" {
+		return nil, validation("Missing folder ID")
+	}
+	folder, err := c.Folders.GetFolderDtoById(id)
+	if err != nil {
+		return nil, lookupErr(err, "Folder not found", "Failed to retrieve folder")
+	}
+	return folder, nil
+}
+
+// FolderChildren returns one level of the tree under id, for clients that
+// expand folders lazily instead of loading the whole tree.
+func (c *Commands) FolderChildren(id string) (*dto.FolderChildren, error) {
+	if id == "" {
+		return nil, validation("Missing folder ID")
+	}
+	folder, err := c.Folders.FolderChildren(id)
+	if err != nil {
+		return nil, lookupErr(err, "Folder not found", "Failed to retrieve folder")
+	}
+	return folder, nil
+}
+
+// SetFolderSort sets how a folder orders its children and notes: by name
+// (natural order), created, updated or manual. desc reverses all but manual.
+func (c *Commands) SetFolderSort(id, sortBy string, desc bool) (*dto.FolderChildren, error) {
+	if id == "" {
+		return nil, validation("Missing folder ID")
+	}
+	switch sortBy {
+	case model.SortByName, model.SortByCreated, model.SortByUpdated, model.SortByManual:
+	default:
+		return nil, validation("sort_by must be name, created, updated or manual")
+	}
+	if _, err := c.Folders.SetSort(id, sortBy, desc); err != nil {
+		return nil, lookupErr(err, "Folder not found", "Failed to update folder")
+	}
+	return c.FolderChildren(id)
+}
+
+// ReorderFolder puts a folder's children and notes in the given order and
+// switches it to manual sorting. A nil list keeps that kind's current order;
+// otherwise it has to list every child folder, or every note, exactly once.
+func (c *Commands) ReorderFolder(id string, folderIDs, noteIDs []string) (*dto.FolderChildren, error) {
+	if id == "" {
+		return nil, validation("Missing folder ID")
+	}
+	if _, err := c.Folders.GetFolderByID(id); err != nil {
+		return nil, lookupErr(err, "Folder not found", "Failed to retrieve folder")
+	}
+	err := c.Folders.Reorder(id, folderIDs, noteIDs)
+	switch {
+	case errors.Is(err, service.ErrNotInFolder):
+		return nil, validation("Reorder must list every child folder or note of the folder exactly once")
+	case err != nil:
+		return nil, internal("Failed to reorder folder", err)
+	}
+	return c.FolderChildren(id)
+}
+
+func (c *Commands) UpdateFolder(currentID, name, parentID *string) (*model.Folder, error) {
+	if currentID == nil || *currentID == "" {
+		return nil, validation("Missing current folder ID")
+	}
+
+	currentFolder, err := c.Folders.GetFolderByID(*currentID)
+	if err != nil {
+		return nil, lookupErr(err, "Folder not found", "Failed to retrieve folder")
+	}
+
+	targetName := currentFolder.Name
+	if name != nil && *name != "" {
+		targetName = *name
+	}
+
+	if currentFolder.ID == RootFolderID {
+		if parentID != nil && *parentID != "" {
+			return nil, invalidMove("The root folder cannot be moved")
+		}
+		updated, err := c.Folders.UpdateFolder(currentFolder.ID, targetName, nil)
+		if err != nil {
+			return nil, internal("Failed to update folder", err)
+		}
+		return updated, nil
+	}
+
+	targetParentID := currentFolder.ParentID
+	if parentID != nil && *parentID != "" {
+		targetParentID = parentID
+	}
+	if targetParentID == nil || *targetParentID == "" {
+		root := RootFolderID
+		targetParentID = &root
+	}
+
+	if _, err := c.Folders.GetFolderByID(*targetParentID); err != nil {
+		return nil, lookupErr(err, "Parent folder does not exist", "Failed to query parent folder")
+	}
+	inside, err := c.isWithinFolder(*targetParentID, currentFolder.ID)
+	if err != nil {
+		return nil, internal("Failed to query folder ancestry", err)
+	}
+	if inside {
+		return nil, invalidMove("Cannot move a folder into itself or one of its subfolders")
+	}
+
+	siblings, err := c.Folders.ListChildrenByParentId(targetParentID)
+	if err != nil {
+		return nil, internal("Failed to check sibling folders", err)
+	}
+	for _, f := range siblings {
+		if f.Name == targetName && f.ID != currentFolder.ID {
+			return nil, conflict("Folder with that name already exists in the target parent")
+		}
+	}
+
+	updated, err := c.Folders.UpdateFolder(currentFolder.ID, targetName, targetParentID)
+	if err != nil {
+		return nil, internal("Failed to update folder", err)
+	}
+	return updated, nil
+}
+
+// MoveItems moves folders and notes into targetID in one transaction: every
+// move is checked as it would be on its own, and one failure undoes them all.
+func (c *Commands) MoveItems(targetID string, folderIDs, noteIDs []string) (*dto.FolderChildren, error) {
+	if targetID == "" {
+		return nil, validation("Missing target folder ID")
+	}
+	if len(folderIDs) == 0 && len(noteIDs) == 0 {
+		return nil, validation("Nothing to move")
+	}
+	if _, err := c.Folders.GetFolderByID(targetID); err != nil {
+		return nil, lookupErr(err, "Target folder does not exist", "Failed to query target folder")
+	}
+
+	err := c.Transaction(func(tx *Commands) error {
+		for _, id := range folderIDs {
+			if _, err := tx.UpdateFolder(&id, nil, &targetID); err != nil {
+				return err
+			}
+		}
+		for _, id := range noteIDs {
+			if _, err := tx.UpdateNote(id, nil, &targetID, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.FolderChildren(targetID)
+}
+
+func (c *Commands) DeleteFolder(id string) (*model.Folder, error) {
+	if id == "" {
+		return nil, validation("Missing folder ID")
+	}
+	if id == RootFolderID {
+		return nil, validation("Cannot delete root folder")
+	}
+
+	folder, err := c.Folders.GetFolderByID(id)
+	if err != nil {
+		return nil, lookupErr(err, "Folder was not found or does not exist", "Failed to retrieve folder")
+	}
+	if err := c.Folders.DeleteFolderAndContents(folder.ID); err != nil {
+		return nil, internal("Something went wrong in the deletion process of the folder", err)
+	}
+	return folder, nil
+}
+
+func folderNames(folders []model.Folder) []string {
+	names := make([]string, 0, len(folders))
+	for _, f := range folders {
+		names = append(names, f.Name)
+	}
+	return names
+}
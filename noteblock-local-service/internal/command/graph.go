@@ -0,0 +1,286 @@
+package command
+
+import (
+	"server/internal/model"
+	"server/internal/model/dto"
+	"server/internal/service"
+	"slices"
+	"sort"
+)
+
+// MaxGraphDepth caps how many link hops a neighbourhood graph spans.
+const MaxGraphDepth = 5
+
+// GraphOptions selects the part of the vault graph.get returns.
+type GraphOptions struct {
+	// NoteID centres the graph on a note and keeps only notes within Depth
+	// link hops of it, in either direction. Empty means the whole vault.
+	NoteID string
+	// Depth defaults to 1 when NoteID is set.
+	Depth int
+	// FolderID keeps only notes in that folder or below it, including notes
+	// placed there.
+	FolderID string
+	// Tags keeps only notes carrying at least one of these tags.
+	Tags []string
+	// HideOrphans drops notes without any link in the graph.
+	HideOrphans bool
+	// IncludeTags and IncludeFolders add tag and folder nodes with
+	// membership and containment edges.
+	IncludeTags    bool
+	IncludeFolders bool
+}
+
+// Graph builds nodes and edges for the notes opts selects. Filters apply
+// before the neighbourhood walk, so hops only pass through notes that match;
+// the centre note itself is always kept.
+func (c *Commands) Graph(opts GraphOptions) (*dto.Graph, error) {
+	if opts.Depth < 0 || opts.Depth > MaxGraphDepth {
+		return nil, validation("Depth must be between 0 and 5")
+	}
+	if opts.NoteID != "" && opts.Depth == 0 {
+		opts.Depth = 1
+	}
+	tagFilter, err := normalizeTags(opts.Tags)
+	if err != nil {
+		return nil, err
+	}
+
+	notes, err := c.Notes.ListAll()
+	if err != nil {
+		return nil, internal("Failed to list notes", err)
+	}
+	pairs, err := c.Links.ResolvedPairs()
+	if err != nil {
+		return nil, internal("Failed to list links", err)
+	}
+	var assignments []service.TagAssignment
+	if opts.IncludeTags || len(tagFilter) > 0 {
+		if assignments, err = c.Tags.Assignments(); err != nil {
+			return nil, internal("Failed to list tags", err)
+		}
+	}
+	var folders []model.Folder
+	var placements []model.Placement
+	if opts.IncludeFolders || opts.FolderID != "" {
+		if folders, err = c.Folders.ListAll(); err != nil {
+			return nil, internal("Failed to list folders", err)
+		}
+		if placements, err = c.Notes.AllPlacements(); err != nil {
+			return nil, internal("Failed to list placements", err)
+		}
+	}
+
+	noteByID := make(map[string]model.Note, len(notes))
+	keep := make(map[string]bool, len(notes))
+	// a note is in its own folder and in every folder it is placed in
+	noteFolders := make(map[string][]string, len(notes))
+	for _, n := range notes {
+		noteByID[n.ID] = n
+		keep[n.ID] = true
+		noteFolders[n.ID] = []string{n.FolderID}
+	}
+	for _, p := range placements {
+		noteFolders[p.NoteID] = append(noteFolders[p.NoteID], p.FolderID)
+	}
+	if opts.NoteID != "" {
+		if _, ok := noteByID[opts.NoteID]; !ok {
+			return nil, notFound("Note not found")
+		}
+	}
+
+	if opts.FolderID != "" {
+		subtree := folderSubtree(folders, opts.FolderID)
+		if len(subtree) == 0 {
+			return nil, notFound("Folder not found")
+		}
+		for id := range keep {
+			if !slices.ContainsFunc(noteFolders[id], func(f string) bool { return subtree[f] }) {
+				delete(keep, id)
+			}
+		}
+	}
+	if len(tagFilter) > 0 {
+		wanted := make(map[string]bool, len(tagFilter))
+		for _, name := range tagFilter {
+			wanted[name] = true
+		}
+		tagged := map[string]bool{}
+		for _, a := range assignments {
+			if wanted[a.Name] {
+				tagged[a.NoteID] = true
+			}
+		}
+		for id := range keep {
+			if !tagged[id] {
+				delete(keep, id)
+			}
+		}
+	}
+
+	var distance map[string]int
+	if opts.NoteID != "" {
+		keep[opts.NoteID] = true
+		distance = neighbourhood(opts.NoteID, opts.Depth, pairs, keep)
+		for id := range keep {
+			if _, ok := distance[id]; !ok {
+				delete(keep, id)
+			}
+		}
+	}
+
+	graph := &dto.Graph{Nodes: []dto.GraphNode{}, Edges: []dto.GraphEdge{}}
+	linked := map[string]bool{}
+	for _, p := range pairs {
+		if p.SourceNoteID == p.TargetNoteID || !keep[p.SourceNoteID] || !keep[p.TargetNoteID] {
+			continue
+		}
+		linked[p.SourceNoteID], linked[p.TargetNoteID] = true, true
+		graph.Edges = append(graph.Edges, dto.GraphEdge{Source: p.SourceNoteID, Target: p.TargetNoteID, Kind: dto.GraphLink, Weight: p.Count})
+	}
+	if opts.HideOrphans {
+		for id := range keep {
+			if !linked[id] && id != opts.NoteID {
+				delete(keep, id)
+			}
+		}
+	}
+
+	for _, n := range notes {
+		if !keep[n.ID] {
+			continue
+		}
+		folderID := n.FolderID
+		node := dto.GraphNode{ID: n.ID, Kind: dto.GraphNote, Label: n.Title, FolderID: &folderID}
+		if d, ok := distance[n.ID]; ok {
+			node.Distance = &d
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+
+	if opts.IncludeTags {
+		seen := map[string]bool{}
+		for _, a := range assignments {
+			if !keep[a.NoteID] {
+				continue
+			}
+			id := "tag:" + a.Name
+			if !seen[id] {
+				seen[id] = true
+				graph.Nodes = append(graph.Nodes, dto.GraphNode{ID: id, Kind: dto.GraphTag, Label: a.Name})
+			}
+			graph.Edges = append(graph.Edges, dto.GraphEdge{Source: id, Target: a.NoteID, Kind: dto.GraphTag, Weight: 1})
+		}
+	}
+
+	if opts.IncludeFolders {
+		folderByID := make(map[string]model.Folder, len(folders))
+		for _, f := range folders {
+			folderByID[f.ID] = f
+		}
+		seen := map[string]bool{}
+		var addFolder func(id string)
+		addFolder = func(id string) {
+			f, ok := folderByID[id]
+			if !ok || seen[id] {
+				return
+			}
+			seen[id] = true
+			graph.Nodes = append(graph.Nodes, dto.GraphNode{ID: "folder:" + id, Kind: dto.GraphFolder, Label: f.Name, FolderID: f.ParentID})
+			if f.ParentID != nil && *f.ParentID != "" {
+				addFolder(*f.ParentID)
+				graph.Edges = append(graph.Edges, dto.GraphEdge{Source: "folder:" + *f.ParentID, Target: "folder:" + id, Kind: dto.GraphFolder, Weight: 1})
+			}
+		}
+		for _, n := range notes {
+			if !keep[n.ID] {
+				continue
+			}
+			for _, folderID := range noteFolders[n.ID] {
+				addFolder(folderID)
+				graph.Edges = append(graph.Edges, dto.GraphEdge{Source: "folder:" + folderID, Target: n.ID, Kind: dto.GraphFolder, Weight: 1})
+			}
+		}
+	}
+
+	in, out := map[string]int{}, map[string]int{}
+	for _, e := range graph.Edges {
+		out[e.Source]++
+		in[e.Target]++
+	}
+	for i := range graph.Nodes {
+		node := &graph.Nodes[i]
+		node.InDegree, node.OutDegree = in[node.ID], out[node.ID]
+		node.Degree = node.InDegree + node.OutDegree
+	}
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Target < b.Target
+	})
+	return graph, nil
+}
+
+// neighbourhood walks links in both directions from start through the notes
+// in keep and returns the hop count of every note within depth.
+func neighbourhood(start string, depth int, pairs []service.LinkPair, keep map[string]bool) map[string]int {
+	adjacent := map[string][]string{}
+	for _, p := range pairs {
+		adjacent[p.SourceNoteID] = append(adjacent[p.SourceNoteID], p.TargetNoteID)
+		adjacent[p.TargetNoteID] = append(adjacent[p.TargetNoteID], p.SourceNoteID)
+	}
+
+	distance := map[string]int{start: 0}
+	frontier := []string{start}
+	for hop := 1; hop <= depth && len(frontier) > 0; hop++ {
+		var next []string
+		for _, id := range frontier {
+			for _, neighbour := range adjacent[id] {
+				if _, seen := distance[neighbour]; seen || !keep[neighbour] {
+					continue
+				}
+				distance[neighbour] = hop
+				next = append(next, neighbour)
+			}
+		}
+		frontier = next
+	}
+	return distance
+}
+
+// folderSubtree returns rootID and every folder below it, or nothing when
+// rootID does not exist.
+func folderSubtree(folders []model.Folder, rootID string) map[string]bool {
+	children := map[string][]string{}
+	exists := false
+	for _, f := range folders {
+		if f.ID == rootID {
+			exists = true
+		}
+		if f.ParentID != nil {
+			children[*f.ParentID] = append(children[*f.ParentID], f.ID)
+		}
+	}
+	if !exists {
+		return nil
+	}
+
+	subtree := map[string]bool{rootID: true}
+	queue := []string{rootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if !subtree[child] {
+				subtree[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	return subtree
+}
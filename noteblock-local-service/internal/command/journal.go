@@ -0,0 +1,227 @@
+package command
+
+import (
+	"server/internal/model/dto"
+	"time"
+)
+
+const (
+	// DateLayout is how journal days are named on the wire and in the vault.
+	DateLayout  = "2006-01-02"
+	monthLayout = "2006-01"
+
+	DefaultJournalFolderName  = "Journal"
+	DefaultJournalTitleFormat = DateLayout
+
+	settingJournalFolder      = "journal.folder_id"
+	settingJournalTitleFormat = "journal.title_format"
+	settingJournalTemplate    = "journal.template_id"
+)
+
+// JournalConfig returns the daily note settings with defaults filled in.
+func (c *Commands) JournalConfig() (*dto.JournalConfig, error) {
+	var cfg dto.JournalConfig
+	for key, value := range map[string]*string{
+		settingJournalFolder:      &cfg.FolderID,
+		settingJournalTitleFormat: &cfg.TitleFormat,
+		settingJournalTemplate:    &cfg.TemplateID,
+	} {
+		v, err := c.Settings.Get(key)
+		if err != nil {
+			return nil, internal("Failed to read journal settings", err)
+		}
+		*value = v
+	}
+	if cfg.TitleFormat == "" {
+		cfg.TitleFormat = DefaultJournalTitleFormat
+	}
+	return &cfg, nil
+}
+
+// ConfigureJournal updates the daily note settings. Nil fields are left alone
+// and empty strings restore the default.
+func (c *Commands) ConfigureJournal(folderID, titleFormat, templateID *string) (*dto.JournalConfig, error) {
+	if folderID != nil && *folderID != "" {
+		if _, err := c.Folders.GetFolderByID(*folderID); err != nil {
+			return nil, lookupErr(err, "Journal folder does not exist", "Failed to query folder")
+		}
+	}
+	if titleFormat != nil && *titleFormat != "" && !isDayLayout(*titleFormat) {
+		return nil, validation("Title format must include the year, month and day")
+	}
+	if templateID != nil && *templateID != "" {
+		template, err := c.Notes.GetNoteMetaData(*templateID)
+		if err != nil {
+			return nil, lookupErr(err, "Template not found", "Failed to retrieve template")
+		}
+		if !template.IsTemplate {
+			return nil, validation("Note is not a template")
+		}
+	}
+
+	err := c.Transaction(func(tx *Commands) error {
+		for key, value := range map[string]*string{
+			settingJournalFolder:      folderID,
+			settingJournalTitleFormat: titleFormat,
+			settingJournalTemplate:    templateID,
+		} {
+			if value == nil {
+				continue
+			}
+			if err := tx.Settings.Set(key, *value); err != nil {
+				return internal("Failed to save journal settings", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.JournalConfig()
+}
+
+// JournalToday finds or creates the daily note for the current day in
+// timezone, an IANA name; empty means the server's local zone.
+func (c *Commands) JournalToday(timezone string) (date string, note *dto.NoteDTO, created bool, err error) {
+	loc := time.Local
+	if timezone != "" {
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return "", nil, false, validation("Unknown timezone: " + timezone)
+		}
+	}
+	date = now().In(loc).Format(DateLayout)
+	note, created, err = c.JournalDay(date)
+	return date, note, created, err
+}
+
+// JournalDay finds or creates the daily note for date (2006-01-02). A note in
+// the journal folder that already carries the day's title is adopted rather
+// than duplicated, so hand-made entries carry over.
+func (c *Commands) JournalDay(date string) (*dto.NoteDTO, bool, error) {
+	day, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return nil, false, validation("Date must be formatted as YYYY-MM-DD")
+	}
+
+	var noteID string
+	var created bool
+	err = c.Transaction(func(tx *Commands) error {
+		existing, err := tx.Journal.Get(date)
+		if err != nil {
+			return internal("Failed to query journal", err)
+		}
+		if existing != nil {
+			noteID = existing.ID
+			return nil
+		}
+
+		noteID, created, err = tx.createDailyNote(day)
+		if err != nil {
+			return err
+		}
+		if err := tx.Journal.Set(date, noteID); err != nil {
+			return internal("Failed to record journal entry", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	note, err := c.GetNote(noteID)
+	if err != nil {
+		return nil, false, err
+	}
+	return note, created, nil
+}
+
+func (c *Commands) createDailyNote(day time.Time) (string, bool, error) {
+	cfg, err := c.JournalConfig()
+	if err != nil {
+		return "", false, err
+	}
+	folderID, err := c.journalFolder(cfg.FolderID)
+	if err != nil {
+		return "", false, err
+	}
+	title := day.Format(cfg.TitleFormat)
+
+	notes, err := c.notesListedIn(folderID)
+	if err != nil {
+		return "", false, internal("Failed to query journal folder", err)
+	}
+	for _, n := range notes {
+		if n.Title == title {
+			return n.ID, false, nil
+		}
+	}
+
+	if cfg.TemplateID != "" {
+		note, err := c.CreateNoteFromTemplate(cfg.TemplateID, &folderID, &title, map[string]string{VarDate: day.Format(DateLayout)})
+		if err == nil {
+			return note.ID, true, nil
+		}
+		// a template that was deleted or unmarked falls back to a blank note
+		if kind := KindOf(err); kind != KindNotFound && kind != KindValidation {
+			return "", false, err
+		}
+	}
+	note, err := c.CreateNote(&title, &folderID)
+	if err != nil {
+		return "", false, err
+	}
+	return note.ID, true, nil
+}
+
+// journalFolder resolves the configured folder, falling back to a "Journal"
+// folder under root that is created on first use.
+func (c *Commands) journalFolder(configured string) (string, error) {
+	if configured != "" {
+		if _, err := c.Folders.GetFolderByID(configured); err == nil {
+			return configured, nil
+		}
+	}
+
+	root := RootFolderID
+	folders, err := c.Folders.ListChildrenByParentId(&root)
+	if err != nil {
+		return "", internal("Failed to query folders", err)
+	}
+	for _, f := range folders {
+		if f.Name == DefaultJournalFolderName {
+			return f.ID, nil
+		}
+	}
+	folder, err := c.Folders.CreateNewFolder(DefaultJournalFolderName, &root)
+	if err != nil {
+		return "", internal("Failed to create journal folder", err)
+	}
+	return folder.ID, nil
+}
+
+// JournalRange lists the daily notes recorded for month (2006-01).
+func (c *Commands) JournalRange(month string) ([]dto.JournalEntryDTO, error) {
+	first, err := time.Parse(monthLayout, month)
+	if err != nil {
+		return nil, validation("Month must be formatted as YYYY-MM")
+	}
+	last := first.AddDate(0, 1, -1)
+
+	rows, err := c.Journal.Between(first.Format(DateLayout), last.Format(DateLayout))
+	if err != nil {
+		return nil, internal("Failed to query journal", err)
+	}
+	entries := make([]dto.JournalEntryDTO, 0, len(rows))
+	for _, r := range rows {
+		entries = append(entries, dto.JournalEntryDTO{Date: r.Date, NoteID: r.NoteID, Title: r.Title})
+	}
+	return entries, nil
+}
+
+// isDayLayout reports whether layout keeps the year, month and day, so every
+// day gets a distinct title.
+func isDayLayout(layout string) bool {
+	day := time.Date(2031, time.November, 23, 0, 0, 0, 0, time.UTC)
+	parsed, err := time.Parse(layout, day.Format(layout))
+	return err == nil && parsed.Year() == day.Year() && parsed.YearDay() == day.YearDay()
+}
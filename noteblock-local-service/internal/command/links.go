@@ -0,0 +1,273 @@
+package command
+
+import (
+	"encoding/json"
+	"regexp"
+	"server/internal/model"
+	"server/internal/model/dto"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// wikiLink matches [[target]] and [[target|label]].
+var wikiLink = regexp.MustCompile(`\[\[([^\[\]|\n]+)(?:\|([^\[\]\n]*))?\]\]`)
+
+// linkIndexVersion is bumped when link parsing changes so IndexLinks reparses
+// every note once.
+const (
+	linkIndexVersion = "1"
+	settingLinkIndex = "links.index_version"
+)
+
+type parsedLink struct {
+	target string
+	label  string
+}
+
+// wikiLinks returns the links written in text outside code, in order.
+func wikiLinks(text string) []parsedLink {
+	if !strings.Contains(text, "[[") {
+		return nil
+	}
+	inCode := codeRanges(text)
+	var links []parsedLink
+	for _, m := range wikiLink.FindAllStringSubmatchIndex(text, -1) {
+		target := strings.TrimSpace(text[m[2]:m[3]])
+		if target == "" || inCode(m[0]) {
+			continue
+		}
+		link := parsedLink{target: target}
+		if m[4] >= 0 {
+			link.label = strings.TrimSpace(text[m[4]:m[5]])
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+// retargetWikiLinks points every link in text whose target is one of from,
+// exactly as written, at to and keeps the labels.
+func retargetWikiLinks(text string, from []string, to string) string {
+	inCode := codeRanges(text)
+	var b strings.Builder
+	last := 0
+	for _, m := range wikiLink.FindAllStringSubmatchIndex(text, -1) {
+		if inCode(m[0]) || !slices.Contains(from, strings.TrimSpace(text[m[2]:m[3]])) {
+			continue
+		}
+		b.WriteString(text[last:m[0]])
+		b.WriteString("[[" + to)
+		if m[4] >= 0 {
+			b.WriteString("|" + text[m[4]:m[5]])
+		}
+		b.WriteString("]]")
+		last = m[1]
+	}
+	if last == 0 {
+		return text
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// syncLinks rebuilds the links written in a note's text blocks.
+func (c *Commands) syncLinks(noteID string) error {
+	note, err := c.Notes.GetNote(noteID)
+	if err != nil {
+		return err
+	}
+
+	var links []model.Link
+	for _, block := range note.Blocks {
+		if block.Type != TextBlockType || !strings.Contains(block.Content, "[[") {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal([]byte(block.Content), &value); err != nil {
+			continue
+		}
+		var parsed []parsedLink
+		mapStrings(value, func(s string) string {
+			parsed = append(parsed, wikiLinks(s)...)
+			return s
+		})
+
+		for _, p := range parsed {
+			link := model.Link{
+				SourceNoteID:  noteID,
+				SourceBlockID: block.ID,
+				Target:        p.target,
+				Label:         p.label,
+			}
+			if _, err := uuid.Parse(p.target); err == nil {
+				link.ByID = true
+				if _, err := c.Notes.GetNoteMetaData(p.target); err == nil {
+					link.TargetNoteID = &p.target
+				}
+			} else if link.TargetNoteID, err = c.Links.ResolveTitle(p.target, note.FolderID); err != nil {
+				return err
+			}
+			links = append(links, link)
+		}
+	}
+	return c.Links.ReplaceNoteLinks(noteID, links)
+}
+
+// IndexLinks parses the links of every note when the vault was last indexed
+// by an older linkIndexVersion, e.g. because it predates links. It is cheap
+// once the index is current.
+func (c *Commands) IndexLinks() error {
+	version, err := c.Settings.Get(settingLinkIndex)
+	if err != nil {
+		return internal("Failed to read link index version", err)
+	}
+	if version == linkIndexVersion {
+		return nil
+	}
+	return c.Transaction(func(tx *Commands) error {
+		var noteIDs []string
+		if err := tx.Notes.DB.Model(&model.Block{}).
+			Where("type = ? AND content LIKE ?", TextBlockType, "%[[%").
+			Distinct().
+			Pluck("note_id", &noteIDs).Error; err != nil {
+			return internal("Failed to query notes", err)
+		}
+		for _, noteID := range noteIDs {
+			if err := tx.syncLinks(noteID); err != nil {
+				return internal("Failed to index links", err)
+			}
+		}
+		if err := tx.Settings.Set(settingLinkIndex, linkIndexVersion); err != nil {
+			return internal("Failed to save link index version", err)
+		}
+		return nil
+	})
+}
+
+// noteTitled resolves the dangling links that name a note's new title.
+func (c *Commands) noteTitled(noteID, title string) error {
+	if err := c.Links.AttachUnresolved(title, noteID); err != nil {
+		return internal("Failed to resolve links", err)
+	}
+	return nil
+}
+
+// retitleLinks rewrites the text of every title link to noteID after it was
+// renamed to newTitle, so the links keep resolving. Only the link texts the
+// index resolved to noteID are rewritten; a block may also link a different
+// note whose title differs from the old one only in case.
+func (c *Commands) retitleLinks(noteID, newTitle string) error {
+	links, err := c.Links.TitleLinksTo(noteID)
+	if err != nil {
+		return internal("Failed to query links", err)
+	}
+	blocks := map[string][]string{} // block ID -> link targets that reach noteID
+	var sources []string
+	for _, l := range links {
+		blocks[l.SourceBlockID] = append(blocks[l.SourceBlockID], l.Target)
+		if !slices.Contains(sources, l.SourceNoteID) {
+			sources = append(sources, l.SourceNoteID)
+		}
+	}
+
+	for _, sourceID := range sources {
+		source, err := c.Notes.GetNote(sourceID)
+		if err != nil {
+			return internal("Failed to retrieve linking note", err)
+		}
+		for _, block := range source.Blocks {
+			targets, ok := blocks[block.ID]
+			if !ok {
+				continue
+			}
+			var value any
+			if err := json.Unmarshal([]byte(block.Content), &value); err != nil {
+				continue
+			}
+			changed := false
+			value = mapStrings(value, func(s string) string {
+				retargeted := retargetWikiLinks(s, targets, newTitle)
+				changed = changed || retargeted != s
+				return retargeted
+			})
+			if !changed {
+				continue
+			}
+			content, err := marshalContent(value)
+			if err != nil {
+				return internal("Failed to rewrite link", err)
+			}
+			if _, err := c.Blocks.UpdateBlockContent(sourceID, block.ID, block.Type, &content); err != nil {
+				return internal("Failed to rewrite link", err)
+			}
+		}
+	}
+	return c.contentChanged(sources...)
+}
+
+// Backlinks lists the links that point at a note.
+func (c *Commands) Backlinks(noteID string) ([]dto.BacklinkDTO, error) {
+	if noteID == "" {
+		return nil, validation("Missing note ID")
+	}
+	if _, err := c.Notes.GetNoteMetaData(noteID); err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+	rows, err := c.Links.Backlinks(noteID)
+	if err != nil {
+		return nil, internal("Failed to query backlinks", err)
+	}
+	links := make([]dto.BacklinkDTO, 0, len(rows))
+	for _, r := range rows {
+		links = append(links, dto.BacklinkDTO{
+			NoteID:    r.SourceNoteID,
+			NoteTitle: r.SourceTitle,
+			BlockID:   r.SourceBlockID,
+			Target:    r.Target,
+			Label:     r.Label,
+		})
+	}
+	return links, nil
+}
+
+// OutgoingLinks lists the links written in a note, resolved or not.
+func (c *Commands) OutgoingLinks(noteID string) ([]dto.LinkDTO, error) {
+	if noteID == "" {
+		return nil, validation("Missing note ID")
+	}
+	if _, err := c.Notes.GetNoteMetaData(noteID); err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+	rows, err := c.Links.Outgoing(noteID)
+	if err != nil {
+		return nil, internal("Failed to query links", err)
+	}
+	links := make([]dto.LinkDTO, 0, len(rows))
+	for _, r := range rows {
+		links = append(links, dto.LinkDTO{
+			BlockID:      r.SourceBlockID,
+			Target:       r.Target,
+			Label:        r.Label,
+			TargetNoteID: r.TargetNoteID,
+			TargetTitle:  r.TargetTitle,
+			Resolved:     r.TargetNoteID != nil,
+		})
+	}
+	return links, nil
+}
+
+// UnresolvedLinks lists link targets that match no note, most used first.
+// Creating a note with one of these titles resolves its links.
+func (c *Commands) UnresolvedLinks() ([]dto.UnresolvedLinkDTO, error) {
+	rows, err := c.Links.Unresolved()
+	if err != nil {
+		return nil, internal("Failed to query links", err)
+	}
+	links := make([]dto.UnresolvedLinkDTO, 0, len(rows))
+	for _, r := range rows {
+		links = append(links, dto.UnresolvedLinkDTO{Target: r.Target, NoteIDs: r.NoteIDs})
+	}
+	return links, nil
+}
@@ -0,0 +1,50 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// uniqueName returns base, or "base N" numbered one past the highest existing
+// "base N", so repeated creates yield "New Note", "New Note 2", "New Note 3".
+func uniqueName(base string, existing []string) string {
+	maxIndex := 0
+	pattern := regexp.MustCompile(`^` + regexp.QuoteMeta(base) + `(?: (\d+))?$`)
+
+	for _, name := range existing {
+		matches := pattern.FindStringSubmatch(name)
+		if len(matches) > 1 && matches[1] != "" {
+			if idx, err := strconv.Atoi(matches[1]); err == nil {
+				if idx > maxIndex {
+					maxIndex = idx
+				}
+			}
+		} else if name == base && maxIndex == 0 {
+			maxIndex = 1
+		}
+	}
+
+	if maxIndex == 0 {
+		return base
+	}
+	return fmt.Sprintf("%s %d", base, maxIndex+1)
+}
+
+var copySuffix = regexp.MustCompile(` \(copy(?: \d+)?\)$`)
+
+// copyName names a duplicate of name that is not in existing: "Spec (copy)",
+// then "Spec (copy 2)" and so on. Copying a copy numbers off the original.
+func copyName(name string, existing []string) string {
+	base := copySuffix.ReplaceAllString(name, "")
+	taken := make(map[string]bool, len(existing))
+	for _, n := range existing {
+		taken[n] = true
+	}
+
+	candidate := base + " (copy)"
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (copy %d)", base, i)
+	}
+	return candidate
+}
@@ -0,0 +1,202 @@
+package command
+
+import (
+	"server/internal/api/mapper"
+	"server/internal/model"
+	"server/internal/model/dto"
+	"server/internal/service"
+)
+
+func (c *Commands) CreateNote(title, folderID *string) (*model.Note, error) {
+	targetFolderID := RootFolderID
+	if folderID != nil && *folderID != "" {
+		targetFolderID = *folderID
+	}
+
+	if _, err := c.Folders.GetFolderByID(targetFolderID); err != nil {
+		return nil, lookupErr(err, "Folder does not exist", "Failed to query folder")
+	}
+
+	existing, err := c.notesListedIn(targetFolderID)
+	if err != nil {
+		return nil, internal("Failed to query notes in current folder", err)
+	}
+
+	var targetTitle string
+	if title == nil || *title == "" {
+		targetTitle = uniqueName("New Note", noteTitles(existing))
+	} else {
+		targetTitle = *title
+		for _, n := range existing {
+			if n.Title == targetTitle {
+				return nil, conflict("Note with that title already exists in this folder")
+			}
+		}
+	}
+
+	var note *model.Note
+	err = c.Transaction(func(tx *Commands) error {
+		var err error
+		note, err = tx.Notes.NewNote(targetTitle, targetFolderID)
+		if err != nil {
+			return internal("Failed to create new note", err)
+		}
+		return tx.noteTitled(note.ID, note.Title)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return note, nil
+}
+
+func (c *Commands) GetNote(id string) (*dto.NoteDTO, error) {
+	if id == "" {
+		return nil, validation("Missing note ID")
+	}
+	note, err := c.Notes.GetNote(id)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to retrieve note")
+	}
+	noteDTO, err := mapper.ToNoteDTO(note, c.Blocks.FindBlock)
+	if err != nil {
+		return nil, internal("Failed to map note to DTO", err)
+	}
+	tags, err := c.Tags.NoteTags(id)
+	if err != nil {
+		return nil, internal("Failed to retrieve note tags", err)
+	}
+	noteDTO.Tags = make([]string, 0, len(tags))
+	for _, t := range tags {
+		noteDTO.Tags = append(noteDTO.Tags, t.Name)
+	}
+	return noteDTO, nil
+}
+
+// UpdateNote renames and/or moves a note and optionally rewrites block order.
+// Everything is checked before anything is written, so a conflict leaves the
+// blocks untouched.
+func (c *Commands) UpdateNote(id string, title, folderID *string, blocks *[]service.BlockIndex) (*model.Note, error) {
+	if id == "" {
+		return nil, validation("Missing note ID")
+	}
+
+	existing, err := c.Notes.GetNoteMetaData(id)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to retrieve note metadata")
+	}
+
+	targetTitle := existing.Title
+	if title != nil && *title != "" {
+		targetTitle = *title
+	}
+
+	targetFolderID := existing.FolderID
+	if folderID != nil && *folderID != "" {
+		targetFolderID = *folderID
+	}
+	if targetFolderID != existing.FolderID {
+		if _, err := c.Folders.GetFolderByID(targetFolderID); err != nil {
+			return nil, lookupErr(err, "Destination folder does not exist", "Failed to query destination folder")
+		}
+	}
+
+	notesInFolder, err := c.notesListedIn(targetFolderID)
+	if err != nil {
+		return nil, internal("Failed to retrieve notes in new folder", err)
+	}
+	if titleTaken(notesInFolder, targetTitle, id) {
+		return nil, conflict("Note with that title already exists in the destination folder")
+	}
+	// a renamed note keeps being listed where it is placed, so the new title
+	// has to be free there too
+	if targetTitle != existing.Title {
+		placements, err := c.Notes.Placements(id)
+		if err != nil {
+			return nil, internal("Failed to query placements", err)
+		}
+		for _, p := range placements {
+			if p.FolderID == targetFolderID {
+				continue
+			}
+			listed, err := c.notesListedIn(p.FolderID)
+			if err != nil {
+				return nil, internal("Failed to query notes in folder", err)
+			}
+			if titleTaken(listed, targetTitle, id) {
+				return nil, conflict("Note with that title already exists in a folder the note is placed in")
+			}
+		}
+	}
+
+	var updated *model.Note
+	err = c.Transaction(func(tx *Commands) error {
+		if blocks != nil {
+			if err := tx.Blocks.ReorderBlocks(id, *blocks); err != nil {
+				return internal("Failed to update blocks", err)
+			}
+		}
+		var err error
+		updated, err = tx.Notes.UpdateNoteMetaData(id, targetTitle, targetFolderID)
+		if err != nil {
+			return internal("Failed to update note metadata", err)
+		}
+		// a note moved into a folder it was placed in now lives there
+		if _, err := tx.Notes.Unplace(id, targetFolderID); err != nil {
+			return internal("Failed to update placements", err)
+		}
+		if targetTitle == existing.Title {
+			return nil
+		}
+		if err := tx.retitleLinks(id, targetTitle); err != nil {
+			return err
+		}
+		return tx.noteTitled(id, targetTitle)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (c *Commands) DeleteNote(id string) error {
+	if id == "" {
+		return validation("Missing note ID")
+	}
+	if err := c.Notes.DeleteNote(id); err != nil {
+		return lookupErr(err, "Note not found", "Failed to delete note")
+	}
+	return nil
+}
+
+// notesListedIn returns every note a folder lists: the ones living there and
+// the ones placed there. Titles are unique across both, so every title check
+// goes through this.
+func (c *Commands) notesListedIn(folderID string) ([]model.Note, error) {
+	notes, err := c.Notes.ListNotesByFolderId(&folderID)
+	if err != nil {
+		return nil, err
+	}
+	placed, err := c.Notes.PlacedNotes(folderID)
+	if err != nil {
+		return nil, err
+	}
+	return append(notes, placed...), nil
+}
+
+// titleTaken reports whether a note other than exceptID has title.
+func titleTaken(notes []model.Note, title, exceptID string) bool {
+	for _, n := range notes {
+		if n.Title == title && n.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func noteTitles(notes []model.Note) []string {
+	titles := make([]string, 0, len(notes))
+	for _, n := range notes {
+		titles = append(titles, n.Title)
+	}
+	return titles
+}
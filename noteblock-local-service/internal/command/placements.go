@@ -0,0 +1,79 @@
+package command
+
+import "server/internal/model/dto"
+
+// NotePlacements lists the folders a note appears in, its primary folder first.
+func (c *Commands) NotePlacements(noteID string) ([]dto.PlacementDTO, error) {
+	if noteID == "" {
+		return nil, validation("Missing note ID")
+	}
+	note, err := c.Notes.GetNoteMetaData(noteID)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+	placements, err := c.Notes.Placements(noteID)
+	if err != nil {
+		return nil, internal("Failed to query placements", err)
+	}
+
+	res := make([]dto.PlacementDTO, 0, len(placements)+1)
+	res = append(res, dto.PlacementDTO{FolderID: note.FolderID, FolderName: note.Folder.Name, Primary: true})
+	for _, p := range placements {
+		res = append(res, dto.PlacementDTO{FolderID: p.FolderID, FolderName: p.Folder.Name})
+	}
+	return res, nil
+}
+
+// PlaceNote lists a note in folderID as well as where it already is. Titles
+// stay unique within a folder, counting the notes placed there.
+func (c *Commands) PlaceNote(noteID, folderID string) ([]dto.PlacementDTO, error) {
+	if noteID == "" || folderID == "" {
+		return nil, validation("Missing note ID or folder ID")
+	}
+	note, err := c.Notes.GetNoteMetaData(noteID)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+	if note.FolderID == folderID {
+		return nil, validation("Note already lives in that folder")
+	}
+	if _, err := c.Folders.GetFolderByID(folderID); err != nil {
+		return nil, lookupErr(err, "Folder does not exist", "Failed to query folder")
+	}
+
+	listed, err := c.notesListedIn(folderID)
+	if err != nil {
+		return nil, internal("Failed to query notes in folder", err)
+	}
+	if titleTaken(listed, note.Title, noteID) {
+		return nil, conflict("Note with that title already exists in this folder")
+	}
+
+	if err := c.Notes.Place(noteID, folderID); err != nil {
+		return nil, internal("Failed to place note", err)
+	}
+	return c.NotePlacements(noteID)
+}
+
+// UnplaceNote removes a note from a folder it was placed in. The primary
+// folder cannot be removed this way; move or delete the note instead.
+func (c *Commands) UnplaceNote(noteID, folderID string) ([]dto.PlacementDTO, error) {
+	if noteID == "" || folderID == "" {
+		return nil, validation("Missing note ID or folder ID")
+	}
+	note, err := c.Notes.GetNoteMetaData(noteID)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+	if note.FolderID == folderID {
+		return nil, validation("Cannot remove a note from its primary folder")
+	}
+	removed, err := c.Notes.Unplace(noteID, folderID)
+	if err != nil {
+		return nil, internal("Failed to remove placement", err)
+	}
+	if !removed {
+		return nil, notFound("Note is not placed in that folder")
+	}
+	return c.NotePlacements(noteID)
+}
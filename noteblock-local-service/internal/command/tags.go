@@ -0,0 +1,497 @@
+package command
+
+import (
+	"encoding/json"
+	"regexp"
+	"server/internal/model"
+	"server/internal/model/dto"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MaxTaggedNotes caps note.listByTags when no smaller limit is given.
+const MaxTaggedNotes = 500
+
+const settingTagsExtractInline = "tags.extract_inline"
+
+var (
+	// hashtag matches #tag where the # does not continue a word, an entity,
+	// a URL fragment or another #.
+	hashtag = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/\\])#([\p{L}\p{N}_][\p{L}\p{N}_/-]*)`)
+	// codeSpan matches fenced and inline code, where hashtags are not tags.
+	codeSpan = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// normalizeTag lower-cases a tag name and strips a leading #.
+func normalizeTag(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return "", validation("Tag name cannot be empty")
+	}
+	if strings.IndexFunc(name, unicode.IsSpace) >= 0 || strings.Contains(name, "#") {
+		return "", validation("Tag names cannot contain spaces or #")
+	}
+	return name, nil
+}
+
+func normalizeTags(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		n, err := normalizeTag(name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[n] {
+			seen[n] = true
+			normalized = append(normalized, n)
+		}
+	}
+	return normalized, nil
+}
+
+// hashtagSpans returns the byte ranges of the tag names (without #) written
+// in text, skipping code.
+func hashtagSpans(text string) [][2]int {
+	inCode := codeRanges(text)
+
+	var spans [][2]int
+	for _, m := range hashtag.FindAllStringSubmatchIndex(text, -1) {
+		start, end := m[2], m[3]
+		for end > start && strings.ContainsRune("/-", rune(text[end-1])) {
+			end--
+		}
+		name := text[start:end]
+		if inCode(start) || strings.IndexFunc(name, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+	return spans
+}
+
+// codeRanges reports whether a byte offset in text falls inside code.
+func codeRanges(text string) func(pos int) bool {
+	code := codeSpan.FindAllStringIndex(text, -1)
+	return func(pos int) bool {
+		for _, c := range code {
+			if pos >= c[0] && pos < c[1] {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// inlineTags collects the normalized hashtags written in a note's text blocks.
+func inlineTags(blocks []model.Block) []string {
+	seen := map[string]bool{}
+	collect := func(s string) string {
+		for _, span := range hashtagSpans(s) {
+			seen[strings.ToLower(s[span[0]:span[1]])] = true
+		}
+		return s
+	}
+	for _, block := range blocks {
+		if block.Type != TextBlockType || !strings.Contains(block.Content, "#") {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal([]byte(block.Content), &value); err == nil {
+			mapStrings(value, collect)
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renameHashtags rewrites #from (in any case) to #to in s.
+func renameHashtags(s, from, to string) string {
+	spans := hashtagSpans(s)
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		if strings.ToLower(s[span[0]:span[1]]) != from {
+			continue
+		}
+		b.WriteString(s[last:span[0]])
+		b.WriteString(to)
+		last = span[1]
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// TagConfig returns the tag settings.
+func (c *Commands) TagConfig() (*dto.TagConfig, error) {
+	extract, err := c.extractInlineTags()
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TagConfig{ExtractInline: extract}, nil
+}
+
+// ConfigureTags switches inline hashtag extraction. Turning it on tags every
+// note from its text; turning it off drops the inline assignments.
+func (c *Commands) ConfigureTags(extractInline bool) (*dto.TagConfig, error) {
+	err := c.Transaction(func(tx *Commands) error {
+		value := ""
+		if extractInline {
+			value = "true"
+		}
+		if err := tx.Settings.Set(settingTagsExtractInline, value); err != nil {
+			return internal("Failed to save tag settings", err)
+		}
+		if !extractInline {
+			if err := tx.Tags.ClearSource(model.TagSourceInline); err != nil {
+				return internal("Failed to remove inline tags", err)
+			}
+			return nil
+		}
+
+		var noteIDs []string
+		if err := tx.Notes.DB.Model(&model.Note{}).Pluck("id", &noteIDs).Error; err != nil {
+			return internal("Failed to query notes", err)
+		}
+		return tx.contentChanged(noteIDs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.TagConfig{ExtractInline: extractInline}, nil
+}
+
+func (c *Commands) extractInlineTags() (bool, error) {
+	value, err := c.Settings.Get(settingTagsExtractInline)
+	if err != nil {
+		return false, internal("Failed to read tag settings", err)
+	}
+	return value == "true", nil
+}
+
+// syncInlineTags makes a note's inline tags match the hashtags in its text.
+func (c *Commands) syncInlineTags(noteID string) error {
+	note, err := c.Notes.GetNote(noteID)
+	if err != nil {
+		return err
+	}
+	tags, err := c.Tags.Ensure(inlineTags(note.Blocks))
+	if err != nil {
+		return err
+	}
+	ids := tagIDs(tags)
+	if err := c.Tags.Unassign(noteID, model.TagSourceInline, ids); err != nil {
+		return err
+	}
+	return c.Tags.Assign(noteID, ids, model.TagSourceInline)
+}
+
+// NoteTags lists a note's tags with how each was assigned.
+func (c *Commands) NoteTags(noteID string) ([]dto.NoteTagDTO, error) {
+	if noteID == "" {
+		return nil, validation("Missing note ID")
+	}
+	if _, err := c.Notes.GetNoteMetaData(noteID); err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+	rows, err := c.Tags.NoteTags(noteID)
+	if err != nil {
+		return nil, internal("Failed to retrieve note tags", err)
+	}
+	tags := make([]dto.NoteTagDTO, 0, len(rows))
+	for _, r := range rows {
+		tags = append(tags, dto.NoteTagDTO{Name: r.Name, Source: r.Source})
+	}
+	return tags, nil
+}
+
+// SetNoteTags replaces a note's manual tags with names.
+func (c *Commands) SetNoteTags(noteID string, names []string) ([]dto.NoteTagDTO, error) {
+	return c.changeNoteTags(noteID, names, func(tx *Commands, ids []string) error {
+		if err := tx.Tags.Unassign(noteID, model.TagSourceManual, ids); err != nil {
+			return err
+		}
+		return tx.Tags.Assign(noteID, ids, model.TagSourceManual)
+	})
+}
+
+// AddNoteTags gives a note the manual tags names, keeping the ones it has.
+func (c *Commands) AddNoteTags(noteID string, names []string) ([]dto.NoteTagDTO, error) {
+	return c.changeNoteTags(noteID, names, func(tx *Commands, ids []string) error {
+		return tx.Tags.Assign(noteID, ids, model.TagSourceManual)
+	})
+}
+
+// RemoveNoteTags removes manual tags from a note. A tag that is still
+// written inline in the note stays as an inline tag.
+func (c *Commands) RemoveNoteTags(noteID string, names []string) ([]dto.NoteTagDTO, error) {
+	return c.changeNoteTags(noteID, names, func(tx *Commands, ids []string) error {
+		return tx.Tags.UnassignTags(noteID, model.TagSourceManual, ids)
+	})
+}
+
+func (c *Commands) changeNoteTags(noteID string, names []string, apply func(tx *Commands, ids []string) error) ([]dto.NoteTagDTO, error) {
+	if noteID == "" {
+		return nil, validation("Missing note ID")
+	}
+	normalized, err := normalizeTags(names)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.Notes.GetNoteMetaData(noteID); err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to query note")
+	}
+
+	err = c.Transaction(func(tx *Commands) error {
+		tags, err := tx.Tags.Ensure(normalized)
+		if err != nil {
+			return internal("Failed to create tags", err)
+		}
+		if err := apply(tx, tagIDs(tags)); err != nil {
+			return internal("Failed to update note tags", err)
+		}
+		return tx.contentChanged(noteID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.NoteTags(noteID)
+}
+
+// ListTags returns every tag in use with the number of notes carrying it.
+func (c *Commands) ListTags() ([]dto.TagDTO, error) {
+	rows, err := c.Tags.List()
+	if err != nil {
+		return nil, internal("Failed to list tags", err)
+	}
+	tags := make([]dto.TagDTO, 0, len(rows))
+	for _, r := range rows {
+		tags = append(tags, dto.TagDTO{ID: r.ID, Name: r.Name, Count: r.Count})
+	}
+	return tags, nil
+}
+
+// RenameTag renames a tag on every note at once. Inline hashtags are
+// rewritten in the text so they keep matching. Renaming onto an existing tag
+// is a conflict; use MergeTags for that.
+func (c *Commands) RenameTag(name, newName string) (*dto.TagDTO, error) {
+	from, err := normalizeTag(name)
+	if err != nil {
+		return nil, err
+	}
+	to, err := normalizeTag(newName)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := c.Tags.GetByName(from)
+	if err != nil {
+		return nil, internal("Failed to query tag", err)
+	}
+	if tag == nil {
+		return nil, notFound("Tag not found")
+	}
+
+	if to != from {
+		existing, err := c.Tags.GetByName(to)
+		if err != nil {
+			return nil, internal("Failed to query tag", err)
+		}
+		if existing != nil {
+			return nil, conflict("A tag with that name already exists; merge the tags instead")
+		}
+		err = c.Transaction(func(tx *Commands) error {
+			if err := tx.rewriteInlineTag(tag.ID, from, to); err != nil {
+				return err
+			}
+			if err := tx.Tags.Rename(tag.ID, to); err != nil {
+				return internal("Failed to rename tag", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c.tagByName(to)
+}
+
+// MergeTags folds every source tag into target, creating target if needed,
+// and deletes the sources. All assignments move in one transaction.
+func (c *Commands) MergeTags(sources []string, target string) (*dto.TagDTO, error) {
+	if len(sources) == 0 {
+		return nil, validation("Missing tags to merge")
+	}
+	from, err := normalizeTags(sources)
+	if err != nil {
+		return nil, err
+	}
+	to, err := normalizeTag(target)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceTags := make([]model.Tag, 0, len(from))
+	for _, name := range from {
+		if name == to {
+			continue
+		}
+		tag, err := c.Tags.GetByName(name)
+		if err != nil {
+			return nil, internal("Failed to query tag", err)
+		}
+		if tag == nil {
+			return nil, notFound("Tag not found: " + name)
+		}
+		sourceTags = append(sourceTags, *tag)
+	}
+
+	err = c.Transaction(func(tx *Commands) error {
+		targets, err := tx.Tags.Ensure([]string{to})
+		if err != nil {
+			return internal("Failed to create tag", err)
+		}
+		for _, tag := range sourceTags {
+			if err := tx.rewriteInlineTag(tag.ID, tag.Name, to); err != nil {
+				return err
+			}
+			if err := tx.Tags.Merge(tag.ID, targets[0].ID); err != nil {
+				return internal("Failed to merge tags", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.tagByName(to)
+}
+
+// rewriteInlineTag renames #from to #to in the text of every note that has
+// tagID inline.
+func (c *Commands) rewriteInlineTag(tagID, from, to string) error {
+	noteIDs, err := c.Tags.NotesWithTag(tagID, model.TagSourceInline)
+	if err != nil {
+		return internal("Failed to query tagged notes", err)
+	}
+	for _, noteID := range noteIDs {
+		note, err := c.Notes.GetNote(noteID)
+		if err != nil {
+			return internal("Failed to retrieve tagged note", err)
+		}
+		for _, block := range note.Blocks {
+			if block.Type != TextBlockType {
+				continue
+			}
+			var value any
+			if err := json.Unmarshal([]byte(block.Content), &value); err != nil {
+				continue
+			}
+			changed := false
+			value = mapStrings(value, func(s string) string {
+				renamed := renameHashtags(s, from, to)
+				changed = changed || renamed != s
+				return renamed
+			})
+			if !changed {
+				continue
+			}
+			content, err := marshalContent(value)
+			if err != nil {
+				return internal("Failed to rewrite tag", err)
+			}
+			if _, err := c.Blocks.UpdateBlockContent(noteID, block.ID, block.Type, &content); err != nil {
+				return internal("Failed to rewrite tag", err)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Commands) tagByName(name string) (*dto.TagDTO, error) {
+	tags, err := c.ListTags()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tags {
+		if t.Name == name {
+			return &t, nil
+		}
+	}
+	// a tag no note carries is not listed
+	tag, err := c.Tags.GetByName(name)
+	if err != nil {
+		return nil, internal("Failed to query tag", err)
+	}
+	if tag == nil {
+		return nil, notFound("Tag not found")
+	}
+	return &dto.TagDTO{ID: tag.ID, Name: tag.Name}, nil
+}
+
+// ListNotesByTags lists notes tagged with every one of names when mode is
+// "all" (the default) or with any of them when it is "any".
+func (c *Commands) ListNotesByTags(names []string, mode string, limit int) ([]dto.TaggedNoteDTO, error) {
+	if len(names) == 0 {
+		return nil, validation("Missing tags")
+	}
+	var all bool
+	switch mode {
+	case "", "all":
+		all = true
+	case "any":
+	default:
+		return nil, validation("Mode must be all or any")
+	}
+	if limit <= 0 || limit > MaxTaggedNotes {
+		limit = MaxTaggedNotes
+	}
+	normalized, err := normalizeTags(names)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, name := range normalized {
+		tag, err := c.Tags.GetByName(name)
+		if err != nil {
+			return nil, internal("Failed to query tag", err)
+		}
+		if tag == nil {
+			if all {
+				return []dto.TaggedNoteDTO{}, nil
+			}
+			continue
+		}
+		ids = append(ids, tag.ID)
+	}
+	if len(ids) == 0 {
+		return []dto.TaggedNoteDTO{}, nil
+	}
+
+	rows, err := c.Tags.NotesByTags(ids, all, limit)
+	if err != nil {
+		return nil, internal("Failed to query tagged notes", err)
+	}
+	notes := make([]dto.TaggedNoteDTO, 0, len(rows))
+	for _, r := range rows {
+		notes = append(notes, dto.TaggedNoteDTO{ID: r.ID, Title: r.Title, FolderID: r.FolderID, Tags: r.Tags})
+	}
+	return notes, nil
+}
+
+func tagIDs(tags []model.Tag) []string {
+	ids := make([]string, 0, len(tags))
+	for _, t := range tags {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
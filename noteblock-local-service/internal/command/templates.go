@@ -0,0 +1,227 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"server/internal/model"
+	"server/internal/model/dto"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TextBlockType is the block type whose content template variables expand in.
+const TextBlockType = "text"
+
+// Built-in template variables. Callers may override any of them.
+const (
+	VarDate   = "date"   // 2006-01-02
+	VarTime   = "time"   // 15:04
+	VarTitle  = "title"  // the new note's title
+	VarFolder = "folder" // the destination folder's name
+)
+
+// templateVar matches {{name}}, allowing spaces inside the braces.
+var templateVar = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// now is the clock used for {{date}} and {{time}}; tests pin it.
+var now = time.Now
+
+// SetTemplate marks a note as a template, or turns it back into a plain note.
+func (c *Commands) SetTemplate(id string, isTemplate bool) (*model.Note, error) {
+	if id == "" {
+		return nil, validation("Missing note ID")
+	}
+	note, err := c.Notes.SetTemplate(id, isTemplate)
+	if err != nil {
+		return nil, lookupErr(err, "Note not found", "Failed to update note")
+	}
+	return note, nil
+}
+
+// ListTemplates returns every template note ordered by title.
+func (c *Commands) ListTemplates() ([]dto.TemplateDTO, error) {
+	notes, err := c.Notes.ListTemplates()
+	if err != nil {
+		return nil, internal("Failed to list templates", err)
+	}
+
+	templates := make([]dto.TemplateDTO, 0, len(notes))
+	for _, note := range notes {
+		templates = append(templates, dto.TemplateDTO{
+			ID:         note.ID,
+			Title:      note.Title,
+			FolderID:   note.FolderID,
+			BlockCount: len(note.Blocks),
+			Variables:  templateVariables(&note),
+			UpdatedAt:  note.UpdatedAt,
+		})
+	}
+	return templates, nil
+}
+
+// CreateNoteFromTemplate instantiates template templateID into folderID (root
+// when empty). Variables are expanded in the title and in text blocks; other
+// blocks are copied as they are, and block order is kept. Without a title the
+// template's own title is expanded and numbered if it is taken.
+func (c *Commands) CreateNoteFromTemplate(templateID string, folderID, title *string, variables map[string]string) (*dto.NoteDTO, error) {
+	if templateID == "" {
+		return nil, validation("Missing template ID")
+	}
+	template, err := c.Notes.GetNote(templateID)
+	if err != nil {
+		return nil, lookupErr(err, "Template not found", "Failed to retrieve template")
+	}
+	if !template.IsTemplate {
+		return nil, validation("Note is not a template")
+	}
+
+	targetFolderID := RootFolderID
+	if folderID != nil && *folderID != "" {
+		targetFolderID = *folderID
+	}
+	folder, err := c.Folders.GetFolderByID(targetFolderID)
+	if err != nil {
+		return nil, lookupErr(err, "Folder does not exist", "Failed to query folder")
+	}
+	existing, err := c.notesListedIn(targetFolderID)
+	if err != nil {
+		return nil, internal("Failed to query notes in current folder", err)
+	}
+
+	at := now()
+	vars := map[string]string{
+		VarDate:   at.Format("2006-01-02"),
+		VarTime:   at.Format("15:04"),
+		VarFolder: folder.Name,
+	}
+	for name, value := range variables {
+		vars[name] = value
+	}
+
+	var targetTitle string
+	if title == nil || *title == "" {
+		targetTitle = uniqueName(expandVariables(template.Title, vars), noteTitles(existing))
+	} else {
+		targetTitle = expandVariables(*title, vars)
+		for _, n := range existing {
+			if n.Title == targetTitle {
+				return nil, conflict("Note with that title already exists in this folder")
+			}
+		}
+	}
+	if _, ok := variables[VarTitle]; !ok {
+		vars[VarTitle] = targetTitle
+	}
+
+	var newID string
+	err = c.Transaction(func(tx *Commands) error {
+		note, err := tx.Notes.NewNote(targetTitle, targetFolderID)
+		if err != nil {
+			return internal("Failed to create new note", err)
+		}
+		for i, block := range template.Blocks {
+			content := json.RawMessage(block.Content)
+			if block.Type == TextBlockType {
+				if content, err = expandContent(content, vars); err != nil {
+					return internal("Failed to expand template block", err)
+				}
+			}
+			if _, err := tx.Blocks.CreateNewBlock(note.ID, block.Type, i, &content); err != nil {
+				return internal("Failed to create block", err)
+			}
+		}
+		if err := tx.contentChanged(note.ID); err != nil {
+			return err
+		}
+		if err := tx.noteTitled(note.ID, note.Title); err != nil {
+			return err
+		}
+		if _, err := tx.Changes.Append("note.createFromTemplate", "note", note.ID, note.ID, map[string]string{"template_id": template.ID}); err != nil {
+			return internal("Failed to log note creation", err)
+		}
+		newID = note.ID
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.GetNote(newID)
+}
+
+// expandVariables replaces every known {{name}} in s; unknown ones stay as written.
+func expandVariables(s string, vars map[string]string) string {
+	return templateVar.ReplaceAllStringFunc(s, func(match string) string {
+		if value, ok := vars[templateVar.FindStringSubmatch(match)[1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// expandContent expands variables in every string inside a block's JSON content.
+func expandContent(content json.RawMessage, vars map[string]string) (json.RawMessage, error) {
+	var value any
+	if err := json.Unmarshal(content, &value); err != nil {
+		return nil, err
+	}
+	return marshalContent(mapStrings(value, func(s string) string { return expandVariables(s, vars) }))
+}
+
+// marshalContent encodes rewritten block content without escaping the <, >
+// and & that markdown text is full of.
+func marshalContent(value any) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+func mapStrings(value any, fn func(string) string) any {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case []any:
+		for i := range v {
+			v[i] = mapStrings(v[i], fn)
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = mapStrings(v[k], fn)
+		}
+	}
+	return value
+}
+
+// templateVariables lists the variable names note uses, sorted.
+func templateVariables(note *model.Note) []string {
+	seen := map[string]bool{}
+	collect := func(s string) string {
+		for _, m := range templateVar.FindAllStringSubmatch(s, -1) {
+			seen[m[1]] = true
+		}
+		return s
+	}
+
+	collect(note.Title)
+	for _, block := range note.Blocks {
+		if block.Type != TextBlockType || !strings.Contains(block.Content, "{{") {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal([]byte(block.Content), &value); err == nil {
+			mapStrings(value, collect)
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,355 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"server/internal/model"
+	"server/internal/model/dto"
+)
+
+// RecoveredFolderName is the folder under root that a repair moves orphaned
+// folders, notes and blocks into.
+const RecoveredFolderName = "Recovered"
+
+// CheckVault looks for damage in the vault: SQLite integrity problems,
+// folders, notes and blocks whose parent is gone, folder cycles, block content
+// that is not JSON and derived rows for missing notes. With repair set the
+// fixable findings are fixed in one transaction: orphans and cycles are
+// reattached under a Recovered folder, corrupt blocks are quarantined and
+// dangling rows are deleted. Integrity problems are only reported; restoring
+// a backup is the fix for those.
+func (c *Commands) CheckVault(repair bool) (*dto.VaultCheckDTO, error) {
+	res := &dto.VaultCheckDTO{Findings: []dto.VaultFinding{}, Repaired: repair}
+
+	problems, err := c.Vault.IntegrityCheck()
+	if err != nil {
+		return nil, internal("Failed to run integrity check", err)
+	}
+	for _, p := range problems {
+		res.Findings = append(res.Findings, dto.VaultFinding{Kind: dto.FindingIntegrity, Detail: p})
+	}
+
+	if repair {
+		err = c.Transaction(func(tx *Commands) error {
+			return tx.checkVault(res, true)
+		})
+	} else {
+		err = c.checkVault(res, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res.OK = len(res.Findings) == 0
+	return res, nil
+}
+
+// vaultCheck carries one check run: where findings go and, when repairing,
+// the Recovered folder, created the first time something needs it.
+type vaultCheck struct {
+	c         *Commands
+	res       *dto.VaultCheckDTO
+	repair    bool
+	recovered string
+}
+
+func (c *Commands) checkVault(res *dto.VaultCheckDTO, repair bool) error {
+	v := &vaultCheck{c: c, res: res, repair: repair}
+	// folders first, so the notes and blocks repaired after them land in a
+	// tree that is whole again
+	for _, step := range []func() error{
+		v.orphanFolders, v.folderCycles, v.orphanNotes, v.orphanBlocks, v.corruptBlocks, v.danglingRows,
+	} {
+		if err := step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *vaultCheck) report(kind, id, detail string) *dto.VaultFinding {
+	v.res.Findings = append(v.res.Findings, dto.VaultFinding{Kind: kind, ID: id, Detail: detail})
+	return &v.res.Findings[len(v.res.Findings)-1]
+}
+
+// recoveredFolder returns the ID of the Recovered folder under root, creating
+// it when there is none yet.
+func (v *vaultCheck) recoveredFolder() (string, error) {
+	if v.recovered != "" {
+		return v.recovered, nil
+	}
+	root := RootFolderID
+	children, err := v.c.Folders.ListChildrenByParentId(&root)
+	if err != nil {
+		return "", internal("Failed to query folders", err)
+	}
+	for _, f := range children {
+		if f.Name == RecoveredFolderName {
+			v.recovered = f.ID
+			v.res.RecoveredFolderID = &v.recovered
+			return v.recovered, nil
+		}
+	}
+	folder, err := v.c.Folders.CreateNewFolder(RecoveredFolderName, &root)
+	if err != nil {
+		return "", internal("Failed to create Recovered folder", err)
+	}
+	v.recovered = folder.ID
+	v.res.RecoveredFolderID = &v.recovered
+	return v.recovered, nil
+}
+
+// reattachFolder moves a folder under Recovered, renaming it if a folder
+// there already has its name.
+func (v *vaultCheck) reattachFolder(folder model.Folder) (string, error) {
+	recovered, err := v.recoveredFolder()
+	if err != nil {
+		return "", err
+	}
+	siblings, err := v.c.Folders.ListChildrenByParentId(&recovered)
+	if err != nil {
+		return "", internal("Failed to query folders", err)
+	}
+	names := make([]string, len(siblings))
+	for i, f := range siblings {
+		names[i] = f.Name
+	}
+	name := uniqueName(folder.Name, names)
+	if _, err := v.c.Folders.UpdateFolder(folder.ID, name, &recovered); err != nil {
+		return "", internal("Failed to move folder", err)
+	}
+	return fmt.Sprintf("moved to %s as %q", RecoveredFolderName, name), nil
+}
+
+// reattachNote moves a note into Recovered under a title no note there has.
+func (v *vaultCheck) reattachNote(note model.Note) (string, error) {
+	recovered, err := v.recoveredFolder()
+	if err != nil {
+		return "", err
+	}
+	title, err := v.freeTitle(recovered, note.Title)
+	if err != nil {
+		return "", err
+	}
+	if _, err := v.c.Notes.UpdateNoteMetaData(note.ID, title, recovered); err != nil {
+		return "", internal("Failed to move note", err)
+	}
+	return fmt.Sprintf("moved to %s as %q", RecoveredFolderName, title), nil
+}
+
+func (v *vaultCheck) freeTitle(folderID, title string) (string, error) {
+	notes, err := v.c.notesListedIn(folderID)
+	if err != nil {
+		return "", internal("Failed to query notes in folder", err)
+	}
+	titles := make([]string, len(notes))
+	for i, n := range notes {
+		titles[i] = n.Title
+	}
+	return uniqueName(title, titles), nil
+}
+
+func (v *vaultCheck) orphanFolders() error {
+	folders, err := v.c.Vault.OrphanFolders()
+	if err != nil {
+		return internal("Failed to query folders", err)
+	}
+	for _, f := range folders {
+		finding := v.report(dto.FindingOrphanFolder, f.ID, fmt.Sprintf("folder %q has no parent folder", f.Name))
+		if v.repair {
+			if finding.Repair, err = v.reattachFolder(f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// folderCycles reports each set of folders that are ancestors of each other
+// once. Repair breaks a cycle at its first folder by ID; the folders hanging
+// below it become reachable along with it.
+func (v *vaultCheck) folderCycles() error {
+	folders, err := v.c.Vault.UnreachableFolders()
+	if err != nil {
+		return internal("Failed to query folders", err)
+	}
+	for _, cycle := range findCycles(folders) {
+		names := make([]string, len(cycle))
+		for i, f := range cycle {
+			names[i] = fmt.Sprintf("%q", f.Name)
+		}
+		finding := v.report(dto.FindingFolderCycle, cycle[0].ID,
+			"folders form a cycle cut off from root: "+strings.Join(names, " > "))
+		if v.repair {
+			if finding.Repair, err = v.reattachFolder(cycle[0]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// findCycles follows parent links among folders and returns each cycle found,
+// starting from its lowest ID so a cycle reads the same however it was
+// entered.
+func findCycles(folders []model.Folder) [][]model.Folder {
+	byID := make(map[string]model.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+
+	var cycles [][]model.Folder
+	done := map[string]bool{}
+	for _, start := range folders {
+		var path []model.Folder
+		onPath := map[string]int{}
+		for f, ok := start, true; ok && !done[f.ID]; {
+			if i, seen := onPath[f.ID]; seen {
+				cycles = append(cycles, rotateToMin(path[i:]))
+				break
+			}
+			onPath[f.ID] = len(path)
+			path = append(path, f)
+			if f.ParentID == nil {
+				break
+			}
+			f, ok = byID[*f.ParentID]
+		}
+		for _, f := range path {
+			done[f.ID] = true
+		}
+	}
+	return cycles
+}
+
+func rotateToMin(cycle []model.Folder) []model.Folder {
+	lowest := 0
+	for i, f := range cycle {
+		if f.ID < cycle[lowest].ID {
+			lowest = i
+		}
+	}
+	return append(slices.Clone(cycle[lowest:]), cycle[:lowest]...)
+}
+
+func (v *vaultCheck) orphanNotes() error {
+	notes, err := v.c.Vault.OrphanNotes()
+	if err != nil {
+		return internal("Failed to query notes", err)
+	}
+	for _, n := range notes {
+		finding := v.report(dto.FindingOrphanNote, n.ID, fmt.Sprintf("note %q has no folder", n.Title))
+		if v.repair {
+			if finding.Repair, err = v.reattachNote(n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// orphanBlocks reports blocks whose note is gone. Repair gathers the blocks
+// of each missing note into a new note in Recovered, in their old order.
+func (v *vaultCheck) orphanBlocks() error {
+	blocks, err := v.c.Vault.OrphanBlocks()
+	if err != nil {
+		return internal("Failed to query blocks", err)
+	}
+	adopted := map[string]bool{} // missing note IDs already given a new note
+	var recoveredNotes []string
+	for _, b := range blocks {
+		finding := v.report(dto.FindingOrphanBlock, b.ID, fmt.Sprintf("%s block belongs to missing note %s", b.Type, b.NoteID))
+		if !v.repair {
+			continue
+		}
+		if !adopted[b.NoteID] {
+			recovered, err := v.recoveredFolder()
+			if err != nil {
+				return err
+			}
+			title, err := v.freeTitle(recovered, "Recovered note "+shortID(b.NoteID))
+			if err != nil {
+				return err
+			}
+			note, err := v.c.Notes.NewNote(title, recovered)
+			if err != nil {
+				return internal("Failed to create note", err)
+			}
+			if err := v.c.Vault.AdoptBlocks(b.NoteID, note.ID); err != nil {
+				return internal("Failed to move blocks", err)
+			}
+			adopted[b.NoteID] = true
+			recoveredNotes = append(recoveredNotes, note.ID)
+		}
+		finding.Repair = "moved to a note in " + RecoveredFolderName
+	}
+	if len(recoveredNotes) > 0 {
+		if err := v.c.contentChanged(recoveredNotes...); err != nil {
+			return internal("Failed to index recovered notes", err)
+		}
+	}
+	return nil
+}
+
+func shortID(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// corruptBlocks reports blocks whose content does not parse. Repair
+// quarantines them: the type becomes "quarantined" and the content keeps the
+// old type and text, so the note loads and nothing is thrown away.
+func (v *vaultCheck) corruptBlocks() error {
+	var corrupt []model.Block
+	if err := v.c.Vault.EachBlock(func(b model.Block) error {
+		if !json.Valid([]byte(b.Content)) {
+			corrupt = append(corrupt, b)
+		}
+		return nil
+	}); err != nil {
+		return internal("Failed to scan blocks", err)
+	}
+
+	for _, b := range corrupt {
+		finding := v.report(dto.FindingCorruptBlock, b.ID, fmt.Sprintf("%s block content is not valid JSON", b.Type))
+		if !v.repair {
+			continue
+		}
+		content, err := json.Marshal(dto.QuarantinedContent{Type: b.Type, Raw: b.Content})
+		if err != nil {
+			return internal("Failed to encode quarantined block", err)
+		}
+		raw := json.RawMessage(content)
+		if _, err := v.c.Blocks.UpdateBlockContent(b.NoteID, b.ID, dto.QuarantinedBlockType, &raw); err != nil {
+			return internal("Failed to quarantine block", err)
+		}
+		finding.Repair = "quarantined"
+	}
+	return nil
+}
+
+func (v *vaultCheck) danglingRows() error {
+	rows, err := v.c.Vault.DanglingRows()
+	if err != nil {
+		return internal("Failed to query derived rows", err)
+	}
+	start := len(v.res.Findings)
+	for _, r := range rows {
+		v.report(dto.FindingDanglingRow, r.Table+"/"+r.Key, "row in "+r.Table+" points at a missing note or folder")
+	}
+	if !v.repair || len(rows) == 0 {
+		return nil
+	}
+	if err := v.c.Vault.DeleteDanglingRows(); err != nil {
+		return internal("Failed to delete derived rows", err)
+	}
+	for i := start; i < len(v.res.Findings); i++ {
+		v.res.Findings[i].Repair = "deleted"
+	}
+	return nil
+}
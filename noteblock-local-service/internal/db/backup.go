@@ -0,0 +1,131 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/gorm"
+)
+
+// ErrNewerSchema is returned for a database written by a newer binary, which
+// this one cannot migrate and must not restore.
+var ErrNewerSchema = errors.New("database was written by a newer version of noteblock")
+
+// restoreBusyTimeout bounds how long Restore waits for other connections to
+// let go of the live database.
+const restoreBusyTimeout = 10 * time.Second
+
+// VacuumInto writes a consistent, compacted copy of the open database to path
+// without blocking readers. SQLite refuses to overwrite an existing file.
+func VacuumInto(db *gorm.DB, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return db.Exec("VACUUM INTO ?", path).Error
+}
+
+// Verify opens the database file at path read-only, runs SQLite's integrity
+// check on it and returns its schema version. A copy only counts as a backup
+// once it passes.
+func Verify(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	conn, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(path)+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	rows, err := conn.Query("PRAGMA integrity_check")
+	if err != nil {
+		return 0, err
+	}
+	var problems []string
+	for rows.Next() {
+		var problem string
+		if err := rows.Scan(&problem); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		problems = append(problems, problem)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(problems) != 1 || problems[0] != "ok" {
+		return 0, fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+
+	var version int
+	if err := conn.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion {
+		return version, ErrNewerSchema
+	}
+	return version, nil
+}
+
+// Restore replaces the contents of the open database with the database file
+// at path using SQLite's online backup API. The copy happens inside the live
+// connection, so every handle on db stays valid and sees the restored data;
+// run Migrate afterwards to bring an older backup up to date.
+func Restore(db *gorm.DB, path string) error {
+	ctx := context.Background()
+
+	src, err := sql.Open("sqlite3", "file:"+filepath.ToSlash(path)+"?mode=ro")
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer srcConn.Close()
+
+	live, err := db.DB()
+	if err != nil {
+		return err
+	}
+	dstConn, err := live.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer dstConn.Close()
+
+	return dstConn.Raw(func(dst any) error {
+		return srcConn.Raw(func(src any) error {
+			backup, err := dst.(*sqlite3.SQLiteConn).Backup("main", src.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+			// Step reports busy and locked as not done yet; retry those
+			// until the other connections finish what they are doing
+			deadline := time.Now().Add(restoreBusyTimeout)
+			for {
+				done, err := backup.Step(-1)
+				if err != nil || done {
+					if finishErr := backup.Finish(); err == nil {
+						err = finishErr
+					}
+					return err
+				}
+				if time.Now().After(deadline) {
+					_ = backup.Finish()
+					return errors.New("timed out waiting for the database to be free")
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	})
+}
@@ -5,46 +5,167 @@ import (
 	"os"
 	"path/filepath"
 	"server/internal/model"
+	"server/internal/order"
+	"strconv"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
-func InitDb() *gorm.DB {
+// SchemaVersion is bumped whenever Migrate changes the shape of the database.
+// It is stored in SQLite's user_version pragma so clients can detect a vault
+// written by a newer binary.
+//
+//  1. folders, notes and blocks
+//  2. blocks ordered by fractional order_key instead of the integer index column
+//  3. change log
+//  4. notes.is_template
+//  5. settings and daily notes
+//  6. tags
+//  7. wiki-links
+//  8. note placements in more than one folder
+//  9. folder sort settings and manual sort keys
+const SchemaVersion = 9
+
+// FileName is the vault database inside DataDir.
+const FileName = "noteblock.sqlite"
+
+// DataDir is where the vault and everything beside it (uploads, socket, token) lives.
+func DataDir() string {
 	// Check if Electron gave us a NOTE_DB_PATH
 	basePath := os.Getenv("NOTE_DB_PATH")
 	if basePath == "" {
 		// Fallback for dev: use local "data" folder
 		basePath = "data"
 	}
+	return basePath
+}
+
+func InitDb() *gorm.DB {
+	basePath := DataDir()
+	log.Println("Using database at:", filepath.Join(basePath, FileName))
 
+	db, err := Open(basePath)
+	if err != nil {
+		panic("failed to open DB: " + err.Error())
+	}
+	return db
+}
+
+// Open opens (creating if needed) and migrates the vault in basePath.
+func Open(basePath string) (*gorm.DB, error) {
 	// Ensure folder exists
 	if err := os.MkdirAll(basePath, os.ModePerm); err != nil {
-		log.Fatalf("failed to create db directory: %v", err)
+		return nil, err
 	}
 
-	dbPath := filepath.Join(basePath, "noteblock.sqlite")
-	log.Println("Using database at:", dbPath)
-
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
+	db, err := gorm.Open(sqlite.Open(filepath.Join(basePath, FileName)), &gorm.Config{
+		// stdout belongs to the IPC stream and CLI output, so gorm logs go to stderr
+		Logger: logger.New(log.New(os.Stderr, "", log.LstdFlags), logger.Config{
+			LogLevel:                  logger.Warn,
+			IgnoreRecordNotFoundError: true,
+		}),
+	})
 	if err != nil {
-		panic("failed to open DB: " + err.Error())
+		return nil, err
 	}
 	db.Exec("PRAGMA foreign_keys = ON")
 
 	//////TODO: remove in production
 	//db.Migrator().DropTable(&model.Block{}, &model.Note{}, &model.Folder{})
-	db.AutoMigrate(&model.Block{}, &model.Note{}, &model.Folder{})
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate brings the schema up to SchemaVersion and makes sure the root folder exists.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&model.Block{}, &model.Note{}, &model.Folder{}, &model.Change{}, &model.Setting{}, &model.DailyNote{}, &model.Tag{}, &model.NoteTag{}, &model.Link{}, &model.Placement{}); err != nil {
+		return err
+	}
 
 	var count int64
-	db.Model(&model.Folder{}).Where("id = root", "root").Count(&count)
+	if err := db.Model(&model.Folder{}).Where("id = ?", "root").Count(&count).Error; err != nil {
+		return err
+	}
 	if count == 0 {
-		db.Create(&model.Folder{
+		if err := db.Create(&model.Folder{
 			ID:   "root",
 			Name: "Root",
-		})
+		}).Error; err != nil {
+			return err
+		}
 		log.Println("Created root folder with ID 'root'")
 	}
+	// renaming root used to give it a parent, itself; root has none
+	if err := db.Model(&model.Folder{}).Where("id = ? AND parent_id IS NOT NULL", "root").Update("parent_id", nil).Error; err != nil {
+		return err
+	}
 
-	return db
+	if err := backfillOrderKeys(db); err != nil {
+		return err
+	}
+
+	current, err := GetSchemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if current < SchemaVersion {
+		return setSchemaVersion(db, SchemaVersion)
+	}
+	return nil
+}
+
+// backfillOrderKeys keys every note that has blocks without an order_key,
+// keeping the order of the legacy index column where a vault still has one.
+func backfillOrderKeys(db *gorm.DB) error {
+	var noteIDs []string
+	if err := db.Model(&model.Block{}).
+		Where("order_key IS NULL OR order_key = ''").
+		Distinct().
+		Pluck("note_id", &noteIDs).Error; err != nil {
+		return err
+	}
+	if len(noteIDs) == 0 {
+		return nil
+	}
+
+	orderBy := "created_at, id"
+	if db.Migrator().HasColumn("blocks", "index") {
+		orderBy = `"index", created_at, id`
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, noteID := range noteIDs {
+			var blockIDs []string
+			if err := tx.Raw("SELECT id FROM blocks WHERE note_id = ? ORDER BY "+orderBy, noteID).
+				Scan(&blockIDs).Error; err != nil {
+				return err
+			}
+			keys, err := order.KeysBetween("", "", len(blockIDs))
+			if err != nil {
+				return err
+			}
+			for i, id := range blockIDs {
+				if err := tx.Model(&model.Block{}).Where("id = ?", id).Update("order_key", keys[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+		log.Printf("Assigned block order keys in %d notes", len(noteIDs))
+		return nil
+	})
+}
+
+func GetSchemaVersion(db *gorm.DB) (int, error) {
+	var version int
+	err := db.Raw("PRAGMA user_version").Scan(&version).Error
+	return version, err
+}
+
+func setSchemaVersion(db *gorm.DB, version int) error {
+	// pragmas do not accept bound parameters
+	return db.Exec("PRAGMA user_version = " + strconv.Itoa(version)).Error
 }
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMigrate_KeysLegacyBlockIndexes(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "legacy.sqlite")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test sqlite db: %v", err)
+	}
+
+	// the shape schema 1 left behind: blocks ordered by an integer index
+	for _, stmt := range []string{
+		"CREATE TABLE blocks (id text PRIMARY KEY, note_id text NOT NULL, type text, `index` integer, created_at datetime, updated_at datetime, content text)",
+		"INSERT INTO blocks (id, note_id, type, `index`, content) VALUES ('c', 'n', 'text', 2, '{}'), ('a', 'n', 'text', 0, '{}'), ('b', 'n', 'text', 1, '{}')",
+		"PRAGMA user_version = 1",
+	} {
+		if err := conn.Exec(stmt).Error; err != nil {
+			t.Fatalf("failed to build legacy schema: %v", err)
+		}
+	}
+
+	if err := Migrate(conn); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+
+	var ids []string
+	if err := conn.Raw("SELECT id FROM blocks WHERE note_id = 'n' ORDER BY order_key").Scan(&ids).Error; err != nil {
+		t.Fatalf("failed to read keyed blocks: %v", err)
+	}
+	if strings.Join(ids, "") != "abc" {
+		t.Fatalf("expected order_key to keep the legacy order abc, got %v", ids)
+	}
+	if version, _ := GetSchemaVersion(conn); version != SchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", SchemaVersion, version)
+	}
+}
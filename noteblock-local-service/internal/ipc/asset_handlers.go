@@ -0,0 +1,126 @@
+package ipc
+
+import (
+	"encoding/base64"
+
+	"server/internal/model/dto"
+)
+
+// assetUploadParams carries the image as base64, or as the frame body when
+// the session negotiated FramingBinary.
+type assetUploadParams struct {
+	Filename   string `json:"filename"`
+	DataBase64 string `json:"data_base64,omitempty"`
+}
+
+type assetUploadResult struct {
+	URL string `json:"url"`
+}
+
+// assetRefParams addresses an asset by its noteblock-image:/// URL or bare file name.
+type assetRefParams struct {
+	URL string `json:"url"`
+}
+
+// assetGetParams reads a chunk; omit Length to read up to command.MaxAssetChunk.
+type assetGetParams struct {
+	URL    string `json:"url"`
+	Offset int64  `json:"offset,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// assetGetResult omits DataBase64 on binary sessions; the bytes follow as the frame body.
+type assetGetResult struct {
+	Filename   string `json:"filename"`
+	Offset     int64  `json:"offset"`
+	Size       int    `json:"size"`
+	TotalSize  int64  `json:"total_size"`
+	EOF        bool   `json:"eof"`
+	DataBase64 string `json:"data_base64,omitempty"`
+}
+
+type assetListResult struct {
+	Assets []dto.AssetEntry `json:"assets"`
+}
+
+func (s *Server) assetUpload(req Request) Response {
+	var body assetUploadParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	data := req.Body
+	if len(data) == 0 {
+		decoded, err := base64.StdEncoding.DecodeString(body.DataBase64)
+		if err != nil {
+			return rpcErr(req.ID, "BAD_REQUEST", "Invalid base64 image data")
+		}
+		data = decoded
+	}
+
+	url, err := s.cmds.UploadImage(body.Filename, data)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID: req.ID,
+		Result: assetUploadResult{
+			URL: url,
+		},
+	}
+}
+
+func (s *Server) assetGet(req Request) Response {
+	var body assetGetParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	chunk, err := s.cmds.ReadAsset(body.URL, body.Offset, body.Length)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	result := assetGetResult{
+		Filename:  chunk.Filename,
+		Offset:    chunk.Offset,
+		Size:      len(chunk.Data),
+		TotalSize: chunk.TotalSize,
+		EOF:       chunk.EOF(),
+	}
+	if req.framing == FramingBinary {
+		return Response{ID: req.ID, Result: result, Body: chunk.Data}
+	}
+	result.DataBase64 = base64.StdEncoding.EncodeToString(chunk.Data)
+	return Response{ID: req.ID, Result: result}
+}
+
+func (s *Server) assetStat(req Request) Response {
+	var body assetRefParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	info, err := s.cmds.StatAsset(body.URL)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: info,
+	}
+}
+
+func (s *Server) assetList(req Request) Response {
+	assets, err := s.cmds.ListAssets()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: assetListResult{Assets: assets},
+	}
+}
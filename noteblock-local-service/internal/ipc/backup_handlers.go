@@ -0,0 +1,84 @@
+package ipc
+
+import "server/internal/model/dto"
+
+type backupRestoreParams struct {
+	ID string `json:"id"`
+}
+
+type backupListResult struct {
+	Backups []dto.BackupDTO `json:"backups"`
+}
+
+func (s *Server) backupCreate(req Request) Response {
+	backup, err := s.cmds.CreateBackup()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: backup,
+	}
+}
+
+func (s *Server) backupList(req Request) Response {
+	backups, err := s.cmds.ListBackups()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: backupListResult{Backups: backups},
+	}
+}
+
+// backupRestore runs under the dispatch lock like every handler. IPC sessions
+// and the REST handlers (through Server.Exclusive) all wait for it, so none
+// of them sees or writes the vault half restored.
+func (s *Server) backupRestore(req Request) Response {
+	var body backupRestoreParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	res, err := s.cmds.RestoreBackup(body.ID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: res,
+	}
+}
+
+func (s *Server) backupConfig(req Request) Response {
+	cfg, err := s.cmds.BackupConfig()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: cfg,
+	}
+}
+
+func (s *Server) backupConfigure(req Request) Response {
+	var body dto.BackupConfig
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	cfg, err := s.cmds.ConfigureBackups(body)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: cfg,
+	}
+}
@@ -1,119 +1,150 @@
 package ipc
 
-import "encoding/base64"
+import (
+	"server/internal/model"
+	"server/internal/model/dto"
+)
+
+type blockCreateParams struct {
+	NoteID  string `json:"note_id"`
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Content any    `json:"content"`
+}
+
+type blockUpdateParams struct {
+	NoteID  string `json:"note_id"`
+	BlockID string `json:"block_id"`
+	Type    string `json:"type"`
+	Content any    `json:"content"`
+}
+
+type blockRefParams struct {
+	NoteID  string `json:"note_id"`
+	BlockID string `json:"block_id"`
+}
+
+// blockMoveParams places a block, or for block.copy its copy, after AfterID
+// and/or before BeforeID in TargetNoteID (default: its own note). With neither
+// neighbour it goes to the end of the note.
+type blockMoveParams struct {
+	NoteID       string `json:"note_id"`
+	BlockID      string `json:"block_id"`
+	TargetNoteID string `json:"target_note_id,omitempty"`
+	AfterID      string `json:"after_id,omitempty"`
+	BeforeID     string `json:"before_id,omitempty"`
+}
+
+// blockDeleteResult counts the embeds of the deleted block; they stay in
+// their notes as tombstones with status missing.
+type blockDeleteResult struct {
+	OrphanedEmbeds int `json:"orphaned_embeds"`
+}
+
+type blockReferencesParams struct {
+	BlockID string `json:"block_id"`
+}
+
+type blockReferencesResult struct {
+	References []dto.BlockReferenceDTO `json:"references"`
+}
+
+type blockResult struct {
+	ID       string `json:"id"`
+	NoteID   string `json:"note_id"`
+	Type     string `json:"type"`
+	Index    int    `json:"index"`
+	OrderKey string `json:"order_key"`
+}
 
 func (s *Server) blockCreate(req Request) Response {
-	var body struct {
-		NoteID  string `json:"note_id"`
-		Type    string `json:"type"`
-		Index   int    `json:"index"`
-		Content any    `json:"content"`
-	}
+	var body blockCreateParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.NoteID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing note ID")
-	}
 
-	raw, err := toRawMessage(body.Content)
-	if err != nil {
-		return rpcErr(req.ID, "BAD_REQUEST", "Invalid block content")
-	}
-	block, err := s.blockSvc.CreateNewBlock(body.NoteID, body.Type, body.Index, raw)
+	block, err := s.cmds.CreateBlock(body.NoteID, body.Type, body.Index, body.Content)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to create block")
-	}
-	return Response{
-		ID: req.ID,
-		Result: map[string]any{
-			"id":      block.ID,
-			"note_id": block.NoteID,
-			"type":    block.Type,
-			"index":   block.Index,
-		},
+		return cmdErrToRPC(req.ID, err)
 	}
+	return Response{ID: req.ID, Result: toBlockResult(block)}
 }
 
 func (s *Server) blockUpdate(req Request) Response {
-	var body struct {
-		NoteID  string `json:"note_id"`
-		BlockID string `json:"block_id"`
-		Type    string `json:"type"`
-		Content any    `json:"content"`
-	}
+	var body blockUpdateParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.NoteID == "" || body.BlockID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing note ID or block ID")
-	}
 
-	raw, err := toRawMessage(body.Content)
+	block, err := s.cmds.UpdateBlock(body.NoteID, body.BlockID, body.Type, body.Content)
 	if err != nil {
-		return rpcErr(req.ID, "BAD_REQUEST", "Invalid block content")
+		return cmdErrToRPC(req.ID, err)
 	}
-	block, err := s.blockSvc.UpdateBlockContent(body.NoteID, body.BlockID, body.Type, raw)
+	return Response{ID: req.ID, Result: toBlockResult(block)}
+}
+
+func (s *Server) blockDelete(req Request) Response {
+	var body blockRefParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+	orphaned, err := s.cmds.DeleteBlock(body.NoteID, body.BlockID)
 	if err != nil {
-		return dbErrToRPC(req.ID, err, "Failed to update block")
+		return cmdErrToRPC(req.ID, err)
 	}
 	return Response{
-		ID: req.ID,
-		Result: map[string]any{
-			"id":      block.ID,
-			"note_id": block.NoteID,
-			"type":    block.Type,
-			"index":   block.Index,
-		},
+		ID:     req.ID,
+		Result: blockDeleteResult{OrphanedEmbeds: orphaned},
 	}
 }
 
-func (s *Server) blockDelete(req Request) Response {
-	var body struct {
-		NoteID  string `json:"note_id"`
-		BlockID string `json:"block_id"`
-	}
+func (s *Server) blockReferences(req Request) Response {
+	var body blockReferencesParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.NoteID == "" || body.BlockID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing note ID or block ID")
-	}
-	if err := s.blockSvc.DeleteBlock(body.NoteID, body.BlockID); err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to delete block")
+	refs, err := s.cmds.BlockReferences(body.BlockID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
 	}
 	return Response{
 		ID:     req.ID,
-		Result: map[string]any{},
+		Result: blockReferencesResult{References: refs},
 	}
 }
 
-func (s *Server) assetUpload(req Request) Response {
-	var body struct {
-		Filename   string `json:"filename"`
-		DataBase64 string `json:"data_base64"`
-	}
+func (s *Server) blockMove(req Request) Response {
+	var body blockMoveParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.Filename == "" || body.DataBase64 == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing filename or image data")
-	}
 
-	data, err := base64.StdEncoding.DecodeString(body.DataBase64)
+	block, err := s.cmds.MoveBlock(body.NoteID, body.BlockID, body.TargetNoteID, body.AfterID, body.BeforeID)
 	if err != nil {
-		return rpcErr(req.ID, "BAD_REQUEST", "Invalid base64 image data")
+		return cmdErrToRPC(req.ID, err)
 	}
+	return Response{ID: req.ID, Result: toBlockResult(block)}
+}
 
-	url, err := s.blockSvc.SaveImageBytes(body.Filename, data)
+func (s *Server) blockCopy(req Request) Response {
+	var body blockMoveParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	block, err := s.cmds.CopyBlock(body.NoteID, body.BlockID, body.TargetNoteID, body.AfterID, body.BeforeID)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to save image")
+		return cmdErrToRPC(req.ID, err)
 	}
+	return Response{ID: req.ID, Result: toBlockResult(block)}
+}
 
-	return Response{
-		ID: req.ID,
-		Result: map[string]any{
-			"url": url,
-		},
+func toBlockResult(block *model.Block) blockResult {
+	return blockResult{
+		ID:       block.ID,
+		NoteID:   block.NoteID,
+		Type:     block.Type,
+		Index:    block.Index,
+		OrderKey: block.OrderKey,
 	}
 }
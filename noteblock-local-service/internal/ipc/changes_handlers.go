@@ -0,0 +1,39 @@
+package ipc
+
+import "server/internal/model/dto"
+
+// changesListParams pages through the change log; pass the last seen
+// LastSeq as Since to continue.
+type changesListParams struct {
+	Since uint64 `json:"since,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+type changesListResult struct {
+	Changes []dto.ChangeDTO `json:"changes"`
+	LastSeq uint64          `json:"last_seq"`
+}
+
+func (s *Server) changesList(req Request) Response {
+	var body changesListParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	changes, err := s.cmds.ListChanges(body.Since, body.Limit)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	lastSeq := body.Since
+	if len(changes) > 0 {
+		lastSeq = changes[len(changes)-1].Seq
+	}
+	return Response{
+		ID: req.ID,
+		Result: changesListResult{
+			Changes: changes,
+			LastSeq: lastSeq,
+		},
+	}
+}
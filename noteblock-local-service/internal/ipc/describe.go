@@ -0,0 +1,59 @@
+package ipc
+
+import (
+	"sort"
+
+	"server/internal/jsonschema"
+)
+
+type MethodDescription struct {
+	Name   string            `json:"name"`
+	Params jsonschema.Schema `json:"params"`
+	Result jsonschema.Schema `json:"result"`
+}
+
+// Description is the machine-readable catalog served by rpc.describe and
+// consumed by cmd/ipcgen to emit the renderer's TypeScript definitions.
+type Description struct {
+	ProtocolVersion string                       `json:"protocol_version"`
+	Methods         []MethodDescription          `json:"methods"`
+	Defs            map[string]jsonschema.Schema `json:"$defs"`
+}
+
+// Describe builds the method catalog without needing live services, since
+// the handler table only captures method values.
+func Describe() Description {
+	return describeHandlers((&Server{}).buildHandlers())
+}
+
+func (s *Server) rpcDescribe(req Request) Response {
+	return Response{
+		ID:     req.ID,
+		Result: describeHandlers(s.handlers),
+	}
+}
+
+func describeHandlers(handlers map[string]method) Description {
+	names := make([]string, 0, len(handlers))
+	for name := range handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	reflector := jsonschema.NewReflector()
+	methods := make([]MethodDescription, 0, len(names))
+	for _, name := range names {
+		m := handlers[name]
+		methods = append(methods, MethodDescription{
+			Name:   name,
+			Params: reflector.Reflect(m.params),
+			Result: reflector.Reflect(m.result),
+		})
+	}
+
+	return Description{
+		ProtocolVersion: ProtocolVersion,
+		Methods:         methods,
+		Defs:            reflector.Defs,
+	}
+}
@@ -1,27 +1,92 @@
 package ipc
 
+import "server/internal/model/dto"
+
 type handlerFn func(Request) Response
 
-func (s *Server) buildHandlers() map[string]handlerFn {
-	return map[string]handlerFn{
-		"folder.create":     s.folderCreate,
-		"folder.get":        s.folderGet,
-		"folder.update":     s.folderUpdate,
-		"folder.delete":     s.folderDelete,
-		"note.create":       s.noteCreate,
-		"note.get":          s.noteGet,
-		"note.update":       s.noteUpdate,
-		"note.delete":       s.noteDelete,
-		"block.create":      s.blockCreate,
-		"block.update":      s.blockUpdate,
-		"block.delete":      s.blockDelete,
-		"asset.uploadImage": s.assetUpload,
+// method pairs a handler with zero values of its params and result types so
+// rpc.describe can publish their schemas. Mutating methods are broadcast to
+// every connected session once they succeed.
+type method struct {
+	handler handlerFn
+	params  any
+	result  any
+	mutates bool
+}
+
+func query(handler handlerFn, params, result any) method {
+	return method{handler: handler, params: params, result: result}
+}
+
+func mutation(handler handlerFn, params, result any) method {
+	return method{handler: handler, params: params, result: result, mutates: true}
+}
+
+func (s *Server) buildHandlers() map[string]method {
+	return map[string]method{
+		"initialize":              query(s.initialize, initializeParams{}, initializeResult{}),
+		"rpc.describe":            query(s.rpcDescribe, emptyParams{}, Description{}),
+		"folder.create":           mutation(s.folderCreate, folderCreateParams{}, folderResult{}),
+		"folder.get":              query(s.folderGet, idParams{}, dto.FolderResponse{}),
+		"folder.children":         query(s.folderChildren, idParams{}, dto.FolderChildren{}),
+		"folder.sort":             mutation(s.folderSort, folderSortParams{}, dto.FolderChildren{}),
+		"folder.reorder":          mutation(s.folderReorder, folderReorderParams{}, dto.FolderChildren{}),
+		"folder.move":             mutation(s.folderMove, folderMoveParams{}, dto.FolderChildren{}),
+		"folder.update":           mutation(s.folderUpdate, folderUpdateParams{}, folderResult{}),
+		"folder.delete":           mutation(s.folderDelete, idParams{}, folderDeleteResult{}),
+		"folder.duplicate":        mutation(s.folderDuplicate, folderDuplicateParams{}, dto.FolderResponse{}),
+		"note.create":             mutation(s.noteCreate, noteCreateParams{}, noteResult{}),
+		"note.get":                query(s.noteGet, idParams{}, dto.NoteDTO{}),
+		"note.update":             mutation(s.noteUpdate, noteUpdateParams{}, noteUpdateResult{}),
+		"note.delete":             mutation(s.noteDelete, idParams{}, messageResult{}),
+		"note.duplicate":          mutation(s.noteDuplicate, noteDuplicateParams{}, dto.NoteDTO{}),
+		"note.createFromTemplate": mutation(s.noteCreateFromTemplate, noteFromTemplateParams{}, dto.NoteDTO{}),
+		"note.placements":         query(s.notePlacements, idParams{}, placementsResult{}),
+		"note.place":              mutation(s.notePlace, notePlaceParams{}, placementsResult{}),
+		"note.unplace":            mutation(s.noteUnplace, notePlaceParams{}, placementsResult{}),
+		"note.backlinks":          query(s.noteBacklinks, idParams{}, backlinksResult{}),
+		"note.outgoingLinks":      query(s.noteOutgoingLinks, idParams{}, outgoingLinksResult{}),
+		"link.unresolved":         query(s.linkUnresolved, emptyParams{}, unresolvedLinksResult{}),
+		"note.tags.set":           mutation(s.noteTagsSet, noteTagsParams{}, noteTagsResult{}),
+		"note.tags.add":           mutation(s.noteTagsAdd, noteTagsParams{}, noteTagsResult{}),
+		"note.tags.remove":        mutation(s.noteTagsRemove, noteTagsParams{}, noteTagsResult{}),
+		"note.listByTags":         query(s.noteListByTags, notesByTagsParams{}, notesByTagsResult{}),
+		"tag.list":                query(s.tagList, emptyParams{}, tagListResult{}),
+		"tag.rename":              mutation(s.tagRename, tagRenameParams{}, dto.TagDTO{}),
+		"tag.merge":               mutation(s.tagMerge, tagMergeParams{}, dto.TagDTO{}),
+		"tag.config":              query(s.tagConfig, emptyParams{}, dto.TagConfig{}),
+		"tag.configure":           mutation(s.tagConfigure, dto.TagConfig{}, dto.TagConfig{}),
+		"template.set":            mutation(s.templateSet, templateSetParams{}, templateSetResult{}),
+		"template.list":           query(s.templateList, emptyParams{}, templateListResult{}),
+		"block.create":            mutation(s.blockCreate, blockCreateParams{}, blockResult{}),
+		"block.update":            mutation(s.blockUpdate, blockUpdateParams{}, blockResult{}),
+		"block.move":              mutation(s.blockMove, blockMoveParams{}, blockResult{}),
+		"block.copy":              mutation(s.blockCopy, blockMoveParams{}, blockResult{}),
+		"block.delete":            mutation(s.blockDelete, blockRefParams{}, blockDeleteResult{}),
+		"block.references":        query(s.blockReferences, blockReferencesParams{}, blockReferencesResult{}),
+		"backup.create":           query(s.backupCreate, emptyParams{}, dto.BackupDTO{}),
+		"backup.list":             query(s.backupList, emptyParams{}, backupListResult{}),
+		"backup.restore":          mutation(s.backupRestore, backupRestoreParams{}, dto.BackupRestoreDTO{}),
+		"backup.config":           query(s.backupConfig, emptyParams{}, dto.BackupConfig{}),
+		"backup.configure":        mutation(s.backupConfigure, dto.BackupConfig{}, dto.BackupConfig{}),
+		"vault.check":             query(s.vaultCheck, vaultCheckParams{}, dto.VaultCheckDTO{}),
+		"asset.uploadImage":       mutation(s.assetUpload, assetUploadParams{}, assetUploadResult{}),
+		"asset.get":               query(s.assetGet, assetGetParams{}, assetGetResult{}),
+		"asset.stat":              query(s.assetStat, assetRefParams{}, dto.AssetInfo{}),
+		"asset.list":              query(s.assetList, emptyParams{}, assetListResult{}),
+		"journal.today":           query(s.journalToday, journalTodayParams{}, journalDayResult{}),
+		"journal.get":             query(s.journalGet, journalGetParams{}, journalDayResult{}),
+		"journal.range":           query(s.journalRange, journalRangeParams{}, journalRangeResult{}),
+		"journal.config":          query(s.journalConfig, emptyParams{}, dto.JournalConfig{}),
+		"journal.configure":       mutation(s.journalConfigure, journalConfigureParams{}, dto.JournalConfig{}),
+		"graph.get":               query(s.graphGet, graphGetParams{}, dto.Graph{}),
+		"changes.list":            query(s.changesList, changesListParams{}, changesListResult{}),
 	}
 }
 
 func (s *Server) handle(req Request) Response {
-	if handler, ok := s.handlers[req.Method]; ok {
-		return handler(req)
+	if m, ok := s.handlers[req.Method]; ok {
+		return m.handler(req)
 	}
 	return rpcErr(req.ID, "METHOD_NOT_FOUND", "Unknown method: "+req.Method)
 }
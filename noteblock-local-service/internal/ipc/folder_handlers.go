@@ -1,68 +1,121 @@
 package ipc
 
+type folderCreateParams struct {
+	Name     *string `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+type folderUpdateParams struct {
+	CurrentID *string `json:"current_id"`
+	Name      *string `json:"name"`
+	ParentID  *string `json:"parent_id"`
+}
+
+type folderResult struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+// folderDuplicateParams copies folder ID into ParentID, or next to the original.
+type folderDuplicateParams struct {
+	ID       string  `json:"id"`
+	ParentID *string `json:"parent_id"`
+}
+
+// folderSortParams sets how folder ID orders its contents; SortBy is name,
+// created, updated or manual.
+type folderSortParams struct {
+	ID       string `json:"id"`
+	SortBy   string `json:"sort_by"`
+	SortDesc bool   `json:"sort_desc,omitempty"`
+}
+
+// folderReorderParams lists every child folder and/or every note of folder
+// ID in their new manual order. An omitted list keeps that order.
+type folderReorderParams struct {
+	ID        string   `json:"id"`
+	FolderIDs []string `json:"folder_ids,omitempty"`
+	NoteIDs   []string `json:"note_ids,omitempty"`
+}
+
+// folderMoveParams moves FolderIDs and NoteIDs into folder TargetID, all or
+// none of them.
+type folderMoveParams struct {
+	TargetID  string   `json:"target_id"`
+	FolderIDs []string `json:"folder_ids,omitempty"`
+	NoteIDs   []string `json:"note_ids,omitempty"`
+}
+
+type folderDeleteResult struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
 func (s *Server) folderCreate(req Request) Response {
-	var body struct {
-		Name     *string `json:"name"`
-		ParentID *string `json:"parent_id"`
-	}
+	var body folderCreateParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
 
-	if body.ParentID == nil || *body.ParentID == "" {
-		root := "root"
-		body.ParentID = &root
+	folder, err := s.cmds.CreateFolder(body.Name, body.ParentID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID: req.ID,
+		Result: folderResult{
+			ID:       folder.ID,
+			Name:     folder.Name,
+			ParentID: folder.ParentID,
+		},
 	}
+}
 
-	if _, err := s.folderSvc.GetFolderByID(*body.ParentID); err != nil {
-		return dbErrToRPC(req.ID, err, "Failed to query parent folder")
+func (s *Server) folderGet(req Request) Response {
+	var body idParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
 
-	existingFolders, err := s.folderSvc.ListChildrenByParentId(body.ParentID)
+	folder, err := s.cmds.GetFolderTree(body.ID)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to query folders")
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: folder,
 	}
+}
 
-	if body.Name == nil || *body.Name == "" {
-		newName := generateUniqueFolderName(existingFolders)
-		body.Name = &newName
-	} else {
-		for _, f := range existingFolders {
-			if f.Name == *body.Name {
-				return rpcErr(req.ID, "CONFLICT", "Folder with that name already exists")
-			}
-		}
+func (s *Server) folderChildren(req Request) Response {
+	var body idParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
 
-	folder, err := s.folderSvc.CreateNewFolder(*body.Name, body.ParentID)
+	folder, err := s.cmds.FolderChildren(body.ID)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to create new folder")
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
-		ID: req.ID,
-		Result: map[string]any{
-			"id":        folder.ID,
-			"name":      folder.Name,
-			"parent_id": folder.ParentID,
-		},
+		ID:     req.ID,
+		Result: folder,
 	}
 }
 
-func (s *Server) folderGet(req Request) Response {
-	var body struct {
-		ID string `json:"id"`
-	}
+func (s *Server) folderSort(req Request) Response {
+	var body folderSortParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.ID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing folder ID")
-	}
 
-	folder, err := s.folderSvc.GetFolderDtoById(body.ID)
+	folder, err := s.cmds.SetFolderSort(body.ID, body.SortBy, body.SortDesc)
 	if err != nil {
-		return dbErrToRPC(req.ID, err, "Failed to retrieve folder")
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
@@ -71,94 +124,94 @@ func (s *Server) folderGet(req Request) Response {
 	}
 }
 
-func (s *Server) folderUpdate(req Request) Response {
-	var body struct {
-		CurrentID *string `json:"current_id"`
-		Name      *string `json:"name"`
-		ParentID  *string `json:"parent_id"`
-	}
+func (s *Server) folderReorder(req Request) Response {
+	var body folderReorderParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.CurrentID == nil || *body.CurrentID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing current folder ID")
-	}
 
-	currentFolder, err := s.folderSvc.GetFolderByID(*body.CurrentID)
+	folder, err := s.cmds.ReorderFolder(body.ID, body.FolderIDs, body.NoteIDs)
 	if err != nil {
-		return dbErrToRPC(req.ID, err, "Failed to retrieve folder")
+		return cmdErrToRPC(req.ID, err)
 	}
 
-	targetName := currentFolder.Name
-	if body.Name != nil && *body.Name != "" {
-		targetName = *body.Name
+	return Response{
+		ID:     req.ID,
+		Result: folder,
 	}
+}
 
-	targetParentID := currentFolder.ParentID
-	if body.ParentID != nil && *body.ParentID != "" {
-		targetParentID = body.ParentID
-	}
-	if targetParentID == nil || *targetParentID == "" {
-		root := "root"
-		targetParentID = &root
+func (s *Server) folderMove(req Request) Response {
+	var body folderMoveParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
 
-	if _, err := s.folderSvc.GetFolderByID(*targetParentID); err != nil {
-		return dbErrToRPC(req.ID, err, "Parent folder does not exist")
+	folder, err := s.cmds.MoveItems(body.TargetID, body.FolderIDs, body.NoteIDs)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
 	}
 
-	siblings, err := s.folderSvc.ListChildrenByParentId(targetParentID)
-	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to check sibling folders")
+	return Response{
+		ID:     req.ID,
+		Result: folder,
 	}
-	for _, f := range siblings {
-		if f.Name == targetName && f.ID != currentFolder.ID {
-			return rpcErr(req.ID, "CONFLICT", "Folder with that name already exists in the target parent")
-		}
+}
+
+func (s *Server) folderUpdate(req Request) Response {
+	var body folderUpdateParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
 
-	updated, err := s.folderSvc.UpdateFolder(currentFolder.ID, targetName, targetParentID)
+	updated, err := s.cmds.UpdateFolder(body.CurrentID, body.Name, body.ParentID)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to update folder")
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
 		ID: req.ID,
-		Result: map[string]any{
-			"id":        updated.ID,
-			"name":      updated.Name,
-			"parent_id": updated.ParentID,
+		Result: folderResult{
+			ID:       updated.ID,
+			Name:     updated.Name,
+			ParentID: updated.ParentID,
 		},
 	}
 }
 
 func (s *Server) folderDelete(req Request) Response {
-	var body struct {
-		ID string `json:"id"`
-	}
+	var body idParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.ID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing folder ID")
-	}
-	if body.ID == "root" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Cannot delete root folder")
-	}
 
-	folder, err := s.folderSvc.GetFolderByID(body.ID)
+	folder, err := s.cmds.DeleteFolder(body.ID)
 	if err != nil {
-		return dbErrToRPC(req.ID, err, "Folder was not found or does not exist")
-	}
-	if err := s.folderSvc.DeleteFolderAndContents(folder.ID); err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Something went wrong in the deletion process of the folder")
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
 		ID: req.ID,
-		Result: map[string]any{
-			"id":      folder.ID,
-			"message": "Folder deleted successfully",
+		Result: folderDeleteResult{
+			ID:      folder.ID,
+			Message: "Folder deleted successfully",
 		},
 	}
 }
+
+func (s *Server) folderDuplicate(req Request) Response {
+	var body folderDuplicateParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	folder, err := s.cmds.DuplicateFolder(body.ID, body.ParentID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: folder,
+	}
+}
@@ -0,0 +1,133 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Framing modes a stream session can speak. Every session starts in
+// FramingJSON; a client that lists FramingBinary in initialize switches both
+// directions to binary frames right after the initialize response.
+//
+// A binary frame is a big-endian uint32 header length, a big-endian uint32
+// body length, the JSON header (a Request, Response or Notification) and then
+// the raw body. Bodies carry asset bytes that would otherwise be base64 in JSON.
+const (
+	FramingJSON   = "json"
+	FramingBinary = "binary"
+)
+
+// maxMessageSize bounds a single line, header or body.
+const maxMessageSize = 20 * 1024 * 1024
+
+var errMessageTooLarge = errors.New("ipc message exceeds 20MB")
+
+// frameReader reads requests in whichever framing the session is using.
+type frameReader struct {
+	br     *bufio.Reader
+	binary bool
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{br: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// next returns the raw JSON of the next message and its body, if any.
+// Blank lines between JSON messages are skipped.
+func (fr *frameReader) next() ([]byte, []byte, error) {
+	if fr.binary {
+		return fr.readFrame()
+	}
+	for {
+		line, err := fr.readLine()
+		if len(line) > 0 {
+			return line, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func (fr *frameReader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := fr.br.ReadSlice('\n')
+		line = append(line, chunk...)
+		if len(line) > maxMessageSize {
+			return nil, errMessageTooLarge
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		trimmed := trimNewline(line)
+		if err != nil && len(trimmed) > 0 && errors.Is(err, io.EOF) {
+			return trimmed, nil
+		}
+		return trimmed, err
+	}
+}
+
+func (fr *frameReader) readFrame() ([]byte, []byte, error) {
+	var lengths [8]byte
+	if _, err := io.ReadFull(fr.br, lengths[:]); err != nil {
+		return nil, nil, err
+	}
+	headerLen := binary.BigEndian.Uint32(lengths[:4])
+	bodyLen := binary.BigEndian.Uint32(lengths[4:])
+	if headerLen > maxMessageSize || bodyLen > maxMessageSize {
+		return nil, nil, errMessageTooLarge
+	}
+
+	buf := make([]byte, int(headerLen)+int(bodyLen))
+	if _, err := io.ReadFull(fr.br, buf); err != nil {
+		return nil, nil, unexpectedEOF(err)
+	}
+	var body []byte
+	if bodyLen > 0 {
+		body = buf[headerLen:]
+	}
+	return buf[:headerLen], body, nil
+}
+
+// writeFrame writes v as a binary frame followed by body.
+func writeFrame(w io.Writer, v any, body []byte) error {
+	header, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 8, 8+len(header)+len(body))
+	binary.BigEndian.PutUint32(frame[:4], uint32(len(header)))
+	binary.BigEndian.PutUint32(frame[4:], uint32(len(body)))
+	frame = append(frame, header...)
+	frame = append(frame, body...)
+	_, err = w.Write(frame)
+	return err
+}
+
+// negotiateFraming picks the best framing both sides support.
+func negotiateFraming(offered []string) string {
+	for _, f := range offered {
+		if f == FramingBinary {
+			return FramingBinary
+		}
+	}
+	return FramingJSON
+}
+
+func trimNewline(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package ipc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"testing"
+)
+
+func writeTestFrame(t *testing.T, w io.Writer, v any, body []byte) {
+	t.Helper()
+	if err := writeFrame(w, v, body); err != nil {
+		t.Fatalf("failed to write frame: %v", err)
+	}
+}
+
+func readTestFrame(t *testing.T, r *bufio.Reader) (map[string]any, []byte) {
+	t.Helper()
+	var lengths [8]byte
+	if _, err := io.ReadFull(r, lengths[:]); err != nil {
+		t.Fatalf("failed to read frame lengths: %v", err)
+	}
+	buf := make([]byte, binary.BigEndian.Uint32(lengths[:4])+binary.BigEndian.Uint32(lengths[4:]))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("failed to read frame: %v", err)
+	}
+	headerLen := binary.BigEndian.Uint32(lengths[:4])
+	var header map[string]any
+	if err := json.Unmarshal(buf[:headerLen], &header); err != nil {
+		t.Fatalf("frame header is not json: %v", err)
+	}
+	return header, buf[headerLen:]
+}
+
+func TestFraming_BinaryUploadAndGet(t *testing.T) {
+	srv := setupTestServer(t)
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		_ = srv.Run(inR, outW)
+		_ = outW.Close()
+	}()
+	defer inW.Close()
+	out := bufio.NewReader(outR)
+
+	_, _ = inW.Write([]byte(`{"id":"1","method":"initialize","params":{"protocol_version":"` + ProtocolVersion + `","framing":["binary"]}}` + "\n"))
+	line, err := out.ReadBytes('\n')
+	if err != nil {
+		t.Fatalf("failed to read initialize response: %v", err)
+	}
+	var initRes struct {
+		Result initializeResult `json:"result"`
+	}
+	if err := json.Unmarshal(line, &initRes); err != nil || initRes.Result.Framing != FramingBinary {
+		t.Fatalf("expected binary framing, got %s (%v)", line, err)
+	}
+
+	image := []byte{0x89, 'P', 'N', 'G', '\n', 0x00, 0xff}
+	writeTestFrame(t, inW, map[string]any{"id": "2", "method": "asset.uploadImage", "params": map[string]any{"filename": "x.png"}}, image)
+	// the upload is a mutation, so its change event is broadcast, framed, ahead of the response
+	header, _ := readTestFrame(t, out)
+	if header["method"] != "event.changed" {
+		t.Fatalf("expected event.changed, got %+v", header)
+	}
+	header, _ = readTestFrame(t, out)
+	result, _ := header["result"].(map[string]any)
+	url, _ := result["url"].(string)
+	if url == "" {
+		t.Fatalf("expected upload url, got %+v", header)
+	}
+
+	writeTestFrame(t, inW, map[string]any{"id": "3", "method": "asset.get", "params": map[string]any{"url": url}}, nil)
+	header, body := readTestFrame(t, out)
+	result, _ = header["result"].(map[string]any)
+	if _, inlined := result["data_base64"]; inlined {
+		t.Fatalf("expected bytes in the frame body, not the result: %+v", result)
+	}
+	if !bytes.Equal(body, image) {
+		t.Fatalf("expected body %v, got %v", image, body)
+	}
+}
+
+func TestFraming_OlderClientsStayOnJSON(t *testing.T) {
+	srv := setupTestServer(t)
+	input := `{"id":"1","method":"initialize","params":{"protocol_version":"1.0"}}` + "\n" +
+		`{"id":"2","method":"asset.uploadImage","params":{"filename":"x.png","data_base64":"AQID"}}` + "\n"
+	var out bytes.Buffer
+	if err := srv.Run(bytes.NewBufferString(input), &out); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	scanner := bufio.NewScanner(&out)
+	var framings []string
+	for scanner.Scan() {
+		var msg struct {
+			Result struct {
+				Framing string `json:"framing"`
+				URL     string `json:"url"`
+			} `json:"result"`
+			Error *RPCError `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("expected newline json, got %q", scanner.Text())
+		}
+		if msg.Error != nil {
+			t.Fatalf("unexpected error: %+v", msg.Error)
+		}
+		if msg.Result.Framing != "" {
+			framings = append(framings, msg.Result.Framing)
+		}
+	}
+	if len(framings) != 1 || framings[0] != FramingJSON {
+		t.Fatalf("expected json framing, got %v", framings)
+	}
+}
+
+func TestFraming_BroadcastDuringHandshake(t *testing.T) {
+	srv := setupTestServer(t)
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	go func() {
+		_ = srv.Run(inR, outW)
+		_ = outW.Close()
+	}()
+	defer inW.Close()
+	out := bufio.NewReader(outR)
+
+	// another session's mutations keep broadcasting while this one switches
+	stop := make(chan struct{})
+	defer close(stop)
+	for range 8 {
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					srv.broadcast(Notification{Method: "event.changed", Params: changeEvent{Method: "note.update"}})
+				}
+			}
+		}()
+	}
+
+	go func() {
+		_, _ = inW.Write([]byte(`{"id":"1","method":"initialize","params":{"protocol_version":"` + ProtocolVersion + `","framing":["binary"]}}` + "\n"))
+	}()
+	for {
+		line, err := out.ReadBytes('\n')
+		if err != nil {
+			t.Fatalf("failed to read json line: %v", err)
+		}
+		var msg struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(line, &msg); err != nil {
+			t.Fatalf("expected json before the switch, got %q", line)
+		}
+		if msg.ID == "1" {
+			break
+		}
+	}
+
+	go func() { _ = writeFrame(inW, map[string]any{"id": "2", "method": "tag.list"}, nil) }()
+	for {
+		// a frame starts with its header length, never with a json brace
+		if first, err := out.Peek(1); err != nil || first[0] == '{' {
+			t.Fatalf("expected only binary frames after the switch, got %q (%v)", first, err)
+		}
+		header, _ := readTestFrame(t, out)
+		if header["id"] == "2" {
+			return
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package ipc
+
+import "server/internal/command"
+
+// graphGetParams returns the whole vault, or the Depth-hop neighbourhood of
+// NoteID when it is set. FolderID and Tags narrow the notes first.
+type graphGetParams struct {
+	NoteID         string   `json:"note_id,omitempty"`
+	Depth          int      `json:"depth,omitempty"`
+	FolderID       string   `json:"folder_id,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	HideOrphans    bool     `json:"hide_orphans,omitempty"`
+	IncludeTags    bool     `json:"include_tags,omitempty"`
+	IncludeFolders bool     `json:"include_folders,omitempty"`
+}
+
+func (s *Server) graphGet(req Request) Response {
+	var body graphGetParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	graph, err := s.cmds.Graph(command.GraphOptions(body))
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: graph,
+	}
+}
@@ -0,0 +1,119 @@
+package ipc
+
+import "server/internal/model/dto"
+
+// journalTodayParams picks "today" in Timezone, an IANA name such as
+// Europe/Berlin; the server's local zone is used when it is omitted.
+type journalTodayParams struct {
+	Timezone string `json:"timezone,omitempty"`
+}
+
+type journalGetParams struct {
+	Date string `json:"date"`
+}
+
+type journalDayResult struct {
+	Date    string      `json:"date"`
+	Created bool        `json:"created"`
+	Note    dto.NoteDTO `json:"note"`
+}
+
+type journalRangeParams struct {
+	Month string `json:"month"`
+}
+
+type journalRangeResult struct {
+	Entries []dto.JournalEntryDTO `json:"entries"`
+}
+
+// journalConfigureParams leaves nil fields unchanged; empty strings restore
+// the default.
+type journalConfigureParams struct {
+	FolderID    *string `json:"folder_id"`
+	TitleFormat *string `json:"title_format"`
+	TemplateID  *string `json:"template_id"`
+}
+
+func (s *Server) journalToday(req Request) Response {
+	var body journalTodayParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	date, note, created, err := s.cmds.JournalToday(body.Timezone)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return s.journalDay(req, journalDayResult{Date: date, Created: created, Note: *note})
+}
+
+func (s *Server) journalGet(req Request) Response {
+	var body journalGetParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	note, created, err := s.cmds.JournalDay(body.Date)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return s.journalDay(req, journalDayResult{Date: body.Date, Created: created, Note: *note})
+}
+
+// journalDay answers journal.today and journal.get. Both are queries, so
+// looking up an existing day stays quiet; creating its note is announced like
+// any mutation.
+func (s *Server) journalDay(req Request, res journalDayResult) Response {
+	if res.Created {
+		s.announce(req.Method, req.Params, res)
+	}
+	return Response{ID: req.ID, Result: res}
+}
+
+func (s *Server) journalRange(req Request) Response {
+	var body journalRangeParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	entries, err := s.cmds.JournalRange(body.Month)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: journalRangeResult{Entries: entries},
+	}
+}
+
+func (s *Server) journalConfig(req Request) Response {
+	cfg, err := s.cmds.JournalConfig()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: cfg,
+	}
+}
+
+func (s *Server) journalConfigure(req Request) Response {
+	var body journalConfigureParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	cfg, err := s.cmds.ConfigureJournal(body.FolderID, body.TitleFormat, body.TemplateID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: cfg,
+	}
+}
@@ -0,0 +1,61 @@
+package ipc
+
+import "server/internal/model/dto"
+
+type backlinksResult struct {
+	Links []dto.BacklinkDTO `json:"links"`
+}
+
+type outgoingLinksResult struct {
+	Links []dto.LinkDTO `json:"links"`
+}
+
+type unresolvedLinksResult struct {
+	Links []dto.UnresolvedLinkDTO `json:"links"`
+}
+
+func (s *Server) noteBacklinks(req Request) Response {
+	var body idParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	links, err := s.cmds.Backlinks(body.ID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: backlinksResult{Links: links},
+	}
+}
+
+func (s *Server) noteOutgoingLinks(req Request) Response {
+	var body idParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	links, err := s.cmds.OutgoingLinks(body.ID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: outgoingLinksResult{Links: links},
+	}
+}
+
+func (s *Server) linkUnresolved(req Request) Response {
+	links, err := s.cmds.UnresolvedLinks()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: unresolvedLinksResult{Links: links},
+	}
+}
@@ -1,75 +1,71 @@
 package ipc
 
-import (
-	"server/internal/api/mapper"
-	"server/internal/model"
-	"server/internal/model/dto"
+import "server/internal/service"
 
-	"gorm.io/gorm"
-)
+type noteCreateParams struct {
+	Title    *string `json:"title"`
+	FolderID *string `json:"folder_id"`
+}
 
-func (s *Server) noteCreate(req Request) Response {
-	var body struct {
-		Title    *string `json:"title"`
-		FolderID *string `json:"folder_id"`
-	}
-	if err := parseParams(req.Params, &body); err != nil {
-		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
-	}
+// noteDuplicateParams copies note ID into FolderID, or next to the original.
+type noteDuplicateParams struct {
+	ID       string  `json:"id"`
+	FolderID *string `json:"folder_id"`
+}
 
-	if body.FolderID == nil || *body.FolderID == "" {
-		root := "root"
-		body.FolderID = &root
-	}
+type noteUpdateParams struct {
+	ID       string        `json:"id"`
+	Title    *string       `json:"title"`
+	FolderID *string       `json:"folder_id"`
+	Blocks   *[]blockOrder `json:"blocks"`
+}
 
-	existingNotesInFolder, err := s.noteSvc.ListNotesByFolderId(body.FolderID)
-	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to query notes in current folder")
-	}
+// blockOrder is the slice of a block note.update needs to reorder it.
+type blockOrder struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+}
 
-	if body.Title == nil || *body.Title == "" {
-		newName := generateUniqueNoteName(existingNotesInFolder)
-		body.Title = &newName
-	} else {
-		for _, n := range existingNotesInFolder {
-			if n.Title == *body.Title {
-				return rpcErr(req.ID, "CONFLICT", "Note with that title already exists in this folder")
-			}
-		}
+type noteResult struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	FolderID string `json:"folder_id"`
+}
+
+type noteUpdateResult struct {
+	noteResult
+	Message string `json:"message"`
+}
+
+func (s *Server) noteCreate(req Request) Response {
+	var body noteCreateParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
 
-	note, err := s.noteSvc.NewNote(*body.Title, *body.FolderID)
+	note, err := s.cmds.CreateNote(body.Title, body.FolderID)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to create new note")
+		return cmdErrToRPC(req.ID, err)
 	}
 	return Response{
 		ID: req.ID,
-		Result: map[string]any{
-			"id":        note.ID,
-			"title":     note.Title,
-			"folder_id": note.FolderID,
+		Result: noteResult{
+			ID:       note.ID,
+			Title:    note.Title,
+			FolderID: note.FolderID,
 		},
 	}
 }
 
 func (s *Server) noteGet(req Request) Response {
-	var body struct {
-		ID string `json:"id"`
-	}
+	var body idParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.ID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing note ID")
-	}
 
-	note, err := s.noteSvc.GetNote(body.ID)
-	if err != nil {
-		return dbErrToRPC(req.ID, err, "Failed to retrieve note")
-	}
-	dtoNote, err := mapper.ToNoteDTO(note)
+	dtoNote, err := s.cmds.GetNote(body.ID)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to map note to DTO")
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
@@ -79,94 +75,68 @@ func (s *Server) noteGet(req Request) Response {
 }
 
 func (s *Server) noteUpdate(req Request) Response {
-	var body struct {
-		ID       string          `json:"id"`
-		Title    *string         `json:"title"`
-		FolderID *string         `json:"folder_id"`
-		Blocks   *[]dto.BlockDTO `json:"blocks"`
-	}
+	var body noteUpdateParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.ID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing note ID")
-	}
-
-	existingNote, err := s.noteSvc.GetNoteMetaData(body.ID)
-	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to retrieve note metadata")
-	}
-
-	targetTitle := existingNote.Title
-	if body.Title != nil && *body.Title != "" {
-		targetTitle = *body.Title
-	}
-
-	targetFolderID := existingNote.FolderID
-	if body.FolderID != nil && *body.FolderID != "" {
-		targetFolderID = *body.FolderID
-	}
 
+	var order *[]service.BlockIndex
 	if body.Blocks != nil {
-		err = s.blockSvc.DB.Transaction(func(tx *gorm.DB) error {
-			for _, block := range *body.Blocks {
-				if err := tx.Model(&model.Block{}).
-					Where("id = ? AND note_id = ?", block.ID, body.ID).
-					Update("index", block.Index).Error; err != nil {
-					return err
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			return rpcErr(req.ID, "INTERNAL", "Failed to update blocks")
+		indexes := make([]service.BlockIndex, 0, len(*body.Blocks))
+		for _, block := range *body.Blocks {
+			indexes = append(indexes, service.BlockIndex{ID: block.ID, Index: block.Index})
 		}
+		order = &indexes
 	}
 
-	notesInFolder, err := s.noteSvc.ListNotesByFolderId(&targetFolderID)
+	data, err := s.cmds.UpdateNote(body.ID, body.Title, body.FolderID, order)
 	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to retrieve notes in new folder")
-	}
-	for _, n := range notesInFolder {
-		if n.Title == targetTitle && n.ID != body.ID {
-			return rpcErr(req.ID, "CONFLICT", "Note with that title already exists in the destination folder")
-		}
-	}
-
-	data, err := s.noteSvc.UpdateNoteMetaData(body.ID, targetTitle, targetFolderID)
-	if err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to update note metadata")
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
 		ID: req.ID,
-		Result: map[string]any{
-			"id":        data.ID,
-			"title":     data.Title,
-			"folder_id": data.FolderID,
-			"message":   "Note and blocks updated successfully",
+		Result: noteUpdateResult{
+			noteResult: noteResult{
+				ID:       data.ID,
+				Title:    data.Title,
+				FolderID: data.FolderID,
+			},
+			Message: "Note and blocks updated successfully",
 		},
 	}
 }
 
 func (s *Server) noteDelete(req Request) Response {
-	var body struct {
-		ID string `json:"id"`
-	}
+	var body idParams
 	if err := parseParams(req.Params, &body); err != nil {
 		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
 	}
-	if body.ID == "" {
-		return rpcErr(req.ID, "BAD_REQUEST", "Missing note ID")
-	}
-	if err := s.noteSvc.DeleteNote(body.ID); err != nil {
-		return rpcErr(req.ID, "INTERNAL", "Failed to delete note")
+	if err := s.cmds.DeleteNote(body.ID); err != nil {
+		return cmdErrToRPC(req.ID, err)
 	}
 
 	return Response{
 		ID: req.ID,
-		Result: map[string]any{
-			"message": "Note deleted successfully",
+		Result: messageResult{
+			Message: "Note deleted successfully",
 		},
 	}
 }
+
+func (s *Server) noteDuplicate(req Request) Response {
+	var body noteDuplicateParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	note, err := s.cmds.DuplicateNote(body.ID, body.FolderID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: note,
+	}
+}
@@ -0,0 +1,63 @@
+package ipc
+
+import "server/internal/model/dto"
+
+type notePlaceParams struct {
+	ID       string `json:"id"`
+	FolderID string `json:"folder_id"`
+}
+
+type placementsResult struct {
+	Placements []dto.PlacementDTO `json:"placements"`
+}
+
+func (s *Server) notePlacements(req Request) Response {
+	var body idParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	placements, err := s.cmds.NotePlacements(body.ID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: placementsResult{Placements: placements},
+	}
+}
+
+func (s *Server) notePlace(req Request) Response {
+	var body notePlaceParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	placements, err := s.cmds.PlaceNote(body.ID, body.FolderID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: placementsResult{Placements: placements},
+	}
+}
+
+func (s *Server) noteUnplace(req Request) Response {
+	var body notePlaceParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	placements, err := s.cmds.UnplaceNote(body.ID, body.FolderID)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: placementsResult{Placements: placements},
+	}
+}
@@ -0,0 +1,118 @@
+package ipc
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"server/internal/buildinfo"
+	"server/internal/db"
+)
+
+// ProtocolVersion is the wire protocol spoken by this server as "major.minor".
+// Minor bumps only add methods or optional fields; a major bump breaks clients.
+const ProtocolVersion = "1.17"
+
+// capabilities advertises optional protocol features beyond the method list.
+var capabilities = []string{"events"}
+
+type initializeParams struct {
+	ProtocolVersion string `json:"protocol_version,omitempty"`
+	ClientName      string `json:"client_name,omitempty"`
+	ClientVersion   string `json:"client_version,omitempty"`
+	// Token is required from socket clients; see ServeListener.
+	Token string `json:"token,omitempty"`
+	// Framing lists the framings the client can speak besides newline JSON.
+	Framing []string `json:"framing,omitempty"`
+}
+
+type initializeResult struct {
+	ProtocolVersion string         `json:"protocol_version"`
+	Server          buildinfo.Info `json:"server"`
+	SchemaVersion   int            `json:"schema_version"`
+	Methods         []string       `json:"methods"`
+	Capabilities    []string       `json:"capabilities"`
+	// Framing is what the session speaks after this response; see FramingBinary.
+	Framing string `json:"framing"`
+}
+
+func (s *Server) initialize(req Request) Response {
+	var body initializeParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	if body.ProtocolVersion != "" {
+		if err := checkProtocolCompatible(body.ProtocolVersion); err != nil {
+			return rpcErr(req.ID, "INCOMPATIBLE_PROTOCOL", err.Error())
+		}
+	}
+
+	schemaVersion, err := db.GetSchemaVersion(s.cmds.Notes.DB)
+	if err != nil {
+		return rpcErr(req.ID, "INTERNAL", "Failed to read schema version")
+	}
+	if schemaVersion > db.SchemaVersion {
+		return rpcErr(req.ID, "INCOMPATIBLE_SCHEMA",
+			fmt.Sprintf("Database schema %d is newer than this server supports (%d)", schemaVersion, db.SchemaVersion))
+	}
+
+	// only stream sessions can switch framing; HTTP and replay stay on JSON
+	framing := FramingJSON
+	if req.framing != "" {
+		framing = negotiateFraming(body.Framing)
+	}
+
+	return Response{
+		ID: req.ID,
+		Result: initializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Server:          buildinfo.Get(),
+			SchemaVersion:   schemaVersion,
+			Methods:         s.methodNames(),
+			Capabilities:    capabilities,
+			Framing:         framing,
+		},
+	}
+}
+
+func (s *Server) methodNames() []string {
+	names := make([]string, 0, len(s.handlers))
+	for name := range s.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkProtocolCompatible accepts clients on the same major version whose minor
+// version is not ahead of ours, since a newer minor may rely on methods we lack.
+func checkProtocolCompatible(clientVersion string) error {
+	clientMajor, clientMinor, err := parseProtocolVersion(clientVersion)
+	if err != nil {
+		return err
+	}
+	serverMajor, serverMinor, _ := parseProtocolVersion(ProtocolVersion)
+
+	if clientMajor != serverMajor || clientMinor > serverMinor {
+		return fmt.Errorf("Client protocol %s is not supported by server protocol %s", clientVersion, ProtocolVersion)
+	}
+	return nil
+}
+
+func parseProtocolVersion(version string) (int, int, error) {
+	majorStr, minorStr, found := strings.Cut(version, ".")
+	if !found {
+		minorStr = "0"
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid protocol version: %s", version)
+	}
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return 0, 0, fmt.Errorf("Invalid protocol version: %s", version)
+	}
+	return major, minor, nil
+}
@@ -0,0 +1,214 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RecordingsDirName is where recordings are written inside the data dir.
+const RecordingsDirName = "recordings"
+
+const (
+	defaultRecordMaxBytes = 10 << 20
+	defaultRecordMaxFiles = 5
+	redactedValue         = "[redacted]"
+)
+
+// redactedKeys hold user content; everything else is needed to make sense of a report.
+var redactedKeys = map[string]bool{
+	"content":     true,
+	"data_base64": true,
+}
+
+type RecorderOptions struct {
+	// Redact replaces block content and uploaded image data with a placeholder.
+	Redact bool
+	// MaxBytes starts a new file once the current one grows past it. Zero means 10 MiB.
+	MaxBytes int64
+	// MaxFiles is how many files are kept before the oldest is deleted. Zero means 5.
+	MaxFiles int
+}
+
+// Record is one line of a recording: a request, what we answered and how long it took.
+type Record struct {
+	At         time.Time `json:"at"`
+	DurationMs float64   `json:"duration_ms"`
+	Redacted   bool      `json:"redacted,omitempty"`
+	Framing    string    `json:"framing,omitempty"`
+	Request    Request   `json:"request"`
+	// Body is the request's frame body; redaction drops it.
+	Body     []byte          `json:"body,omitempty"`
+	Response json.RawMessage `json:"response"`
+}
+
+// Recorder appends every dispatched request to rotating JSONL files so a
+// session can be replayed later with `noteblock replay`.
+type Recorder struct {
+	dir  string
+	opts RecorderOptions
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	seq  int
+}
+
+func NewRecorder(dir string, opts RecorderOptions) (*Recorder, error) {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultRecordMaxBytes
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultRecordMaxFiles
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	r := &Recorder{dir: dir, opts: opts}
+	if err := r.rotate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetRecorder turns recording on for every session; pass nil to turn it off.
+func (s *Server) SetRecorder(r *Recorder) {
+	s.recorder = r
+}
+
+// Path is the file currently being written.
+func (r *Recorder) Path() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Name()
+}
+
+func (r *Recorder) Record(req Request, res Response, at time.Time, took time.Duration) error {
+	response, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	body := req.Body
+	if r.opts.Redact {
+		req.Params = redactJSON(req.Params)
+		response = redactJSON(response)
+		body = nil
+	}
+	line, err := json.Marshal(Record{
+		At:         at,
+		DurationMs: float64(took.Microseconds()) / 1000,
+		Redacted:   r.opts.Redact,
+		Framing:    req.framing,
+		Request:    req,
+		Body:       body,
+		Response:   response,
+	})
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(line))+1 > r.opts.MaxBytes {
+		if err := r.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := r.file.Write(append(line, '\n'))
+	r.size += int64(n)
+	return err
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// rotate opens a fresh file and prunes the oldest ones. Names sort chronologically.
+func (r *Recorder) rotate() error {
+	if r.file != nil {
+		_ = r.file.Close()
+	}
+	r.seq++
+	name := fmt.Sprintf("ipc-%s-%04d.jsonl", time.Now().Format("20060102-150405"), r.seq)
+	f, err := os.OpenFile(filepath.Join(r.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.size = 0
+
+	files, err := filepath.Glob(filepath.Join(r.dir, "ipc-*.jsonl"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for len(files) > r.opts.MaxFiles {
+		_ = os.Remove(files[0])
+		files = files[1:]
+	}
+	return nil
+}
+
+// ReadRecording loads the records of one recording file in order.
+func ReadRecording(path string) ([]Record, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 40*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+func redactJSON(raw json.RawMessage) json.RawMessage {
+	if len(raw) == 0 {
+		return raw
+	}
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return raw
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+func redactValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if redactedKeys[key] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactValue(child)
+			}
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = redactValue(child)
+		}
+	}
+	return v
+}
@@ -0,0 +1,153 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// liveSession drives srv.Run over pipes so later requests can use IDs from
+// earlier responses, the way the renderer does.
+type liveSession struct {
+	t      *testing.T
+	w      *io.PipeWriter
+	r      *bufio.Scanner
+	nextID int
+}
+
+func startSession(t *testing.T, srv *Server) *liveSession {
+	t.Helper()
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		_ = srv.Run(inR, outW)
+		_ = outW.Close()
+		close(done)
+	}()
+	t.Cleanup(func() {
+		_ = inW.Close()
+		<-done
+	})
+	return &liveSession{t: t, w: inW, r: bufio.NewScanner(outR)}
+}
+
+func (ls *liveSession) call(method string, params any) map[string]any {
+	ls.t.Helper()
+	ls.nextID++
+	line, _ := json.Marshal(map[string]any{"id": strconv.Itoa(ls.nextID), "method": method, "params": params})
+	if _, err := ls.w.Write(append(line, '\n')); err != nil {
+		ls.t.Fatalf("write failed: %v", err)
+	}
+	for ls.r.Scan() {
+		var msg map[string]any
+		if err := json.Unmarshal(ls.r.Bytes(), &msg); err != nil {
+			ls.t.Fatalf("bad response line: %v", err)
+		}
+		if msg["id"] == strconv.Itoa(ls.nextID) {
+			result, _ := msg["result"].(map[string]any)
+			return result
+		}
+	}
+	ls.t.Fatalf("session closed before %s answered", method)
+	return nil
+}
+
+func recordScript(t *testing.T, opts RecorderOptions) (*Recorder, []Record) {
+	t.Helper()
+	srv := setupTestServer(t)
+	rec, err := NewRecorder(t.TempDir(), opts)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	srv.SetRecorder(rec)
+
+	ls := startSession(t, srv)
+	ls.call("initialize", map[string]any{"protocol_version": ProtocolVersion})
+	folder := ls.call("folder.create", map[string]any{"name": "Projects"})
+	note := ls.call("note.create", map[string]any{"title": "Plan", "folder_id": folder["id"]})
+	block := ls.call("block.create", map[string]any{"note_id": note["id"], "type": "text", "index": 0, "content": map[string]any{"text": "secret"}})
+	ls.call("block.update", map[string]any{"note_id": note["id"], "block_id": block["id"], "type": "text", "content": map[string]any{"text": "more secret"}})
+	ls.call("note.get", map[string]any{"id": note["id"]})
+	ls.call("folder.get", map[string]any{"id": "root"})
+	_ = ls.w.Close()
+	_ = rec.Close()
+
+	records, err := ReadRecording(rec.Path())
+	if err != nil {
+		t.Fatalf("failed to read recording: %v", err)
+	}
+	if len(records) != 7 {
+		t.Fatalf("expected 7 records, got %d", len(records))
+	}
+	return rec, records
+}
+
+func TestRecorder_ReplayRemapsIDs(t *testing.T) {
+	_, records := recordScript(t, RecorderOptions{})
+
+	mismatches, err := setupTestServer(t).Replay(records)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("expected a clean replay, got %+v", mismatches)
+	}
+
+	// a response that no longer matches is reported
+	records[5].Response = json.RawMessage(`{"id":"6","error":{"code":"NOT_FOUND","message":"Note not found"}}`)
+	mismatches, err = setupTestServer(t).Replay(records)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Method != "note.get" {
+		t.Fatalf("expected one note.get mismatch, got %+v", mismatches)
+	}
+}
+
+func TestRecorder_RedactsContent(t *testing.T) {
+	_, records := recordScript(t, RecorderOptions{Redact: true})
+
+	for _, rec := range records {
+		if strings.Contains(string(rec.Request.Params), "secret") || strings.Contains(string(rec.Response), "secret") {
+			t.Fatalf("recording leaked block content: %s", rec.Request.Method)
+		}
+	}
+
+	mismatches, err := setupTestServer(t).Replay(records)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if len(mismatches) != 0 {
+		t.Fatalf("expected redacted recording to replay cleanly, got %+v", mismatches)
+	}
+}
+
+func TestRecorder_Rotates(t *testing.T) {
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir, RecorderOptions{MaxBytes: 1, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	defer rec.Close()
+
+	var paths []string
+	for i := 0; i < 4; i++ {
+		if err := rec.Record(Request{ID: strconv.Itoa(i), Method: "note.get"}, Response{ID: strconv.Itoa(i)}, time.Now(), 0); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+		paths = append(paths, rec.Path())
+	}
+	if paths[0] == paths[1] {
+		t.Fatalf("expected a new file once MaxBytes was exceeded")
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "ipc-*.jsonl"))
+	if len(matches) != 2 {
+		t.Fatalf("expected rotation to keep 2 files, got %d", len(matches))
+	}
+}
@@ -0,0 +1,155 @@
+package ipc
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// volatileKeys differ between any two runs and are left out of the comparison.
+var volatileKeys = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+}
+
+// Mismatch is a replayed response that did not match the recorded one.
+type Mismatch struct {
+	Index  int             `json:"index"`
+	Method string          `json:"method"`
+	Want   json.RawMessage `json:"want"`
+	Got    json.RawMessage `json:"got"`
+}
+
+// Replay re-issues recorded requests in order and returns the responses that
+// differ. IDs and upload URLs minted during the replay are mapped onto the
+// recorded ones, so later requests address the same records. The initialize
+// handshake describes the binary rather than the vault and is not compared.
+func (s *Server) Replay(records []Record) ([]Mismatch, error) {
+	remap := map[string]string{}
+	var mismatches []Mismatch
+
+	for i, rec := range records {
+		req := rec.Request
+		req.Body = rec.Body
+		if rec.Framing == FramingBinary {
+			// answer as the binary session did, so asset bytes stay out of the result
+			req.framing = FramingBinary
+		}
+		if len(req.Params) > 0 {
+			var params any
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				return nil, err
+			}
+			raw, err := json.Marshal(substituteIDs(params, remap))
+			if err != nil {
+				return nil, err
+			}
+			req.Params = raw
+		}
+
+		got, err := json.Marshal(s.Call(req))
+		if err != nil {
+			return nil, err
+		}
+		if rec.Redacted {
+			got = redactJSON(got)
+		}
+		if req.Method == "initialize" {
+			continue
+		}
+
+		var want, have any
+		if err := json.Unmarshal(rec.Response, &want); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(got, &have); err != nil {
+			return nil, err
+		}
+		learnIDs("", want, have, remap)
+
+		if !reflect.DeepEqual(stripVolatile(substituteIDs(want, remap)), stripVolatile(have)) {
+			mismatches = append(mismatches, Mismatch{
+				Index:  i,
+				Method: req.Method,
+				Want:   rec.Response,
+				Got:    got,
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+func isIDKey(key string) bool {
+	return key == "id" || key == "url" || strings.HasSuffix(key, "_id")
+}
+
+// learnIDs walks the recorded and replayed responses side by side and maps
+// every recorded identifier to the one the replay produced in its place.
+func learnIDs(key string, want, got any, remap map[string]string) {
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			return
+		}
+		for k, child := range w {
+			learnIDs(k, child, g[k], remap)
+		}
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			return
+		}
+		for i := 0; i < len(w) && i < len(g); i++ {
+			learnIDs(key, w[i], g[i], remap)
+		}
+	case string:
+		g, ok := got.(string)
+		if !ok || !isIDKey(key) || w == g {
+			return
+		}
+		if _, known := remap[w]; !known {
+			remap[w] = g
+		}
+	}
+}
+
+func substituteIDs(v any, remap map[string]string) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, child := range v {
+			out[k] = substituteIDs(child, remap)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, child := range v {
+			out[i] = substituteIDs(child, remap)
+		}
+		return out
+	case string:
+		if mapped, ok := remap[v]; ok {
+			return mapped
+		}
+	}
+	return v
+}
+
+func stripVolatile(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if volatileKeys[k] {
+				delete(v, k)
+			} else {
+				stripVolatile(child)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			stripVolatile(child)
+		}
+	}
+	return v
+}
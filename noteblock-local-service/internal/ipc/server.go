@@ -1,44 +1,94 @@
 package ipc
 
 import (
-	"bufio"
 	"encoding/json"
 	"errors"
 	"io"
-	"server/internal/service"
+	"server/internal/command"
+	"sync"
+	"time"
 )
 
 type Server struct {
-	noteSvc   *service.NoteService
-	folderSvc *service.FolderService
-	blockSvc  *service.BlockService
-	handlers  map[string]handlerFn
+	cmds     *command.Commands
+	handlers map[string]method
+
+	// token gates sessions that do not arrive over the trusted stdio pipe
+	token string
+
+	// mu serializes dispatch so handlers from concurrent sessions never race on
+	// SQLite. Transports that call commands directly take it through Exclusive.
+	mu         sync.Mutex
+	sessionsMu sync.Mutex
+	sessions   map[*session]struct{}
+
+	// recorder, when set, captures every request a session sends; see SetRecorder
+	recorder *Recorder
 }
 
-func NewServer(noteSvc *service.NoteService, folderSvc *service.FolderService, blockSvc *service.BlockService) *Server {
+func NewServer(cmds *command.Commands) *Server {
 	s := &Server{
-		noteSvc:   noteSvc,
-		folderSvc: folderSvc,
-		blockSvc:  blockSvc,
+		cmds:     cmds,
+		sessions: map[*session]struct{}{},
 	}
 	s.handlers = s.buildHandlers()
 	return s
 }
 
+// Run serves the trusted session on the pipe of the process that spawned us.
 func (s *Server) Run(r io.Reader, w io.Writer) error {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, 64*1024), 20*1024*1024)
-	encoder := json.NewEncoder(w)
+	return s.serve(newSession(w, true), r)
+}
 
-	for scanner.Scan() {
-		line := scanner.Bytes()
-		if len(line) == 0 {
-			continue
+// Call dispatches one request on behalf of a transport that has already
+// authenticated its caller, such as the HTTP API. Mutations are still
+// broadcast to every connected session.
+func (s *Server) Call(req Request) Response {
+	return s.dispatch(&session{authenticated: true}, req)
+}
+
+// Exclusive runs fn under the dispatch lock, for transports such as the REST
+// handlers that call commands directly instead of going through Call. It
+// keeps their writes from landing in the middle of an IPC request, e.g. while
+// backup.restore swaps the vault.
+func (s *Server) Exclusive(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn()
+}
+
+// ExclusiveMutation runs fn under the dispatch lock like Exclusive and, when
+// fn reports success, broadcasts event.changed for method as if it had been
+// dispatched. Transports that write without going through Call use it so
+// connected sessions still hear about their changes.
+func (s *Server) ExclusiveMutation(method string, params json.RawMessage, fn func() (result any, ok bool)) {
+	var (
+		result any
+		ok     bool
+	)
+	s.Exclusive(func() { result, ok = fn() })
+	if ok {
+		s.announce(method, params, result)
+	}
+}
+
+func (s *Server) serve(sess *session, r io.Reader) error {
+	s.addSession(sess)
+	defer s.removeSession(sess)
+
+	fr := newFrameReader(r)
+	for {
+		raw, body, err := fr.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
 		}
 
 		var req Request
-		if err := json.Unmarshal(line, &req); err != nil {
-			if encErr := encoder.Encode(Response{
+		if err := json.Unmarshal(raw, &req); err != nil {
+			if encErr := sess.send(Response{
 				ID: "",
 				Error: &RPCError{
 					Code:    "BAD_REQUEST",
@@ -49,15 +99,60 @@ func (s *Server) Run(r io.Reader, w io.Writer) error {
 			}
 			continue
 		}
+		req.Body = body
+		req.framing = sess.currentFraming()
 
-		res := s.handle(req)
-		if err := encoder.Encode(res); err != nil {
+		start := time.Now()
+		res := s.dispatch(sess, req)
+		if s.recorder != nil {
+			// a full disk must not take the editor down with it
+			_ = s.recorder.Record(req, res, start, time.Since(start))
+		}
+		// the initialize response still goes out as JSON; everything after it is framed
+		if result, ok := res.Result.(initializeResult); ok && res.Error == nil && result.Framing != sess.currentFraming() {
+			if err := sess.sendThenSetFraming(res, result.Framing); err != nil {
+				return err
+			}
+			fr.binary = result.Framing == FramingBinary
+			continue
+		}
+		if err := sess.send(res); err != nil {
 			return err
 		}
 	}
+}
+
+func (s *Server) dispatch(sess *session, req Request) Response {
+	if !sess.isAuthenticated() {
+		if req.Method != "initialize" {
+			return rpcErr(req.ID, "UNAUTHORIZED", "Call initialize with a valid token first")
+		}
+		if !s.checkToken(req.Params) {
+			return rpcErr(req.ID, "UNAUTHORIZED", "Invalid token")
+		}
+	}
+
+	s.mu.Lock()
+	res := s.handle(req)
+	s.mu.Unlock()
 
-	if err := scanner.Err(); err != nil && !errors.Is(err, io.EOF) {
-		return err
+	if req.Method == "initialize" && res.Error == nil {
+		sess.authenticate()
 	}
-	return nil
+	if m, ok := s.handlers[req.Method]; ok && m.mutates && res.Error == nil {
+		s.announce(req.Method, req.Params, res.Result)
+	}
+	return res
+}
+
+// announce broadcasts event.changed for a method that changed the vault.
+func (s *Server) announce(method string, params json.RawMessage, result any) {
+	s.broadcast(Notification{
+		Method: "event.changed",
+		Params: changeEvent{
+			Method: method,
+			Params: params,
+			Result: result,
+		},
+	})
 }
@@ -1,16 +1,24 @@
 package ipc
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"image"
+	"image/png"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"server/internal/model"
+	"server/internal/command"
+	localdb "server/internal/db"
 	"server/internal/model/dto"
-	"server/internal/service"
 )
 
 func setupTestServer(t *testing.T) *Server {
@@ -30,22 +38,16 @@ func setupTestServer(t *testing.T) *Server {
 		_ = sqlDB.Close()
 	})
 
-	if err := db.AutoMigrate(&model.Block{}, &model.Note{}, &model.Folder{}); err != nil {
+	if err := localdb.Migrate(db); err != nil {
 		t.Fatalf("failed to migrate test schema: %v", err)
 	}
-	if err := db.Create(&model.Folder{ID: "root", Name: "Root"}).Error; err != nil {
-		t.Fatalf("failed to create root folder: %v", err)
-	}
 
 	_ = os.Setenv("NOTE_DB_PATH", tmpDir)
 	t.Cleanup(func() {
 		_ = os.Unsetenv("NOTE_DB_PATH")
 	})
 
-	noteSvc := &service.NoteService{DB: db}
-	folderSvc := &service.FolderService{DB: db, NoteService: noteSvc}
-	blockSvc := &service.BlockService{DB: db}
-	return NewServer(noteSvc, folderSvc, blockSvc)
+	return NewServer(command.New(db))
 }
 
 func mustRaw(t *testing.T, v any) json.RawMessage {
@@ -68,8 +70,7 @@ func TestIPCServer_SmokeCRUDFlow(t *testing.T) {
 	if createFolderRes.Error != nil {
 		t.Fatalf("folder.create failed: %+v", createFolderRes.Error)
 	}
-	folderMap := createFolderRes.Result.(map[string]any)
-	folderID := folderMap["id"].(string)
+	folderID := createFolderRes.Result.(folderResult).ID
 
 	createNoteRes := srv.handle(Request{
 		ID:     "2",
@@ -79,7 +80,7 @@ func TestIPCServer_SmokeCRUDFlow(t *testing.T) {
 	if createNoteRes.Error != nil {
 		t.Fatalf("note.create failed: %+v", createNoteRes.Error)
 	}
-	noteID := createNoteRes.Result.(map[string]any)["id"].(string)
+	noteID := createNoteRes.Result.(noteResult).ID
 
 	createBlockRes := srv.handle(Request{
 		ID:     "3",
@@ -94,7 +95,7 @@ func TestIPCServer_SmokeCRUDFlow(t *testing.T) {
 	if createBlockRes.Error != nil {
 		t.Fatalf("block.create failed: %+v", createBlockRes.Error)
 	}
-	blockID := createBlockRes.Result.(map[string]any)["id"].(string)
+	blockID := createBlockRes.Result.(blockResult).ID
 
 	getNoteRes := srv.handle(Request{
 		ID:     "4",
@@ -167,3 +168,342 @@ func TestIPCServer_UnknownMethod(t *testing.T) {
 		t.Fatalf("expected METHOD_NOT_FOUND, got: %+v", res.Error)
 	}
 }
+
+func TestIPCServer_Initialize(t *testing.T) {
+	srv := setupTestServer(t)
+	res := srv.handle(Request{
+		ID:     "init",
+		Method: "initialize",
+		Params: mustRaw(t, map[string]any{"protocol_version": ProtocolVersion, "client_name": "test", "client_version": "0.0.1"}),
+	})
+	if res.Error != nil {
+		t.Fatalf("initialize failed: %+v", res.Error)
+	}
+	result, ok := res.Result.(initializeResult)
+	if !ok {
+		t.Fatalf("expected initializeResult, got %T", res.Result)
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		t.Fatalf("expected protocol %s, got %s", ProtocolVersion, result.ProtocolVersion)
+	}
+	if result.SchemaVersion != localdb.SchemaVersion {
+		t.Fatalf("expected schema version %d, got %d", localdb.SchemaVersion, result.SchemaVersion)
+	}
+	found := false
+	for _, m := range result.Methods {
+		if m == "note.create" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected note.create in methods, got %v", result.Methods)
+	}
+}
+
+func TestIPCServer_InitializeRejectsIncompatibleClient(t *testing.T) {
+	srv := setupTestServer(t)
+	for _, version := range []string{"2.0", "1.99", "garbage"} {
+		res := srv.handle(Request{
+			ID:     "init",
+			Method: "initialize",
+			Params: mustRaw(t, map[string]any{"protocol_version": version}),
+		})
+		if res.Error == nil || res.Error.Code != "INCOMPATIBLE_PROTOCOL" {
+			t.Fatalf("expected INCOMPATIBLE_PROTOCOL for %s, got: %+v", version, res.Error)
+		}
+	}
+}
+
+func TestIPCServer_DescribeCoversEveryMethod(t *testing.T) {
+	srv := setupTestServer(t)
+	res := srv.handle(Request{ID: "d", Method: "rpc.describe"})
+	if res.Error != nil {
+		t.Fatalf("rpc.describe failed: %+v", res.Error)
+	}
+	desc := res.Result.(Description)
+	if len(desc.Methods) != len(srv.handlers) {
+		t.Fatalf("expected %d described methods, got %d", len(srv.handlers), len(desc.Methods))
+	}
+
+	var noteCreate *MethodDescription
+	for i := range desc.Methods {
+		if desc.Methods[i].Name == "note.create" {
+			noteCreate = &desc.Methods[i]
+		}
+	}
+	if noteCreate == nil {
+		t.Fatalf("note.create missing from description")
+	}
+	if noteCreate.Params["$ref"] != "#/$defs/NoteCreateParams" {
+		t.Fatalf("unexpected note.create params schema: %v", noteCreate.Params)
+	}
+	params := desc.Defs["NoteCreateParams"]["properties"].(map[string]any)
+	if _, ok := params["folder_id"]; !ok {
+		t.Fatalf("expected folder_id in note.create params, got %v", params)
+	}
+
+	// dto.FolderResponse is self-referential; it must resolve through $defs
+	if _, ok := desc.Defs["FolderResponse"]; !ok {
+		t.Fatalf("expected FolderResponse definition")
+	}
+	if _, err := json.Marshal(desc); err != nil {
+		t.Fatalf("description is not serializable: %v", err)
+	}
+}
+
+func TestIPCServer_AssetStatListAndChunkedGet(t *testing.T) {
+	srv := setupTestServer(t)
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatalf("failed to encode test png: %v", err)
+	}
+	upload := srv.handle(Request{
+		ID:     "1",
+		Method: "asset.uploadImage",
+		Params: mustRaw(t, map[string]any{"filename": "pixel.png", "data_base64": base64.StdEncoding.EncodeToString(img.Bytes())}),
+	})
+	if upload.Error != nil {
+		t.Fatalf("upload failed: %+v", upload.Error)
+	}
+	url := upload.Result.(assetUploadResult).URL
+
+	note := srv.handle(Request{ID: "2", Method: "note.create", Params: mustRaw(t, map[string]any{"title": "Gallery"})})
+	noteID := note.Result.(noteResult).ID
+	block := srv.handle(Request{
+		ID:     "3",
+		Method: "block.create",
+		Params: mustRaw(t, map[string]any{"note_id": noteID, "type": "image", "index": 0, "content": map[string]any{"url": url}}),
+	})
+	if block.Error != nil {
+		t.Fatalf("block.create failed: %+v", block.Error)
+	}
+
+	stat := srv.handle(Request{ID: "4", Method: "asset.stat", Params: mustRaw(t, map[string]any{"url": url})})
+	if stat.Error != nil {
+		t.Fatalf("asset.stat failed: %+v", stat.Error)
+	}
+	info := stat.Result.(*dto.AssetInfo)
+	sum := sha256.Sum256(img.Bytes())
+	if info.MimeType != "image/png" || info.Width != 3 || info.Height != 2 ||
+		info.Size != int64(img.Len()) || info.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("unexpected asset info: %+v", info)
+	}
+
+	list := srv.handle(Request{ID: "5", Method: "asset.list"})
+	assets := list.Result.(assetListResult).Assets
+	if len(assets) != 1 || len(assets[0].References) != 1 || assets[0].References[0].NoteTitle != "Gallery" {
+		t.Fatalf("unexpected asset list: %+v", assets)
+	}
+
+	var got []byte
+	for offset := 0; ; {
+		res := srv.handle(Request{ID: "6", Method: "asset.get", Params: mustRaw(t, map[string]any{"url": url, "offset": offset, "length": 16})})
+		if res.Error != nil {
+			t.Fatalf("asset.get failed: %+v", res.Error)
+		}
+		chunk := res.Result.(assetGetResult)
+		data, _ := base64.StdEncoding.DecodeString(chunk.DataBase64)
+		got = append(got, data...)
+		offset += len(data)
+		if chunk.EOF {
+			break
+		}
+	}
+	if !bytes.Equal(got, img.Bytes()) {
+		t.Fatalf("chunked asset.get did not reassemble the upload")
+	}
+
+	missing := srv.handle(Request{ID: "7", Method: "asset.stat", Params: mustRaw(t, map[string]any{"url": "noteblock-image:///../../noteblock.sqlite"})})
+	if missing.Error == nil || missing.Error.Code != "NOT_FOUND" {
+		t.Fatalf("expected NOT_FOUND for a path outside uploads, got %+v", missing)
+	}
+}
+
+func TestIPCServer_BlockOrderAndMove(t *testing.T) {
+	srv := setupTestServer(t)
+	note := srv.handle(Request{ID: "n", Method: "note.create", Params: mustRaw(t, map[string]any{"title": "Order"})})
+	noteID := note.Result.(noteResult).ID
+
+	ids := map[string]string{}
+	create := func(name string, index int) {
+		res := srv.handle(Request{
+			ID:     name,
+			Method: "block.create",
+			Params: mustRaw(t, map[string]any{"note_id": noteID, "type": "text", "index": index, "content": map[string]any{"text": name}}),
+		})
+		if res.Error != nil {
+			t.Fatalf("block.create %s failed: %+v", name, res.Error)
+		}
+		ids[name] = res.Result.(blockResult).ID
+	}
+	order := func() string {
+		res := srv.handle(Request{ID: "g", Method: "note.get", Params: mustRaw(t, map[string]any{"id": noteID})})
+		var names []string
+		for i, block := range res.Result.(*dto.NoteDTO).Blocks {
+			if block.Index != i {
+				t.Fatalf("expected dense index %d, got %d", i, block.Index)
+			}
+			var content struct{ Text string }
+			_ = json.Unmarshal(block.Content, &content)
+			names = append(names, content.Text)
+		}
+		return strings.Join(names, "")
+	}
+	move := func(block, after, before string) Response {
+		return srv.handle(Request{
+			ID:     "m",
+			Method: "block.move",
+			Params: mustRaw(t, map[string]any{"note_id": noteID, "block_id": ids[block], "after_id": ids[after], "before_id": ids[before]}),
+		})
+	}
+
+	create("A", 0)
+	create("B", 1)
+	create("C", 2)
+	create("D", 1)
+	if got := order(); got != "ADBC" {
+		t.Fatalf("expected ADBC after inserting in the middle, got %s", got)
+	}
+
+	if res := move("C", "A", "D"); res.Error != nil || res.Result.(blockResult).Index != 1 {
+		t.Fatalf("move C between A and D failed: %+v", res)
+	}
+	if got := order(); got != "ACDB" {
+		t.Fatalf("expected ACDB, got %s", got)
+	}
+	if res := move("A", "", ""); res.Error != nil {
+		t.Fatalf("move A to end failed: %+v", res.Error)
+	}
+	if res := move("B", "", "C"); res.Error != nil {
+		t.Fatalf("move B before C failed: %+v", res.Error)
+	}
+	if got := order(); got != "BCDA" {
+		t.Fatalf("expected BCDA, got %s", got)
+	}
+
+	if res := move("B", "C", "A"); res.Error == nil || res.Error.Code != "BAD_REQUEST" {
+		t.Fatalf("expected BAD_REQUEST for non-adjacent neighbours, got %+v", res.Error)
+	}
+	ids["X"] = "missing"
+	if res := move("B", "X", ""); res.Error == nil || res.Error.Code != "NOT_FOUND" {
+		t.Fatalf("expected NOT_FOUND for a missing neighbour, got %+v", res.Error)
+	}
+}
+
+func TestIPCServer_BlockMoveAndCopyAcrossNotes(t *testing.T) {
+	srv := setupTestServer(t)
+	newNote := func(title string) string {
+		res := srv.handle(Request{ID: title, Method: "note.create", Params: mustRaw(t, map[string]any{"title": title})})
+		return res.Result.(noteResult).ID
+	}
+	newBlock := func(noteID, text string) string {
+		res := srv.handle(Request{
+			ID:     text,
+			Method: "block.create",
+			Params: mustRaw(t, map[string]any{"note_id": noteID, "type": "image", "index": 99, "content": map[string]any{"text": text, "url": "noteblock-image:///x_" + text + ".png"}}),
+		})
+		return res.Result.(blockResult).ID
+	}
+	texts := func(noteID string) string {
+		res := srv.handle(Request{ID: "g", Method: "note.get", Params: mustRaw(t, map[string]any{"id": noteID})})
+		var out []string
+		for _, block := range res.Result.(*dto.NoteDTO).Blocks {
+			var content struct{ Text string }
+			_ = json.Unmarshal(block.Content, &content)
+			out = append(out, content.Text)
+		}
+		return strings.Join(out, "")
+	}
+
+	long := newNote("Long")
+	part := newNote("Part")
+	a := newBlock(long, "A")
+	b := newBlock(long, "B")
+	x := newBlock(part, "X")
+
+	moved := srv.handle(Request{ID: "m", Method: "block.move", Params: mustRaw(t, map[string]any{"note_id": long, "block_id": b, "target_note_id": part, "before_id": x})})
+	if moved.Error != nil || moved.Result.(blockResult).NoteID != part {
+		t.Fatalf("cross-note move failed: %+v", moved)
+	}
+	copied := srv.handle(Request{ID: "c", Method: "block.copy", Params: mustRaw(t, map[string]any{"note_id": long, "block_id": a, "target_note_id": part, "after_id": x})})
+	if copied.Error != nil || copied.Result.(blockResult).ID == a {
+		t.Fatalf("cross-note copy failed: %+v", copied)
+	}
+	if got := texts(long); got != "A" {
+		t.Fatalf("expected only A left in the source note, got %s", got)
+	}
+	if got := texts(part); got != "BXA" {
+		t.Fatalf("expected BXA in the target note, got %s", got)
+	}
+
+	// the copy shares the original's asset reference rather than a new file
+	refs, err := srv.cmds.Assets.References("x_A.png")
+	if err != nil || len(refs) != 2 {
+		t.Fatalf("expected the original and the copy to reference x_A.png, got %+v (%v)", refs, err)
+	}
+
+	missing := srv.handle(Request{ID: "x", Method: "block.move", Params: mustRaw(t, map[string]any{"note_id": long, "block_id": a, "target_note_id": "nope"})})
+	if missing.Error == nil || missing.Error.Code != "NOT_FOUND" {
+		t.Fatalf("expected NOT_FOUND for a missing target note, got %+v", missing.Error)
+	}
+
+	res := srv.handle(Request{ID: "j", Method: "changes.list"})
+	changes := res.Result.(changesListResult)
+	if len(changes.Changes) != 2 || changes.Changes[0].Action != "block.move" || changes.Changes[1].Action != "block.copy" {
+		t.Fatalf("unexpected changes: %+v", changes.Changes)
+	}
+	if changes.Changes[1].NoteID != part || changes.LastSeq != changes.Changes[1].Seq {
+		t.Fatalf("unexpected change: %+v", changes)
+	}
+	next := srv.handle(Request{ID: "j2", Method: "changes.list", Params: mustRaw(t, map[string]any{"since": changes.LastSeq})})
+	if len(next.Result.(changesListResult).Changes) != 0 {
+		t.Fatalf("expected no changes after last_seq")
+	}
+}
+
+func TestIPCServer_VaultCheckBroadcastsOnlyRepairs(t *testing.T) {
+	srv := setupTestServer(t)
+	run := func(repair bool) []string {
+		t.Helper()
+		var out bytes.Buffer
+		input := `{"id":"1","method":"vault.check","params":{"repair":` + strconv.FormatBool(repair) + `}}` + "\n"
+		if err := srv.Run(strings.NewReader(input), &out); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		return strings.Split(strings.TrimSpace(out.String()), "\n")
+	}
+
+	if lines := run(false); len(lines) != 1 {
+		t.Fatalf("expected just the response to a check, got %v", lines)
+	}
+	if err := srv.cmds.Notes.DB.Exec(`INSERT INTO notes (id, title, folder_id) VALUES ('stray', 'Stray', 'gone')`).Error; err != nil {
+		t.Fatalf("insert orphan: %v", err)
+	}
+	if lines := run(false); len(lines) != 1 {
+		t.Fatalf("a check that only finds problems must not broadcast, got %v", lines)
+	}
+	if lines := run(true); len(lines) != 2 || !strings.Contains(lines[0], "event.changed") {
+		t.Fatalf("expected a change event ahead of the repair response, got %v", lines)
+	}
+}
+
+func TestIPCServer_JournalBroadcastsOnlyCreation(t *testing.T) {
+	srv := setupTestServer(t)
+	run := func() []string {
+		t.Helper()
+		var out bytes.Buffer
+		input := `{"id":"1","method":"journal.get","params":{"date":"2026-03-14"}}` + "\n"
+		if err := srv.Run(strings.NewReader(input), &out); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		return strings.Split(strings.TrimSpace(out.String()), "\n")
+	}
+
+	if lines := run(); len(lines) != 2 || !strings.Contains(lines[0], "event.changed") || !strings.Contains(lines[1], `"created":true`) {
+		t.Fatalf("expected a change event ahead of the response creating the day, got %v", lines)
+	}
+	if lines := run(); len(lines) != 1 || !strings.Contains(lines[0], `"created":false`) {
+		t.Fatalf("looking up an existing day must not broadcast, got %v", lines)
+	}
+}
@@ -0,0 +1,105 @@
+package ipc
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// session is one connected client. Responses and broadcast notifications share
+// the same writer, so every write goes through send.
+type session struct {
+	mu            sync.Mutex
+	w             io.Writer
+	enc           *json.Encoder
+	framing       string
+	authenticated bool
+}
+
+func newSession(w io.Writer, trusted bool) *session {
+	return &session{
+		w:             w,
+		enc:           json.NewEncoder(w),
+		framing:       FramingJSON,
+		authenticated: trusted,
+	}
+}
+
+func (sess *session) send(v any) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.write(v)
+}
+
+// sendThenSetFraming writes res in the current framing and switches to
+// framing without letting go of the session in between, so a notification
+// broadcast from another goroutine cannot slip out in the old framing after
+// the client has already switched.
+func (sess *session) sendThenSetFraming(res Response, framing string) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if err := sess.write(res); err != nil {
+		return err
+	}
+	sess.framing = framing
+	return nil
+}
+
+// write encodes v in the session's framing; the caller holds mu.
+func (sess *session) write(v any) error {
+	if sess.framing == FramingBinary {
+		var body []byte
+		if res, ok := v.(Response); ok {
+			body = res.Body
+		}
+		return writeFrame(sess.w, v, body)
+	}
+	return sess.enc.Encode(v)
+}
+
+func (sess *session) currentFraming() string {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.framing
+}
+
+func (sess *session) isAuthenticated() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	return sess.authenticated
+}
+
+func (sess *session) authenticate() {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	sess.authenticated = true
+}
+
+func (s *Server) addSession(sess *session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	s.sessions[sess] = struct{}{}
+}
+
+func (s *Server) removeSession(sess *session) {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	delete(s.sessions, sess)
+}
+
+// broadcast fans a notification out to every authenticated session. A client
+// that fails to accept the write is dropped by its own read loop, not here.
+func (s *Server) broadcast(n Notification) {
+	s.sessionsMu.Lock()
+	targets := make([]*session, 0, len(s.sessions))
+	for sess := range s.sessions {
+		if sess.isAuthenticated() {
+			targets = append(targets, sess)
+		}
+	}
+	s.sessionsMu.Unlock()
+
+	for _, sess := range targets {
+		_ = sess.send(n)
+	}
+}
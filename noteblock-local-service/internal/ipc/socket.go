@@ -0,0 +1,71 @@
+package ipc
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+
+	"server/internal/auth"
+)
+
+const (
+	SocketFileName = "noteblock.sock"
+	TokenFileName  = "ipc.token"
+)
+
+// ListenUnix binds the socket at path, replacing a stale socket left behind by
+// a crashed process, and restricts it to the current user from the moment it
+// exists. Call it before starting anything else that creates files; see
+// listenPrivate.
+func ListenUnix(path string) (net.Listener, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ln, err := listenPrivate(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// WriteToken generates a fresh token for this server and stores it at path
+// readable only by the current user. Socket clients read it from there.
+func (s *Server) WriteToken(path string) error {
+	token, err := auth.GenerateTokenFile(path)
+	if err != nil {
+		return err
+	}
+	s.token = token
+	return nil
+}
+
+// ServeListener accepts socket clients until ln is closed. Each connection gets
+// its own session that must authenticate through initialize.
+func (s *Server) ServeListener(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			_ = s.serve(newSession(conn, false), conn)
+		}()
+	}
+}
+
+func (s *Server) checkToken(raw json.RawMessage) bool {
+	var body initializeParams
+	if err := parseParams(raw, &body); err != nil {
+		return false
+	}
+	return auth.TokensEqual(s.token, body.Token)
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package ipc
+
+import "net"
+
+// listenPrivate relies on the permissions of the data directory where there
+// is no umask.
+func listenPrivate(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
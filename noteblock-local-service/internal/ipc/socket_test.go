@@ -0,0 +1,112 @@
+package ipc
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type socketTestClient struct {
+	t    *testing.T
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+func dialTestSocket(t *testing.T, path string) *socketTestClient {
+	t.Helper()
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatalf("failed to dial socket: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &socketTestClient{t: t, conn: conn, dec: json.NewDecoder(bufio.NewReader(conn))}
+}
+
+func (c *socketTestClient) call(id, method string, params any) map[string]any {
+	c.t.Helper()
+	if err := json.NewEncoder(c.conn).Encode(Request{ID: id, Method: method, Params: mustRaw(c.t, params)}); err != nil {
+		c.t.Fatalf("failed to write request: %v", err)
+	}
+	for {
+		msg := c.next()
+		if msg["id"] == id {
+			return msg
+		}
+	}
+}
+
+func (c *socketTestClient) next() map[string]any {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg map[string]any
+	if err := c.dec.Decode(&msg); err != nil {
+		c.t.Fatalf("failed to read message: %v", err)
+	}
+	return msg
+}
+
+func TestIPCServer_SocketSessionsAuthAndFanOut(t *testing.T) {
+	srv := setupTestServer(t)
+	dir := t.TempDir()
+
+	tokenPath := filepath.Join(dir, TokenFileName)
+	if err := srv.WriteToken(tokenPath); err != nil {
+		t.Fatalf("failed to write token: %v", err)
+	}
+	token, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatalf("failed to read token: %v", err)
+	}
+
+	socketPath := filepath.Join(dir, SocketFileName)
+	ln, err := ListenUnix(socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("failed to stat socket: %v", err)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		t.Fatalf("socket should only be accessible by its owner, got %v", info.Mode().Perm())
+	}
+	go func() { _ = srv.ServeListener(ln) }()
+
+	writer := dialTestSocket(t, socketPath)
+	watcher := dialTestSocket(t, socketPath)
+
+	res := writer.call("1", "folder.get", map[string]any{"id": "root"})
+	if res["error"].(map[string]any)["code"] != "UNAUTHORIZED" {
+		t.Fatalf("expected UNAUTHORIZED before initialize, got %v", res)
+	}
+	res = writer.call("2", "initialize", map[string]any{"token": "wrong"})
+	if res["error"].(map[string]any)["code"] != "UNAUTHORIZED" {
+		t.Fatalf("expected UNAUTHORIZED for bad token, got %v", res)
+	}
+
+	for _, c := range []*socketTestClient{writer, watcher} {
+		res = c.call("3", "initialize", map[string]any{"token": string(token)})
+		if res["error"] != nil {
+			t.Fatalf("initialize failed: %v", res["error"])
+		}
+	}
+
+	res = writer.call("4", "folder.create", map[string]any{"name": "Shared"})
+	if res["error"] != nil {
+		t.Fatalf("folder.create failed: %v", res["error"])
+	}
+
+	event := watcher.next()
+	if event["method"] != "event.changed" {
+		t.Fatalf("expected event.changed notification, got %v", event)
+	}
+	params := event["params"].(map[string]any)
+	if params["method"] != "folder.create" || params["result"].(map[string]any)["name"] != "Shared" {
+		t.Fatalf("unexpected event payload: %v", params)
+	}
+}
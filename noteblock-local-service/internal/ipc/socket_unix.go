@@ -0,0 +1,18 @@
+//go:build unix
+
+package ipc
+
+import (
+	"net"
+	"syscall"
+)
+
+// listenPrivate creates the socket under a umask that leaves it to the current
+// user, so other users can never connect, not even before ListenUnix gets to
+// chmod it. The umask is process wide, which is why ListenUnix has to run
+// before anything else creates files.
+func listenPrivate(path string) (net.Listener, error) {
+	old := syscall.Umask(0o177)
+	defer syscall.Umask(old)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,161 @@
+package ipc
+
+import "server/internal/model/dto"
+
+// noteTagsParams names tags with or without the leading #; they are stored
+// lower-cased.
+type noteTagsParams struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+type noteTagsResult struct {
+	Tags []dto.NoteTagDTO `json:"tags"`
+}
+
+type tagListResult struct {
+	Tags []dto.TagDTO `json:"tags"`
+}
+
+type tagRenameParams struct {
+	Name    string `json:"name"`
+	NewName string `json:"new_name"`
+}
+
+type tagMergeParams struct {
+	Sources []string `json:"sources"`
+	Target  string   `json:"target"`
+}
+
+// notesByTagsParams matches notes with every tag when Mode is "all" (the
+// default) or with any of them when it is "any".
+type notesByTagsParams struct {
+	Tags  []string `json:"tags"`
+	Mode  string   `json:"mode,omitempty"`
+	Limit int      `json:"limit,omitempty"`
+}
+
+type notesByTagsResult struct {
+	Notes []dto.TaggedNoteDTO `json:"notes"`
+}
+
+func (s *Server) noteTagsSet(req Request) Response {
+	return s.changeNoteTags(req, s.cmds.SetNoteTags)
+}
+
+func (s *Server) noteTagsAdd(req Request) Response {
+	return s.changeNoteTags(req, s.cmds.AddNoteTags)
+}
+
+func (s *Server) noteTagsRemove(req Request) Response {
+	return s.changeNoteTags(req, s.cmds.RemoveNoteTags)
+}
+
+func (s *Server) changeNoteTags(req Request, change func(noteID string, names []string) ([]dto.NoteTagDTO, error)) Response {
+	var body noteTagsParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	tags, err := change(body.ID, body.Tags)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: noteTagsResult{Tags: tags},
+	}
+}
+
+func (s *Server) tagList(req Request) Response {
+	tags, err := s.cmds.ListTags()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: tagListResult{Tags: tags},
+	}
+}
+
+func (s *Server) tagRename(req Request) Response {
+	var body tagRenameParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	tag, err := s.cmds.RenameTag(body.Name, body.NewName)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: tag,
+	}
+}
+
+func (s *Server) tagMerge(req Request) Response {
+	var body tagMergeParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	tag, err := s.cmds.MergeTags(body.Sources, body.Target)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: tag,
+	}
+}
+
+func (s *Server) tagConfig(req Request) Response {
+	cfg, err := s.cmds.TagConfig()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: cfg,
+	}
+}
+
+func (s *Server) tagConfigure(req Request) Response {
+	var body dto.TagConfig
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	cfg, err := s.cmds.ConfigureTags(body.ExtractInline)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: cfg,
+	}
+}
+
+func (s *Server) noteListByTags(req Request) Response {
+	var body notesByTagsParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	notes, err := s.cmds.ListNotesByTags(body.Tags, body.Mode, body.Limit)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: notesByTagsResult{Notes: notes},
+	}
+}
@@ -0,0 +1,80 @@
+package ipc
+
+import "server/internal/model/dto"
+
+type templateSetParams struct {
+	ID         string `json:"id"`
+	IsTemplate bool   `json:"is_template"`
+}
+
+type templateSetResult struct {
+	noteResult
+	IsTemplate bool `json:"is_template"`
+}
+
+type templateListResult struct {
+	Templates []dto.TemplateDTO `json:"templates"`
+}
+
+// noteFromTemplateParams instantiates TemplateID into FolderID (root when
+// omitted). Variables add to or override the built-in date, time, title and
+// folder values.
+type noteFromTemplateParams struct {
+	TemplateID string            `json:"template_id"`
+	FolderID   *string           `json:"folder_id"`
+	Title      *string           `json:"title"`
+	Variables  map[string]string `json:"variables,omitempty"`
+}
+
+func (s *Server) templateSet(req Request) Response {
+	var body templateSetParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	note, err := s.cmds.SetTemplate(body.ID, body.IsTemplate)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID: req.ID,
+		Result: templateSetResult{
+			noteResult: noteResult{
+				ID:       note.ID,
+				Title:    note.Title,
+				FolderID: note.FolderID,
+			},
+			IsTemplate: note.IsTemplate,
+		},
+	}
+}
+
+func (s *Server) templateList(req Request) Response {
+	templates, err := s.cmds.ListTemplates()
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: templateListResult{Templates: templates},
+	}
+}
+
+func (s *Server) noteCreateFromTemplate(req Request) Response {
+	var body noteFromTemplateParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	note, err := s.cmds.CreateNoteFromTemplate(body.TemplateID, body.FolderID, body.Title, body.Variables)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	return Response{
+		ID:     req.ID,
+		Result: note,
+	}
+}
@@ -2,15 +2,20 @@ package ipc
 
 import (
 	"encoding/json"
-	"errors"
 
-	"gorm.io/gorm"
+	"server/internal/command"
 )
 
 type Request struct {
 	ID     string          `json:"id"`
 	Method string          `json:"method"`
 	Params json.RawMessage `json:"params"`
+	// Body is the raw frame body on binary sessions.
+	Body []byte `json:"-"`
+
+	// framing is the framing of the session the request arrived on, or empty
+	// when it did not come over a stream (HTTP, replay).
+	framing string
 }
 
 type RPCError struct {
@@ -22,8 +27,39 @@ type Response struct {
 	ID     string    `json:"id"`
 	Result any       `json:"result,omitempty"`
 	Error  *RPCError `json:"error,omitempty"`
+	// Body is sent as the frame body; handlers only set it for binary sessions.
+	Body []byte `json:"-"`
+}
+
+// Notification is a server-initiated message. It carries no ID, so clients
+// can tell it apart from a response.
+type Notification struct {
+	Method string `json:"method"`
+	Params any    `json:"params"`
+}
+
+// changeEvent is broadcast as event.changed after a mutating method succeeds.
+// A REST route that writes reports the method it mirrors, with its path
+// parameters and JSON body as Params and its response body as Result.
+type changeEvent struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result any             `json:"result"`
+}
+
+// idParams is shared by the methods that only address a record by ID.
+type idParams struct {
+	ID string `json:"id"`
+}
+
+type messageResult struct {
+	Message string `json:"message"`
 }
 
+type emptyParams struct{}
+
+type emptyResult struct{}
+
 func parseParams(raw json.RawMessage, out any) error {
 	if len(raw) == 0 {
 		return nil
@@ -41,9 +77,22 @@ func rpcErr(reqID, code, message string) Response {
 	}
 }
 
-func dbErrToRPC(reqID string, err error, fallback string) Response {
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return rpcErr(reqID, "NOT_FOUND", "Record not found")
+// cmdErrToRPC maps a command.Error onto the wire codes clients switch on.
+func cmdErrToRPC(reqID string, err error) Response {
+	return rpcErr(reqID, ErrorCode(command.KindOf(err)), command.MessageOf(err))
+}
+
+// ErrorCode is the RPC error code for a command error kind.
+func ErrorCode(kind command.Kind) string {
+	switch kind {
+	case command.KindValidation:
+		return "BAD_REQUEST"
+	case command.KindNotFound:
+		return "NOT_FOUND"
+	case command.KindConflict:
+		return "CONFLICT"
+	case command.KindInvalidMove:
+		return "INVALID_MOVE"
 	}
-	return rpcErr(reqID, "INTERNAL", fallback)
+	return "INTERNAL"
 }
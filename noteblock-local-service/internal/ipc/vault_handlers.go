@@ -0,0 +1,31 @@
+package ipc
+
+import (
+	"slices"
+
+	"server/internal/model/dto"
+)
+
+type vaultCheckParams struct {
+	Repair bool `json:"repair"`
+}
+
+func (s *Server) vaultCheck(req Request) Response {
+	var body vaultCheckParams
+	if err := parseParams(req.Params, &body); err != nil {
+		return rpcErr(req.ID, "BAD_REQUEST", "Invalid params")
+	}
+
+	res, err := s.cmds.CheckVault(body.Repair)
+	if err != nil {
+		return cmdErrToRPC(req.ID, err)
+	}
+
+	// a query, so a plain check stays quiet; a repair that changed something
+	// is announced like any mutation
+	if slices.ContainsFunc(res.Findings, func(f dto.VaultFinding) bool { return f.Repair != "" }) {
+		s.announce(req.Method, req.Params, res)
+	}
+
+	return Response{ID: req.ID, Result: res}
+}
@@ -0,0 +1,152 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is a JSON Schema document kept as a plain map so it marshals as-is.
+type Schema = map[string]any
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// Reflector turns Go types into JSON Schemas. Named struct types are emitted
+// once into Defs and referenced by $ref, which also keeps recursive types
+// such as dto.FolderResponse finite.
+type Reflector struct {
+	Defs map[string]Schema
+}
+
+func NewReflector() *Reflector {
+	return &Reflector{Defs: map[string]Schema{}}
+}
+
+// Reflect returns the schema for the dynamic type of v.
+func (r *Reflector) Reflect(v any) Schema {
+	if v == nil {
+		return Schema{}
+	}
+	return r.reflectType(reflect.TypeOf(v))
+}
+
+func (r *Reflector) reflectType(t reflect.Type) Schema {
+	switch {
+	case t == timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return Nullable(r.reflectType(t.Elem()))
+	case reflect.Interface:
+		return Schema{}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": r.reflectType(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": r.reflectType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.reflectStruct(t)
+		}
+		name := DefName(t)
+		if _, ok := r.Defs[name]; !ok {
+			// reserve the name before descending so self references terminate
+			r.Defs[name] = Schema{}
+			r.Defs[name] = r.reflectStruct(t)
+		}
+		return Schema{"$ref": "#/$defs/" + name}
+	}
+	return Schema{}
+}
+
+func (r *Reflector) reflectStruct(t reflect.Type) Schema {
+	properties := Schema{}
+	required := []string{}
+	r.collectFields(t, properties, &required)
+
+	schema := Schema{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// collectFields follows encoding/json's rules: unexported and "-" fields are
+// skipped and anonymous struct fields are flattened into the parent. A field
+// is required unless it is a pointer or tagged omitempty.
+func (r *Reflector) collectFields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				r.collectFields(ft, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.reflectType(field.Type)
+		if field.Type.Kind() != reflect.Pointer && !strings.Contains(opts, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// Nullable widens a schema to also accept null.
+func Nullable(schema Schema) Schema {
+	if typ, ok := schema["type"].(string); ok {
+		widened := Schema{}
+		for k, v := range schema {
+			widened[k] = v
+		}
+		widened["type"] = []string{typ, "null"}
+		return widened
+	}
+	return Schema{"anyOf": []Schema{schema, {"type": "null"}}}
+}
+
+// DefName is the exported-style name a struct type is registered under.
+func DefName(t reflect.Type) string {
+	runes := []rune(t.Name())
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
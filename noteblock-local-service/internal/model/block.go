@@ -7,10 +7,13 @@ import (
 )
 
 type Block struct {
-	ID        string `gorm:"type:uuid;primaryKey"`
-	NoteID    string `gorm:"type:uuid;not null;index"`
-	Type      string
-	Index     int
+	ID     string `gorm:"type:uuid;primaryKey"`
+	NoteID string `gorm:"type:uuid;not null;index"`
+	Type   string
+	// OrderKey sorts the blocks of a note; see package order.
+	OrderKey string `gorm:"index"`
+	// Index is the block's position in its note, filled in when blocks are loaded.
+	Index     int `gorm:"-"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 	Content   string `gorm:"type:text"`
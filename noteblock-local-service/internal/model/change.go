@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Change is one entry in the change log, an append-only log of structural
+// edits that clients can follow by sequence number.
+type Change struct {
+	Seq       uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time
+	// Action is the method that made the change, e.g. block.move.
+	Action   string
+	Entity   string
+	EntityID string `gorm:"index"`
+	// NoteID is the note the entity ended up in.
+	NoteID string `gorm:"index"`
+	// Data is a JSON object with action specific details.
+	Data string `gorm:"type:text"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DailyNote ties a calendar day to its journal note, so renaming the note or
+// changing the title format never loses track of an entry.
+type DailyNote struct {
+	// Date is the local calendar day as 2006-01-02.
+	Date      string `gorm:"primaryKey"`
+	NoteID    string `gorm:"type:uuid;not null;uniqueIndex"`
+	CreatedAt time.Time
+
+	Note Note `gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "time"
+
+type AssetInfo struct {
+	URL        string    `json:"url"`
+	Filename   string    `json:"filename"`
+	Size       int64     `json:"size"`
+	MimeType   string    `json:"mime_type"`
+	Width      int       `json:"width,omitempty"`
+	Height     int       `json:"height,omitempty"`
+	SHA256     string    `json:"sha256"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// AssetRef is a block whose content points at an asset.
+type AssetRef struct {
+	BlockID   string `json:"block_id"`
+	NoteID    string `json:"note_id"`
+	NoteTitle string `json:"note_title"`
+}
+
+type AssetEntry struct {
+	AssetInfo
+	References []AssetRef `json:"references"`
+}
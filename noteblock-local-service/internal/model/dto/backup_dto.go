@@ -0,0 +1,48 @@
+package dto
+
+import "time"
+
+// Kinds of backup.
+const (
+	BackupManual     = "manual"      // taken on request; never rotated
+	BackupScheduled  = "scheduled"   // taken by the schedule and thinned out by retention
+	BackupPreRestore = "pre_restore" // the vault as it was just before a restore
+)
+
+// Backup schedules.
+const (
+	BackupOff    = "off"
+	BackupHourly = "hourly"
+	BackupDaily  = "daily"
+	BackupWeekly = "weekly"
+)
+
+// BackupDTO describes a verified backup: a copy of the database taken with
+// VACUUM INTO and the uploaded images beside it.
+type BackupDTO struct {
+	ID            string    `json:"id"`
+	Kind          string    `json:"kind"`
+	CreatedAt     time.Time `json:"created_at"`
+	SchemaVersion int       `json:"schema_version"`
+	// Size is the database copy in bytes; images are counted separately.
+	Size       int64 `json:"size"`
+	Images     int   `json:"images"`
+	ImagesSize int64 `json:"images_size"`
+}
+
+// BackupConfig sets how often scheduled backups run and how many of them are
+// kept: the newest backup of each of the last KeepHourly hours, KeepDaily days
+// and KeepWeekly weeks survives rotation.
+type BackupConfig struct {
+	Schedule   string `json:"schedule"`
+	KeepHourly int    `json:"keep_hourly"`
+	KeepDaily  int    `json:"keep_daily"`
+	KeepWeekly int    `json:"keep_weekly"`
+}
+
+type BackupRestoreDTO struct {
+	Restored BackupDTO `json:"restored"`
+	// Safety is the backup taken of the vault just before it was replaced,
+	// which undoes the restore.
+	Safety BackupDTO `json:"safety"`
+}
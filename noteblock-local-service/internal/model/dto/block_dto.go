@@ -9,14 +9,63 @@ type BlockDTO struct {
 	ID        string          `json:"id"`
 	Type      string          `json:"type"`
 	Index     int             `json:"index"`
+	OrderKey  string          `json:"order_key"`
 	Content   json.RawMessage `json:"content"`
 	CreatedAt time.Time       `json:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at"`
+	// Embed is set on embed blocks: the block they transclude, resolved
+	// when the note is read.
+	Embed *EmbedDTO `json:"embed,omitempty"`
+}
+
+// EmbedBlockType is the block type that transcludes another block by ID.
+const EmbedBlockType = "embed"
+
+// QuarantinedBlockType replaces the type of a block whose content was not
+// valid JSON; see QuarantinedContent.
+const QuarantinedBlockType = "quarantined"
+
+// QuarantinedContent keeps what a corrupt block held, so nothing is lost and
+// its note can load again.
+type QuarantinedContent struct {
+	Type string `json:"type"`
+	Raw  string `json:"raw"`
+}
+
+// EmbedContent is the stored content of an embed block.
+type EmbedContent struct {
+	BlockID string `json:"block_id"`
+}
+
+// Embed statuses. Anything but EmbedOK comes without a block, and clients
+// render a placeholder in its place.
+const (
+	EmbedOK         = "ok"
+	EmbedMissing    = "missing"     // the target block was deleted or never existed
+	EmbedCycle      = "cycle"       // the target embeds, directly or not, the block itself
+	EmbedDepthLimit = "depth_limit" // nested deeper than mapper.MaxEmbedDepth
+)
+
+type EmbedDTO struct {
+	BlockID   string    `json:"block_id"`
+	Status    string    `json:"status"`
+	NoteID    string    `json:"note_id,omitempty"`
+	NoteTitle string    `json:"note_title,omitempty"`
+	Block     *BlockDTO `json:"block,omitempty"`
 }
 
 type NoteDTO struct {
-	ID       string     `json:"id"`
-	Title    string     `json:"title"`
-	FolderID string     `json:"folder_id"`
-	Blocks   []BlockDTO `json:"blocks"`
+	ID         string     `json:"id"`
+	Title      string     `json:"title"`
+	FolderID   string     `json:"folder_id"`
+	IsTemplate bool       `json:"is_template"`
+	Tags       []string   `json:"tags"`
+	Blocks     []BlockDTO `json:"blocks"`
+}
+
+// BlockReferenceDTO is an embed block that transcludes another block.
+type BlockReferenceDTO struct {
+	BlockID   string `json:"block_id"`
+	NoteID    string `json:"note_id"`
+	NoteTitle string `json:"note_title"`
 }
@@ -0,0 +1,16 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type ChangeDTO struct {
+	Seq      uint64          `json:"seq"`
+	At       time.Time       `json:"at"`
+	Action   string          `json:"action"`
+	Entity   string          `json:"entity"`
+	EntityID string          `json:"entity_id"`
+	NoteID   string          `json:"note_id"`
+	Data     json.RawMessage `json:"data"`
+}
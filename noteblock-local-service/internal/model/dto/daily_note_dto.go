@@ -0,0 +1,16 @@
+package dto
+
+// JournalConfig is how daily notes are made. An empty FolderID means a
+// "Journal" folder under root; an empty TemplateID means a blank note.
+type JournalConfig struct {
+	FolderID string `json:"folder_id"`
+	// TitleFormat is a Go time layout, e.g. "2006-01-02" or "Monday, January 2 2006".
+	TitleFormat string `json:"title_format"`
+	TemplateID  string `json:"template_id"`
+}
+
+type JournalEntryDTO struct {
+	Date   string `json:"date"`
+	NoteID string `json:"note_id"`
+	Title  string `json:"title"`
+}
@@ -1,13 +1,37 @@
 package dto
 
+// FolderResponse lists children and notes in the folder's sort order.
 type FolderResponse struct {
 	ID       string           `json:"id"`
 	Name     string           `json:"name"`
 	ParentID *string          `json:"parent_id"`
+	SortBy   string           `json:"sort_by"`
+	SortDesc bool             `json:"sort_desc"`
 	Notes    []NoteResponse   `json:"notes"`
 	Children []FolderResponse `json:"children"`
 }
 
+// FolderChildren is one level of the folder tree, for lazy loading.
+type FolderChildren struct {
+	ID       string         `json:"id"`
+	Name     string         `json:"name"`
+	ParentID *string        `json:"parent_id"`
+	SortBy   string         `json:"sort_by"`
+	SortDesc bool           `json:"sort_desc"`
+	Children []FolderChild  `json:"children"`
+	Notes    []NoteResponse `json:"notes"`
+}
+
+// FolderChild is a subfolder with the size of its own first level, so a
+// client can tell whether it is worth expanding.
+type FolderChild struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	ParentID   *string `json:"parent_id"`
+	ChildCount int     `json:"child_count"`
+	NoteCount  int     `json:"note_count"`
+}
+
 type FolderPreview struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
@@ -16,4 +40,14 @@ type FolderPreview struct {
 type NoteResponse struct {
 	ID    string `json:"id"`
 	Title string `json:"title"`
+	// Primary is false where a note is listed through a placement rather
+	// than its own folder.
+	Primary bool `json:"primary"`
+}
+
+// PlacementDTO is one folder a note is listed in.
+type PlacementDTO struct {
+	FolderID   string `json:"folder_id"`
+	FolderName string `json:"folder_name"`
+	Primary    bool   `json:"primary"`
 }
@@ -0,0 +1,40 @@
+package dto
+
+// Graph node and edge kinds.
+const (
+	GraphNote   = "note"
+	GraphTag    = "tag"
+	GraphFolder = "folder"
+	GraphLink   = "link"
+)
+
+// GraphNode is a note, tag or folder. Note nodes use the note ID; tag and
+// folder nodes are prefixed ("tag:work", "folder:<id>") so IDs never clash.
+// FolderID is a note's folder or a folder's parent. Degrees count the edges
+// in the returned graph that touch the node.
+type GraphNode struct {
+	ID        string  `json:"id"`
+	Kind      string  `json:"kind"`
+	Label     string  `json:"label"`
+	FolderID  *string `json:"folder_id,omitempty"`
+	InDegree  int     `json:"in_degree"`
+	OutDegree int     `json:"out_degree"`
+	Degree    int     `json:"degree"`
+	// Distance is the number of link hops from the centre note, if any.
+	Distance *int `json:"distance,omitempty"`
+}
+
+// GraphEdge points from Source to Target: note to linked note, tag to
+// tagged note, folder to contained note or folder. Weight counts the links
+// a link edge stands for.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Kind   string `json:"kind"`
+	Weight int    `json:"weight"`
+}
+
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
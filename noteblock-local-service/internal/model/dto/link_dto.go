@@ -0,0 +1,27 @@
+package dto
+
+// BacklinkDTO is a link into a note from the block BlockID of note NoteID.
+type BacklinkDTO struct {
+	NoteID    string `json:"note_id"`
+	NoteTitle string `json:"note_title"`
+	BlockID   string `json:"block_id"`
+	Target    string `json:"target"`
+	Label     string `json:"label"`
+}
+
+// LinkDTO is a link written in a note; TargetNoteID and TargetTitle are nil
+// while it is unresolved.
+type LinkDTO struct {
+	BlockID      string  `json:"block_id"`
+	Target       string  `json:"target"`
+	Label        string  `json:"label"`
+	TargetNoteID *string `json:"target_note_id"`
+	TargetTitle  *string `json:"target_title"`
+	Resolved     bool    `json:"resolved"`
+}
+
+// UnresolvedLinkDTO is link text no note matches, with the notes using it.
+type UnresolvedLinkDTO struct {
+	Target  string   `json:"target"`
+	NoteIDs []string `json:"note_ids"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+type TagDTO struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// NoteTagDTO is one of a note's tags; Source is "manual" or "inline".
+type NoteTagDTO struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+type TaggedNoteDTO struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	FolderID string   `json:"folder_id"`
+	Tags     []string `json:"tags"`
+}
+
+// TagConfig controls whether #hashtags in text blocks become tags.
+type TagConfig struct {
+	ExtractInline bool `json:"extract_inline"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+// TemplateDTO summarises a template note for pickers. Variables lists the
+// {{name}} placeholders its title and text blocks use, built-ins included.
+type TemplateDTO struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	FolderID   string    `json:"folder_id"`
+	BlockCount int       `json:"block_count"`
+	Variables  []string  `json:"variables"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,30 @@
+package dto
+
+// Kinds of vault.check findings.
+const (
+	FindingIntegrity    = "integrity"     // SQLite's integrity_check failed; restore a backup
+	FindingOrphanFolder = "orphan_folder" // a folder whose parent is gone
+	FindingFolderCycle  = "folder_cycle"  // folders that are each other's ancestors, cut off from root
+	FindingOrphanNote   = "orphan_note"   // a note whose folder is gone
+	FindingOrphanBlock  = "orphan_block"  // a block whose note is gone
+	FindingCorruptBlock = "corrupt_block" // a block whose content is not valid JSON
+	FindingDanglingRow  = "dangling_row"  // a tag, link, placement or daily note row for a missing note
+)
+
+type VaultFinding struct {
+	Kind string `json:"kind"`
+	// ID is the affected row: a folder, note or block ID, or table/key for
+	// dangling rows. Integrity findings have none.
+	ID     string `json:"id,omitempty"`
+	Detail string `json:"detail"`
+	// Repair is what a repair run did about the finding, empty otherwise.
+	Repair string `json:"repair,omitempty"`
+}
+
+type VaultCheckDTO struct {
+	OK       bool           `json:"ok"`
+	Repaired bool           `json:"repaired"`
+	Findings []VaultFinding `json:"findings"`
+	// RecoveredFolderID is the folder repaired orphans were moved into.
+	RecoveredFolderID *string `json:"recovered_folder_id"`
+}
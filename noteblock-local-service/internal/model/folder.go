@@ -6,10 +6,25 @@ import (
 	"time"
 )
 
+// Folder sort modes: how a folder orders its children and notes.
+const (
+	SortByName    = "name"
+	SortByCreated = "created"
+	SortByUpdated = "updated"
+	SortByManual  = "manual"
+)
+
 type Folder struct {
-	ID        string `gorm:"type:uuid;primaryKey"`
-	Name      string
-	ParentID  *string
+	ID       string `gorm:"type:uuid;primaryKey"`
+	Name     string
+	ParentID *string
+	// SortKey places the folder among its siblings when the parent sorts
+	// manually; see package order. It is cleared when the folder moves.
+	SortKey string `gorm:"index"`
+	// SortBy and SortDesc are how this folder orders its own contents.
+	// SortDesc does not apply to manual order.
+	SortBy    string `gorm:"not null;default:name"`
+	SortDesc  bool   `gorm:"not null;default:false"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
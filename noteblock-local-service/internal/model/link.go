@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Link is a [[wiki-link]] written in a text block. Links are rebuilt from the
+// text whenever a note's blocks change, so they are never edited directly.
+type Link struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement"`
+	SourceNoteID  string `gorm:"type:uuid;not null;index"`
+	SourceBlockID string `gorm:"type:uuid;not null;index"`
+	// Target is the text between the brackets: a note title or a note ID.
+	Target string `gorm:"not null;index"`
+	// Label is the optional display text after a |.
+	Label string
+	// ByID is set when Target named the note by ID rather than by title.
+	ByID bool
+	// TargetNoteID is nil while no note matches Target.
+	TargetNoteID *string `gorm:"type:uuid;index"`
+	CreatedAt    time.Time
+}
@@ -7,9 +7,13 @@ import (
 )
 
 type Note struct {
-	ID        string `gorm:"type:uuid;primaryKey"`
-	Title     string
-	FolderID  string `gorm:"type:uuid;not null;index"`
+	ID       string `gorm:"type:uuid;primaryKey"`
+	Title    string
+	FolderID string `gorm:"type:uuid;not null;index"`
+	// IsTemplate marks a note that note.createFromTemplate can instantiate
+	IsTemplate bool `gorm:"not null;default:false;index"`
+	// SortKey places the note in its folder when the folder sorts manually.
+	SortKey   string `gorm:"index"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
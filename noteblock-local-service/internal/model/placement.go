@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Placement lists a note in a folder besides its primary one, Note.FolderID.
+// Deleting a folder only deletes the notes it held the last placement of;
+// the others move their primary placement to another folder.
+type Placement struct {
+	NoteID   string `gorm:"type:uuid;primaryKey"`
+	FolderID string `gorm:"type:uuid;primaryKey;index"`
+	// SortKey places the note in FolderID like Note.SortKey does in its own folder.
+	SortKey   string
+	CreatedAt time.Time
+
+	Note   Note   `gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	Folder Folder `gorm:"foreignKey:FolderID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+// Setting is a vault-wide preference, stored with the vault so every client
+// sees the same value.
+type Setting struct {
+	Key       string `gorm:"primaryKey"`
+	Value     string `gorm:"type:text"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Tag sources. A manual assignment is made through note.tags.*; an inline one
+// is derived from #hashtags in the note's text blocks. Manual wins when both apply.
+const (
+	TagSourceManual = "manual"
+	TagSourceInline = "inline"
+)
+
+type Tag struct {
+	ID string `gorm:"type:uuid;primaryKey"`
+	// Name is normalized: lower case, without the leading #.
+	Name      string `gorm:"not null;uniqueIndex"`
+	CreatedAt time.Time
+}
+
+func (t *Tag) BeforeCreate(*gorm.DB) (err error) {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return
+}
+
+// NoteTag assigns a tag to a note.
+type NoteTag struct {
+	NoteID    string `gorm:"type:uuid;primaryKey"`
+	TagID     string `gorm:"type:uuid;primaryKey;index"`
+	Source    string `gorm:"not null;default:manual"`
+	CreatedAt time.Time
+
+	Note Note `gorm:"foreignKey:NoteID;constraint:OnDelete:CASCADE"`
+	Tag  Tag  `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,119 @@
+// Package order generates lexicographic fractional keys. Sorting rows by their
+// key as a plain string gives their order, and a key can always be minted
+// between any two others, so placing one row never renumbers its neighbours.
+package order
+
+import (
+	"errors"
+	"strings"
+)
+
+// digits are in ASCII order so that byte-wise comparison matches key order.
+const digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidRange = errors.New("order: lower key must sort before upper key")
+
+// KeyBetween returns a key that sorts strictly between a and b. An empty a
+// means "before everything" and an empty b "after everything".
+func KeyBetween(a, b string) (string, error) {
+	if !valid(a) || !valid(b) {
+		return "", errors.New("order: invalid key")
+	}
+	if a != "" && b != "" && a >= b {
+		return "", ErrInvalidRange
+	}
+	return midpoint(a, b), nil
+}
+
+// KeysBetween returns n ascending keys between a and b, spread so that later
+// insertions anywhere among them stay short.
+func KeysBetween(a, b string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if n == 1 {
+		key, err := KeyBetween(a, b)
+		if err != nil {
+			return nil, err
+		}
+		return []string{key}, nil
+	}
+
+	mid, err := KeyBetween(a, b)
+	if err != nil {
+		return nil, err
+	}
+	left, err := KeysBetween(a, mid, n/2)
+	if err != nil {
+		return nil, err
+	}
+	right, err := KeysBetween(mid, b, n-n/2-1)
+	if err != nil {
+		return nil, err
+	}
+	keys := append(left, mid)
+	return append(keys, right...), nil
+}
+
+// midpoint assumes a < b (b == "" is +infinity) and that neither ends in the
+// zero digit, which is what keeps room below every key.
+func midpoint(a, b string) string {
+	if b != "" {
+		// skip the prefix a and b share, treating a missing digit of a as zero
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			rest := ""
+			if n < len(a) {
+				rest = a[n:]
+			}
+			return b[:n] + midpoint(rest, b[n:])
+		}
+	}
+
+	lo := 0
+	if a != "" {
+		lo = strings.IndexByte(digits, a[0])
+	}
+	hi := len(digits)
+	if b != "" {
+		hi = strings.IndexByte(digits, b[0])
+	}
+	if hi-lo > 1 {
+		return string(digits[(lo+hi)/2])
+	}
+
+	// the first digits are adjacent
+	if b != "" && len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if len(a) > 1 {
+		rest = a[1:]
+	}
+	return string(digits[lo]) + midpoint(rest, "")
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+func valid(key string) bool {
+	if key == "" {
+		return true
+	}
+	if key[len(key)-1] == digits[0] {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package order
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestKeyBetween_Bounds(t *testing.T) {
+	cases := []struct{ a, b string }{
+		{"", ""},
+		{"", "V"},
+		{"V", ""},
+		{"V", "W"},
+		{"V", "V1"},
+		{"0V", "1"},
+		{"zz", ""},
+		{"", "01"},
+	}
+	for _, c := range cases {
+		key, err := KeyBetween(c.a, c.b)
+		if err != nil {
+			t.Fatalf("KeyBetween(%q, %q) failed: %v", c.a, c.b, err)
+		}
+		if (c.a != "" && key <= c.a) || (c.b != "" && key >= c.b) {
+			t.Fatalf("KeyBetween(%q, %q) = %q is out of range", c.a, c.b, key)
+		}
+		if !valid(key) {
+			t.Fatalf("KeyBetween(%q, %q) = %q is not a valid key", c.a, c.b, key)
+		}
+	}
+}
+
+func TestKeyBetween_Rejects(t *testing.T) {
+	for _, c := range []struct{ a, b string }{{"W", "V"}, {"V", "V"}, {"V0", ""}, {"", "a-b"}} {
+		if _, err := KeyBetween(c.a, c.b); err == nil {
+			t.Fatalf("expected KeyBetween(%q, %q) to fail", c.a, c.b)
+		}
+	}
+}
+
+func TestKeyBetween_RandomInsertsStayOrdered(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	keys := []string{}
+	for i := 0; i < 2000; i++ {
+		pos := rng.Intn(len(keys) + 1)
+		lo, hi := "", ""
+		if pos > 0 {
+			lo = keys[pos-1]
+		}
+		if pos < len(keys) {
+			hi = keys[pos]
+		}
+		key, err := KeyBetween(lo, hi)
+		if err != nil {
+			t.Fatalf("insert %d failed: %v", i, err)
+		}
+		keys = append(keys[:pos], append([]string{key}, keys[pos:]...)...)
+	}
+	if !sort.StringsAreSorted(keys) {
+		t.Fatal("keys are no longer in insertion order")
+	}
+	for i := 1; i < len(keys); i++ {
+		if keys[i] == keys[i-1] {
+			t.Fatalf("duplicate key %q", keys[i])
+		}
+	}
+}
+
+func TestKeysBetween_Spread(t *testing.T) {
+	keys, err := KeysBetween("", "", 100)
+	if err != nil {
+		t.Fatalf("KeysBetween failed: %v", err)
+	}
+	if len(keys) != 100 || !sort.StringsAreSorted(keys) {
+		t.Fatalf("expected 100 sorted keys, got %v", keys)
+	}
+	for _, key := range keys {
+		if len(key) > 2 {
+			t.Fatalf("expected short keys for 100 rows, got %q", key)
+		}
+	}
+}
@@ -1,42 +1,80 @@
 package routes
 
 import (
+	"os"
+	"server/internal/api"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"server/internal/api"
 )
 
+type Config struct {
+	// Token is required as a bearer token on every route.
+	Token string
+	// AllowOrigins lists browser origins (e.g. an extension) allowed to call
+	// the API. Empty means no cross-origin access at all.
+	AllowOrigins []string
+	// ImagesDir is served under /uploads/images.
+	ImagesDir string
+}
+
 // have all routes in this file, single file this application's API surface
-func Setup(fh *api.FolderHandler, nh *api.NoteHandler, bh *api.BlockHandler) *gin.Engine {
-	r := gin.Default()
+func Setup(cfg Config, fh *api.FolderHandler, nh *api.NoteHandler, bh *api.BlockHandler, rh *api.RPCHandler) *gin.Engine {
+	r := gin.New()
+	// stdout carries the IPC stream when both transports run in one process
+	r.Use(gin.LoggerWithWriter(os.Stderr), gin.RecoveryWithWriter(os.Stderr))
 
-	r.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"}, // or "*"
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	if len(cfg.AllowOrigins) > 0 {
+		r.Use(cors.New(cors.Config{
+			AllowOrigins:  cfg.AllowOrigins,
+			AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders:  []string{"Origin", "Content-Type", "Authorization", "X-Request-ID"},
+			ExposeHeaders: []string{"Content-Length"},
+		}))
+	}
+	r.Use(api.BearerAuth(cfg.Token))
 
-	r.Static("/uploads/images", "./uploads/images")
+	r.Static("/uploads/images", cfg.ImagesDir)
 
 	apiGroup := r.Group("/api")
 	{
-		apiGroup.POST("/folders", fh.Create)
-		apiGroup.GET("/folders/:id", fh.Retrieve)
-		apiGroup.PUT("/folders", fh.Update)
-		apiGroup.DELETE("/folders/:id", fh.Delete)
+		// every IPC method, including those without a dedicated REST route
+		apiGroup.POST("/rpc/:method", rh.Call)
+	}
+
+	// the REST handlers call commands directly, so they take the IPC
+	// dispatch lock themselves, and writes announce themselves to IPC
+	// sessions as the method they mirror; /rpc gets both from Server.Call
+	read := api.Exclusive(rh.Server)
+	write := func(method string) gin.HandlerFunc { return api.Mutation(rh.Server, method) }
+	{
+		apiGroup.POST("/folders", write("folder.create"), fh.Create)
+		apiGroup.GET("/folders/:id", read, fh.Retrieve)
+		apiGroup.GET("/folders/:id/children", read, fh.Children)
+		apiGroup.PUT("/folders/:id/sort", write("folder.sort"), fh.Sort)
+		apiGroup.PUT("/folders/:id/order", write("folder.reorder"), fh.Reorder)
+		apiGroup.POST("/folders/:id/move", write("folder.move"), fh.Move)
+		apiGroup.PUT("/folders", write("folder.update"), fh.Update)
+		apiGroup.DELETE("/folders/:id", write("folder.delete"), fh.Delete)
+		apiGroup.POST("/folders/:id/duplicate", write("folder.duplicate"), fh.Duplicate)
+
+		apiGroup.POST("/notes", write("note.create"), nh.Create)
+		apiGroup.GET("/notes/:id", read, nh.Get)
+		apiGroup.PUT("/notes/:id", write("note.update"), nh.Update)
+		apiGroup.DELETE("/notes/:id", write("note.delete"), nh.Delete)
+		apiGroup.POST("/notes/:id/duplicate", write("note.duplicate"), nh.Duplicate)
+		apiGroup.PUT("/notes/:id/template", write("template.set"), nh.SetTemplate)
 
-		apiGroup.POST("/notes", nh.Create)
-		apiGroup.GET("/notes/:id", nh.Get)
-		apiGroup.PUT("/notes/:id", nh.Update)
-		apiGroup.DELETE("/notes/:id", nh.Delete)
+		apiGroup.GET("/templates", read, nh.ListTemplates)
+		apiGroup.POST("/templates/:id/notes", write("note.createFromTemplate"), nh.CreateFromTemplate)
 
-		apiGroup.POST("/notes/:id/blocks", bh.Create)
-		apiGroup.PUT("/notes/:id/blocks/:block_id", bh.UpdateContent)
-		apiGroup.DELETE("/notes/:id/blocks/:block_id", bh.Delete)
+		apiGroup.POST("/notes/:id/blocks", write("block.create"), bh.Create)
+		apiGroup.PUT("/notes/:id/blocks/:block_id", write("block.update"), bh.UpdateContent)
+		apiGroup.POST("/notes/:id/blocks/:block_id/move", write("block.move"), bh.Move)
+		apiGroup.POST("/notes/:id/blocks/:block_id/copy", write("block.copy"), bh.Copy)
+		apiGroup.DELETE("/notes/:id/blocks/:block_id", write("block.delete"), bh.Delete)
 
-		apiGroup.POST("/upload", bh.UploadImage)
+		apiGroup.POST("/upload", write("asset.uploadImage"), bh.UploadImage)
 	}
 	return r
 }
@@ -0,0 +1,224 @@
+package routes
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"server/internal/api"
+	"server/internal/command"
+	localdb "server/internal/db"
+	"server/internal/ipc"
+)
+
+func setupTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	r, _ := setupTestServer(t)
+	return r
+}
+
+func setupTestServer(t *testing.T) (*gin.Engine, *ipc.Server) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	tmpDir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tmpDir, "routes_test.sqlite")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test sqlite db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql db handle: %v", err)
+	}
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	if err := localdb.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+
+	cmds := command.New(db)
+	server := ipc.NewServer(cmds)
+	return Setup(Config{Token: "secret", ImagesDir: tmpDir},
+		&api.FolderHandler{Cmds: cmds},
+		&api.NoteHandler{Cmds: cmds},
+		&api.BlockHandler{Cmds: cmds},
+		&api.RPCHandler{Server: server},
+	), server
+}
+
+func doRequest(r *gin.Engine, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRoutes_RequireBearerToken(t *testing.T) {
+	r := setupTestRouter(t)
+
+	if rr := doRequest(r, "GET", "/api/folders/root", "", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %v", rr.Code)
+	}
+	if rr := doRequest(r, "GET", "/api/folders/root", "wrong", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 with wrong token, got %v", rr.Code)
+	}
+	if rr := doRequest(r, "GET", "/api/folders/root", "secret", ""); rr.Code != http.StatusOK {
+		t.Errorf("expected 200 with token, got %v: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRoutes_RPCParity(t *testing.T) {
+	r := setupTestRouter(t)
+
+	rr := doRequest(r, "POST", "/api/rpc/note.create", "secret", `{"title":"Spec"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200 from note.create, got %v: %s", rr.Code, rr.Body.String())
+	}
+	rr = doRequest(r, "POST", "/api/rpc/note.create", "secret", `{"title":"Spec"}`)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("expected 409 for duplicate note, got %v: %s", rr.Code, rr.Body.String())
+	}
+	rr = doRequest(r, "POST", "/api/rpc/rpc.describe", "secret", "")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"note.create"`) {
+		t.Errorf("expected rpc.describe over http, got %v: %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestRoutes_RESTAndRPCAgreeOnErrors(t *testing.T) {
+	r := setupTestRouter(t)
+
+	if rr := doRequest(r, "POST", "/api/notes", "secret", `{"title":"Spec"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201 from POST /api/notes, got %v: %s", rr.Code, rr.Body.String())
+	}
+
+	cases := []struct {
+		name      string
+		rest, rpc *httptest.ResponseRecorder
+		want      int
+	}{
+		{
+			name: "conflict",
+			rest: doRequest(r, "POST", "/api/notes", "secret", `{"title":"Spec"}`),
+			rpc:  doRequest(r, "POST", "/api/rpc/note.create", "secret", `{"title":"Spec"}`),
+			want: http.StatusConflict,
+		},
+		{
+			name: "not found",
+			rest: doRequest(r, "GET", "/api/notes/missing", "secret", ""),
+			rpc:  doRequest(r, "POST", "/api/rpc/note.get", "secret", `{"id":"missing"}`),
+			want: http.StatusNotFound,
+		},
+		{
+			name: "validation",
+			rest: doRequest(r, "DELETE", "/api/folders/root", "secret", ""),
+			rpc:  doRequest(r, "POST", "/api/rpc/folder.delete", "secret", `{"id":"root"}`),
+			want: http.StatusBadRequest,
+		},
+	}
+	for _, c := range cases {
+		if c.rest.Code != c.want || c.rpc.Code != c.want {
+			t.Errorf("%s: expected %v from both transports, got rest=%v rpc=%v", c.name, c.want, c.rest.Code, c.rpc.Code)
+		}
+	}
+}
+
+func TestRoutes_RESTWaitsForDispatchLock(t *testing.T) {
+	r, server := setupTestServer(t)
+
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go server.Exclusive(func() {
+		close(held)
+		<-release
+	})
+	<-held
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- doRequest(r, http.MethodPost, "/api/notes", "secret", `{"title":"Waiting"}`)
+	}()
+	select {
+	case <-done:
+		t.Fatal("REST write ran while an IPC request held the dispatch lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if w := <-done; w.Code != http.StatusCreated && w.Code != http.StatusOK {
+		t.Fatalf("expected the write to go through once the lock was free, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRoutes_RESTWritesBroadcastChanges(t *testing.T) {
+	r, server := setupTestServer(t)
+
+	in, feed := io.Pipe()
+	outR, out := io.Pipe()
+	go func() { _ = server.Run(in, out) }()
+	t.Cleanup(func() { _ = feed.Close() })
+	lines := bufio.NewScanner(outR)
+	next := func() string {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("session closed: %v", lines.Err())
+		}
+		return lines.Text()
+	}
+	// the session is registered once it has answered a request
+	if _, err := io.WriteString(feed, `{"id":"1","method":"folder.get","params":{"id":"root"}}`+"\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	next()
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- doRequest(r, http.MethodPost, "/api/notes", "secret", `{"title":"From REST"}`) }()
+	var event struct {
+		Method string `json:"method"`
+		Params struct {
+			Method string         `json:"method"`
+			Params map[string]any `json:"params"`
+			Result map[string]any `json:"result"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(next()), &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	if event.Method != "event.changed" || event.Params.Method != "note.create" ||
+		event.Params.Params["title"] != "From REST" || event.Params.Result["title"] != "From REST" {
+		t.Fatalf("unexpected change event %+v", event)
+	}
+	id := event.Params.Result["id"].(string)
+
+	go func() { done <- doRequest(r, http.MethodPut, "/api/notes/"+id, "secret", `{"title":"Renamed"}`) }()
+	if err := json.Unmarshal([]byte(next()), &event); err != nil {
+		t.Fatalf("decode event: %v", err)
+	}
+	<-done
+	if event.Params.Method != "note.update" || event.Params.Params["id"] != id || event.Params.Params["title"] != "Renamed" {
+		t.Fatalf("expected path parameters in the event params, got %+v", event)
+	}
+
+	// reads and failed writes stay quiet
+	doRequest(r, http.MethodGet, "/api/notes/"+id, "secret", "")
+	doRequest(r, http.MethodPost, "/api/notes", "secret", `{"title":"Renamed"}`)
+	if _, err := io.WriteString(feed, `{"id":"2","method":"folder.get","params":{"id":"root"}}`+"\n"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if line := next(); !strings.Contains(line, `"id":"2"`) {
+		t.Fatalf("expected no event before the next response, got %s", line)
+	}
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"server/internal/model"
+	"server/internal/model/dto"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// AssetService answers questions about uploaded files without callers having
+// to know where they live on disk.
+type AssetService struct {
+	DB *gorm.DB
+}
+
+func (s *AssetService) path(url string) (string, error) {
+	name := ImageName(url)
+	if name == "" {
+		return "", os.ErrNotExist
+	}
+	return filepath.Join(ImagesDir(), name), nil
+}
+
+// ReadRange returns up to length bytes of the asset starting at offset, and
+// the asset's total size.
+func (s *AssetService) ReadRange(url string, offset, length int64) ([]byte, int64, error) {
+	path, err := s.path(url)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	total := info.Size()
+	if offset >= total {
+		return []byte{}, total, nil
+	}
+	if remaining := total - offset; length > remaining {
+		length = remaining
+	}
+
+	data := make([]byte, length)
+	if _, err := f.ReadAt(data, offset); err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	return data, total, nil
+}
+
+func (s *AssetService) Stat(url string) (*dto.AssetInfo, error) {
+	path, err := s.path(url)
+	if err != nil {
+		return nil, err
+	}
+	return statFile(path)
+}
+
+// List returns every uploaded asset with the blocks that reference it, ordered by name.
+func (s *AssetService) List() ([]dto.AssetEntry, error) {
+	entries, err := os.ReadDir(ImagesDir())
+	if os.IsNotExist(err) {
+		return []dto.AssetEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make([]dto.AssetEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := statFile(filepath.Join(ImagesDir(), entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		refs, err := s.References(info.Filename)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, dto.AssetEntry{AssetInfo: *info, References: refs})
+	}
+	sort.Slice(assets, func(i, j int) bool { return assets[i].Filename < assets[j].Filename })
+	return assets, nil
+}
+
+// References finds the blocks whose content mentions the asset's file name.
+// Names carry a UUID prefix, so a substring match does not collide.
+func (s *AssetService) References(filename string) ([]dto.AssetRef, error) {
+	refs := []dto.AssetRef{}
+	err := s.DB.Model(&model.Block{}).
+		Select("blocks.id AS block_id, blocks.note_id AS note_id, notes.title AS note_title").
+		Joins("JOIN notes ON notes.id = blocks.note_id").
+		Where("blocks.content LIKE ? ESCAPE '\\'", "%"+escapeLike(filename)+"%").
+		Order("notes.title, blocks.id").
+		Scan(&refs).Error
+	return refs, err
+}
+
+func statFile(path string) (*dto.AssetInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+
+	name := filepath.Base(path)
+	info := &dto.AssetInfo{
+		URL:        ImageURLPrefix + name,
+		Filename:   name,
+		Size:       fi.Size(),
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		ModifiedAt: fi.ModTime().UTC(),
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	info.MimeType = http.DetectContentType(head[:n])
+	// sniffing cannot tell svg or webp apart from text or bytes, the extension can
+	if byExt := mime.TypeByExtension(filepath.Ext(name)); byExt != "" &&
+		(info.MimeType == "application/octet-stream" || strings.HasPrefix(info.MimeType, "text/plain")) {
+		info.MimeType = byExt
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if cfg, _, err := image.DecodeConfig(f); err == nil {
+		info.Width = cfg.Width
+		info.Height = cfg.Height
+	}
+	return info, nil
+}
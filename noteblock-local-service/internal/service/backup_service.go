@@ -0,0 +1,372 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"server/internal/db"
+	"server/internal/model/dto"
+
+	"gorm.io/gorm"
+)
+
+// BackupsDirName is the directory beside the vault database that holds one
+// directory per backup: the database copy, its images and a manifest.
+const BackupsDirName = "backups"
+
+const (
+	backupManifestName = "backup.json"
+	backupImagesDir    = "images"
+	backupTimeLayout   = "20060102-150405.000"
+)
+
+var (
+	// ErrBackupNotFound is returned for a backup ID with no finished backup.
+	ErrBackupNotFound = errors.New("backup not found")
+	// ErrBackupUnverified wraps why a backup failed db.Verify.
+	ErrBackupUnverified = errors.New("backup failed verification")
+)
+
+// backupMu keeps backups, restores and rotation from overlapping. It is not a
+// field because Commands builds fresh services for every transaction.
+var backupMu sync.Mutex
+
+// BackupService takes, lists, rotates and restores backups of the vault.
+// A backup is written to a hidden directory and only moved into place once
+// the copy passes db.Verify, so anything List returns is known to be good.
+type BackupService struct{ DB *gorm.DB }
+
+// dataDir is the directory of the open database file, so backups sit next to
+// the vault however it was opened.
+func (s *BackupService) dataDir() (string, error) {
+	var file string
+	if err := s.DB.Raw("SELECT file FROM pragma_database_list WHERE name = 'main'").Scan(&file).Error; err != nil {
+		return "", err
+	}
+	if file == "" {
+		return "", errors.New("database is not backed by a file")
+	}
+	return filepath.Dir(file), nil
+}
+
+// Create takes a backup of the given kind, named after now.
+func (s *BackupService) Create(kind string, now time.Time) (*dto.BackupDTO, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+	return s.create(kind, now)
+}
+
+func (s *BackupService) create(kind string, now time.Time) (*dto.BackupDTO, error) {
+	dir, err := s.dataDir()
+	if err != nil {
+		return nil, err
+	}
+	id := now.Format(backupTimeLayout) + "-" + kind
+	final := filepath.Join(dir, BackupsDirName, id)
+	if _, err := os.Stat(final); err == nil {
+		return nil, fmt.Errorf("backup %s already exists", id)
+	}
+
+	tmp := filepath.Join(dir, BackupsDirName, ".tmp-"+id)
+	if err := os.RemoveAll(tmp); err != nil {
+		return nil, err
+	}
+	backup, err := s.write(tmp, imagesDirIn(dir), id, kind, now)
+	if err == nil {
+		err = os.Rename(tmp, final)
+	}
+	if err != nil {
+		_ = os.RemoveAll(tmp)
+		return nil, err
+	}
+	return backup, nil
+}
+
+// write fills dir with a backup and checks it before writing the manifest
+// that makes it count.
+func (s *BackupService) write(dir, imagesDir, id, kind string, now time.Time) (*dto.BackupDTO, error) {
+	dbPath := filepath.Join(dir, db.FileName)
+	if err := db.VacuumInto(s.DB, dbPath); err != nil {
+		return nil, err
+	}
+	images, err := listFiles(imagesDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := copyFiles(imagesDir, filepath.Join(dir, backupImagesDir), images); err != nil {
+		return nil, err
+	}
+
+	version, err := db.Verify(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	copied, err := listFiles(filepath.Join(dir, backupImagesDir))
+	if err != nil {
+		return nil, err
+	}
+	var imagesSize int64
+	for name, size := range images {
+		if copied[name] != size {
+			return nil, fmt.Errorf("image %s was not copied intact", name)
+		}
+		imagesSize += size
+	}
+	info, err := os.Stat(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &dto.BackupDTO{
+		ID:            id,
+		Kind:          kind,
+		CreatedAt:     now,
+		SchemaVersion: version,
+		Size:          info.Size(),
+		Images:        len(images),
+		ImagesSize:    imagesSize,
+	}
+	raw, err := json.MarshalIndent(backup, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return backup, os.WriteFile(filepath.Join(dir, backupManifestName), raw, 0o644)
+}
+
+// List returns the finished backups, newest first.
+func (s *BackupService) List() ([]dto.BackupDTO, error) {
+	dir, err := s.dataDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, BackupsDirName))
+	if errors.Is(err, os.ErrNotExist) {
+		return []dto.BackupDTO{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []dto.BackupDTO{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// a directory without a manifest never passed verification
+		backup, err := readManifest(filepath.Join(dir, BackupsDirName, entry.Name()))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, *backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+func readManifest(dir string) (*dto.BackupDTO, error) {
+	raw, err := os.ReadFile(filepath.Join(dir, backupManifestName))
+	if err != nil {
+		return nil, err
+	}
+	var backup dto.BackupDTO
+	if err := json.Unmarshal(raw, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// backupDir returns the directory of the finished backup id.
+func (s *BackupService) backupDir(id string) (string, *dto.BackupDTO, error) {
+	if id == "" || id != filepath.Base(id) || strings.HasPrefix(id, ".") {
+		return "", nil, ErrBackupNotFound
+	}
+	dir, err := s.dataDir()
+	if err != nil {
+		return "", nil, err
+	}
+	path := filepath.Join(dir, BackupsDirName, id)
+	backup, err := readManifest(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil, ErrBackupNotFound
+	}
+	return path, backup, err
+}
+
+// Restore replaces the live vault with backup id. The backup is verified
+// again first, since files can rot after they were written, and the vault is
+// backed up as a pre_restore backup before anything is replaced. Images the
+// backup has are put back; images uploaded since are left where they are.
+// The caller migrates the restored database.
+func (s *BackupService) Restore(id string, now time.Time) (restored, safety *dto.BackupDTO, err error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	path, restored, err := s.backupDir(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbPath := filepath.Join(path, db.FileName)
+	if _, err := db.Verify(dbPath); err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrBackupUnverified, err)
+	}
+
+	if safety, err = s.create(dto.BackupPreRestore, now); err != nil {
+		return nil, nil, err
+	}
+	if err := db.Restore(s.DB, dbPath); err != nil {
+		return nil, nil, err
+	}
+
+	dir, err := s.dataDir()
+	if err != nil {
+		return nil, nil, err
+	}
+	images, err := listFiles(filepath.Join(path, backupImagesDir))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := copyFiles(filepath.Join(path, backupImagesDir), imagesDirIn(dir), images); err != nil {
+		return nil, nil, err
+	}
+	return restored, safety, nil
+}
+
+// Rotate deletes the scheduled backups cfg no longer keeps and returns their
+// IDs. The newest backup in each of the last KeepHourly hours, KeepDaily days
+// and KeepWeekly ISO weeks is kept, and so is the newest backup overall.
+// Manual and pre_restore backups are left alone.
+func (s *BackupService) Rotate(cfg dto.BackupConfig) ([]string, error) {
+	backupMu.Lock()
+	defer backupMu.Unlock()
+
+	backups, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var scheduled []dto.BackupDTO
+	for _, b := range backups {
+		if b.Kind == dto.BackupScheduled {
+			scheduled = append(scheduled, b)
+		}
+	}
+	if len(scheduled) == 0 {
+		return nil, nil
+	}
+
+	keep := map[string]bool{scheduled[0].ID: true}
+	keepNewest := func(n int, bucket func(time.Time) string) {
+		seen := map[string]bool{}
+		for _, b := range scheduled {
+			key := bucket(b.CreatedAt.Local())
+			if seen[key] {
+				continue
+			}
+			if len(seen) == n {
+				return
+			}
+			seen[key] = true
+			keep[b.ID] = true
+		}
+	}
+	keepNewest(cfg.KeepHourly, func(t time.Time) string { return t.Format("2006010215") })
+	keepNewest(cfg.KeepDaily, func(t time.Time) string { return t.Format("20060102") })
+	keepNewest(cfg.KeepWeekly, func(t time.Time) string {
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-%02d", year, week)
+	})
+
+	dir, err := s.dataDir()
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for _, b := range scheduled {
+		if keep[b.ID] {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(dir, BackupsDirName, b.ID)); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, b.ID)
+	}
+	return deleted, nil
+}
+
+// listFiles maps the names of the regular files in dir to their sizes; a
+// missing dir has none.
+func listFiles(dir string) (map[string]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]int64{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]int64, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		files[entry.Name()] = info.Size()
+	}
+	return files, nil
+}
+
+// copyFiles copies files from src to dst, skipping any dst already has at the
+// same size. Uploaded images are never rewritten in place, so a hard link is
+// as good as a copy and costs no space; copying is the fallback for file
+// systems without links.
+func copyFiles(src, dst string, files map[string]int64) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(dst, os.ModePerm); err != nil {
+		return err
+	}
+	for name, size := range files {
+		to := filepath.Join(dst, name)
+		if info, err := os.Stat(to); err == nil && info.Size() == size {
+			continue
+		}
+		_ = os.Remove(to)
+		from := filepath.Join(src, name)
+		if os.Link(from, to) == nil {
+			continue
+		}
+		if err := copyFile(from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -2,30 +2,44 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"server/internal/db"
 	"server/internal/model"
+	"server/internal/model/dto"
+	"server/internal/order"
+	"sort"
+	"strings"
 )
 
 type BlockService struct {
 	DB *gorm.DB
 }
 
+// ImageURLPrefix is the scheme Electron resolves uploaded images through.
+const ImageURLPrefix = "noteblock-image:///"
+
+// ImagesDir is where uploaded images live, next to the vault database.
+func ImagesDir() string {
+	return imagesDirIn(db.DataDir())
+}
+
+func imagesDirIn(dataDir string) string {
+	return filepath.Join(dataDir, "uploads", "images")
+}
+
 func (s *BlockService) SaveImage(file *multipart.FileHeader) (string, error) {
 	newImageUuid := uuid.NewString()
-	imageName := newImageUuid + "_" + file.Filename
+	imageName := newImageUuid + "_" + filepath.Base(file.Filename)
 
-	basePath := os.Getenv("NOTE_DB_PATH")
-	if basePath == "" {
-		basePath = "data"
-	}
-	imagesDir := filepath.Join(basePath, "uploads", "images")
+	imagesDir := ImagesDir()
 	fsPath := filepath.Join(imagesDir, imageName)
-	publicPath := "noteblock-image:///" + imageName
+	publicPath := ImageURLPrefix + imageName
 
 	if err := os.MkdirAll(imagesDir, os.ModePerm); err != nil {
 		return "", err
@@ -52,13 +66,9 @@ func (s *BlockService) SaveImage(file *multipart.FileHeader) (string, error) {
 
 func (s *BlockService) SaveImageBytes(fileName string, data []byte) (string, error) {
 	newImageUuid := uuid.NewString()
-	imageName := newImageUuid + "_" + fileName
+	imageName := newImageUuid + "_" + filepath.Base(fileName)
 
-	basePath := os.Getenv("NOTE_DB_PATH")
-	if basePath == "" {
-		basePath = "data"
-	}
-	imagesDir := filepath.Join(basePath, "uploads", "images")
+	imagesDir := ImagesDir()
 	if err := os.MkdirAll(imagesDir, os.ModePerm); err != nil {
 		return "", err
 	}
@@ -68,10 +78,24 @@ func (s *BlockService) SaveImageBytes(fileName string, data []byte) (string, err
 		return "", err
 	}
 
-	return "noteblock-image:///" + imageName, nil
+	return ImageURLPrefix + imageName, nil
 }
 
+// ImageName resolves a noteblock-image URL, or a bare file name, to the file
+// name inside ImagesDir. It never escapes that directory.
+func ImageName(url string) string {
+	name := filepath.Base(strings.TrimPrefix(url, ImageURLPrefix))
+	if name == "." || name == string(filepath.Separator) {
+		return ""
+	}
+	return name
+}
+
+// ErrBlockNotFound is returned when a block or one of its would-be neighbours is not in the note.
+var ErrBlockNotFound = errors.New("block not found")
+
 // TODO: for non-plugin blocks, we can assert type and json content fields by unmarshalling before storing
+// CreateNewBlock inserts the block at position index, clamped to the note's length.
 func (s *BlockService) CreateNewBlock(noteID string, blockType string, index int, content *json.RawMessage) (*model.Block, error) { // should this somehow handle both creation and update of blocks?
 	jsonString, err := EncodeJsonToString(content)
 	if err != nil {
@@ -81,14 +105,26 @@ func (s *BlockService) CreateNewBlock(noteID string, blockType string, index int
 	block := &model.Block{
 		NoteID:  noteID,
 		Type:    blockType,
-		Index:   index,
 		Content: jsonString,
 	}
 
-	if err := s.DB.Create(block).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		siblings, err := orderedBlocks(tx, noteID, "")
+		if err != nil {
+			return err
+		}
+		index = max(0, min(index, len(siblings)))
+		key, err := keyAt(tx, noteID, siblings, index)
+		if err != nil {
+			return err
+		}
+		block.OrderKey = key
+		block.Index = index
+		return tx.Create(block).Error
+	})
+	if err != nil {
 		return nil, err
 	}
-
 	return block, nil
 }
 
@@ -112,9 +148,272 @@ func (s *BlockService) UpdateBlockContent(noteID string, blockID string, blockTy
 		return nil, err
 	}
 
+	block.Index, err = position(s.DB, &block)
 	return &block, err
 }
 
+// MoveBlock places a block between afterID and beforeID in targetNoteID, which
+// may be its own note. Either neighbour may be empty: with only one given the
+// block goes right next to it, with neither it goes to the end.
+func (s *BlockService) MoveBlock(noteID, blockID, targetNoteID, afterID, beforeID string) (*model.Block, error) {
+	var block model.Block
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&block, "id = ? AND note_id = ?", blockID, noteID).Error; err != nil {
+			return err
+		}
+		key, index, err := placeBlock(tx, targetNoteID, blockID, afterID, beforeID)
+		if err != nil {
+			return err
+		}
+		block.NoteID = targetNoteID
+		block.OrderKey = key
+		block.Index = index
+		return tx.Model(&block).Updates(map[string]any{"note_id": targetNoteID, "order_key": key}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &block, nil
+}
+
+// CopyBlock duplicates a block into targetNoteID at the position MoveBlock
+// would give it. Asset files are never rewritten or deleted, so the copy
+// simply shares the image URLs in its content with the original.
+func (s *BlockService) CopyBlock(noteID, blockID, targetNoteID, afterID, beforeID string) (*model.Block, error) {
+	var copied *model.Block
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var source model.Block
+		if err := tx.First(&source, "id = ? AND note_id = ?", blockID, noteID).Error; err != nil {
+			return err
+		}
+		key, index, err := placeBlock(tx, targetNoteID, "", afterID, beforeID)
+		if err != nil {
+			return err
+		}
+		copied = &model.Block{
+			NoteID:   targetNoteID,
+			Type:     source.Type,
+			Content:  source.Content,
+			OrderKey: key,
+			Index:    index,
+		}
+		return tx.Create(copied).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// placeBlock finds the key and position for a block between afterID and
+// beforeID in noteID, ignoring excludeID (the block being moved) if given.
+func placeBlock(tx *gorm.DB, noteID, excludeID, afterID, beforeID string) (string, int, error) {
+	siblings, err := orderedBlocks(tx, noteID, excludeID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	index := len(siblings)
+	if afterID != "" {
+		i := indexOfBlock(siblings, afterID)
+		if i < 0 {
+			return "", 0, ErrBlockNotFound
+		}
+		index = i + 1
+	}
+	if beforeID != "" {
+		i := indexOfBlock(siblings, beforeID)
+		if i < 0 {
+			return "", 0, ErrBlockNotFound
+		}
+		if afterID != "" && i != index {
+			return "", 0, ErrNotAdjacent
+		}
+		index = i
+	}
+
+	key, err := keyAt(tx, noteID, siblings, index)
+	return key, index, err
+}
+
+// ErrNotAdjacent is returned by MoveBlock and CopyBlock when after and before are not neighbours.
+var ErrNotAdjacent = errors.New("after and before blocks are not adjacent")
+
+// BlockIndex is the position a client assigns to one block of a note.
+type BlockIndex struct {
+	ID    string
+	Index int
+}
+
+// ReorderBlocks applies client-side positions to the blocks of one note and
+// mints fresh order keys for all of them. Blocks the client did not mention
+// keep their place relative to each other; blocks that belong to another
+// note are ignored.
+func (s *BlockService) ReorderBlocks(noteID string, order []BlockIndex) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		blocks, err := orderedBlocks(tx, noteID, "")
+		if err != nil {
+			return err
+		}
+		wanted := make(map[string]int, len(order))
+		for _, block := range order {
+			wanted[block.ID] = block.Index
+		}
+		rank := make(map[string]int, len(blocks))
+		for i, block := range blocks {
+			if index, ok := wanted[block.ID]; ok {
+				rank[block.ID] = index
+			} else {
+				rank[block.ID] = i
+			}
+		}
+		sort.SliceStable(blocks, func(i, j int) bool { return rank[blocks[i].ID] < rank[blocks[j].ID] })
+		return rewriteOrderKeys(tx, blocks)
+	})
+}
+
 func (s *BlockService) DeleteBlock(noteID string, blockID string) error {
 	return s.DB.Delete(&model.Block{}, "id = ? AND note_id = ?", blockID, noteID).Error
 }
+
+// FindBlock returns a block of any note, with Index filled in, and the title
+// of its note. The block is nil when there is none.
+func (s *BlockService) FindBlock(blockID string) (*model.Block, string, error) {
+	var block model.Block
+	err := s.DB.First(&block, "id = ?", blockID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if block.Index, err = position(s.DB, &block); err != nil {
+		return nil, "", err
+	}
+	var note model.Note
+	if err := s.DB.Select("title").First(&note, "id = ?", block.NoteID).Error; err != nil {
+		return nil, "", err
+	}
+	return &block, note.Title, nil
+}
+
+// EmbedRef is an embed block and the note it sits in.
+type EmbedRef struct {
+	BlockID   string
+	NoteID    string
+	NoteTitle string
+}
+
+// EmbedsOf lists the embed blocks that transclude blockID, by note title.
+func (s *BlockService) EmbedsOf(blockID string) ([]EmbedRef, error) {
+	var refs []EmbedRef
+	err := s.DB.Model(&model.Block{}).
+		Select("blocks.id AS block_id, blocks.note_id, notes.title AS note_title").
+		Joins("JOIN notes ON notes.id = blocks.note_id").
+		Where("blocks.type = ? AND json_extract(blocks.content, '$.block_id') = ?", dto.EmbedBlockType, blockID).
+		Order("notes.title, blocks.note_id, blocks.order_key").
+		Scan(&refs).Error
+	return refs, err
+}
+
+// CopyNoteBlocks duplicates every block of one note into another, keeping order keys.
+func (s *BlockService) CopyNoteBlocks(fromNoteID, toNoteID string) error {
+	blocks, err := orderedBlocks(s.DB, fromNoteID, "")
+	if err != nil {
+		return err
+	}
+	for _, block := range blocks {
+		if err := s.DB.Create(&model.Block{
+			NoteID:   toNoteID,
+			Type:     block.Type,
+			Content:  block.Content,
+			OrderKey: block.OrderKey,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OrderedBlocks returns the blocks of a note in order with Index filled in.
+func (s *BlockService) OrderedBlocks(noteID string) ([]model.Block, error) {
+	return orderedBlocks(s.DB, noteID, "")
+}
+
+// orderedBlocks loads a note's blocks in order, leaving out excludeID.
+func orderedBlocks(tx *gorm.DB, noteID, excludeID string) ([]model.Block, error) {
+	var blocks []model.Block
+	q := tx.Where("note_id = ?", noteID)
+	if excludeID != "" {
+		q = q.Where("id <> ?", excludeID)
+	}
+	if err := q.Order("order_key, id").Find(&blocks).Error; err != nil {
+		return nil, err
+	}
+	for i := range blocks {
+		blocks[i].Index = i
+	}
+	return blocks, nil
+}
+
+// keyAt mints a key for position index among siblings. Two blocks can end up
+// sharing a key if REST and IPC insert at the same spot at once; the note is
+// then rekeyed and the key minted again.
+func keyAt(tx *gorm.DB, noteID string, siblings []model.Block, index int) (string, error) {
+	lo, hi := "", ""
+	if index > 0 {
+		lo = siblings[index-1].OrderKey
+	}
+	if index < len(siblings) {
+		hi = siblings[index].OrderKey
+	}
+	key, err := order.KeyBetween(lo, hi)
+	if err == nil {
+		return key, nil
+	}
+
+	if err := rewriteOrderKeys(tx, siblings); err != nil {
+		return "", err
+	}
+	lo, hi = "", ""
+	if index > 0 {
+		lo = siblings[index-1].OrderKey
+	}
+	if index < len(siblings) {
+		hi = siblings[index].OrderKey
+	}
+	return order.KeyBetween(lo, hi)
+}
+
+// rewriteOrderKeys gives blocks evenly spread keys in slice order.
+func rewriteOrderKeys(tx *gorm.DB, blocks []model.Block) error {
+	keys, err := order.KeysBetween("", "", len(blocks))
+	if err != nil {
+		return err
+	}
+	for i := range blocks {
+		blocks[i].OrderKey = keys[i]
+		blocks[i].Index = i
+		if err := tx.Model(&model.Block{}).Where("id = ?", blocks[i].ID).Update("order_key", keys[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func position(tx *gorm.DB, block *model.Block) (int, error) {
+	var count int64
+	err := tx.Model(&model.Block{}).
+		Where("note_id = ? AND (order_key < ? OR (order_key = ? AND id < ?))", block.NoteID, block.OrderKey, block.OrderKey, block.ID).
+		Count(&count).Error
+	return int(count), err
+}
+
+func indexOfBlock(blocks []model.Block, id string) int {
+	for i := range blocks {
+		if blocks[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"encoding/json"
+	"server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ChangeService struct {
+	DB *gorm.DB
+}
+
+// Append records a change; data is marshalled into the entry's details.
+func (s *ChangeService) Append(action, entity, entityID, noteID string, data any) (*model.Change, error) {
+	change, err := newChange(action, entity, entityID, noteID, data)
+	if err != nil {
+		return nil, err
+	}
+	return change, s.DB.Create(change).Error
+}
+
+// AppendAfter records a change numbered above seq even when the log now
+// ends lower, as it does after a restore rolled it back. Clients that had
+// already read up to seq still see the change.
+func (s *ChangeService) AppendAfter(seq uint64, action, entity, entityID, noteID string, data any) (*model.Change, error) {
+	change, err := newChange(action, entity, entityID, noteID, data)
+	if err != nil {
+		return nil, err
+	}
+	last, err := s.LastSeq()
+	if err != nil {
+		return nil, err
+	}
+	change.Seq = max(seq, last) + 1
+	return change, s.DB.Create(change).Error
+}
+
+// LastSeq returns the newest sequence number, 0 for an empty log.
+func (s *ChangeService) LastSeq() (uint64, error) {
+	var seq uint64
+	err := s.DB.Model(&model.Change{}).Select("COALESCE(MAX(seq), 0)").Scan(&seq).Error
+	return seq, err
+}
+
+func newChange(action, entity, entityID, noteID string, data any) (*model.Change, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Change{
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		NoteID:   noteID,
+		Data:     string(raw),
+	}, nil
+}
+
+// Since returns up to limit changes with a sequence number above seq, oldest first.
+func (s *ChangeService) Since(seq uint64, limit int) ([]model.Change, error) {
+	var changes []model.Change
+	err := s.DB.Where("seq > ?", seq).Order("seq").Limit(limit).Find(&changes).Error
+	return changes, err
+}
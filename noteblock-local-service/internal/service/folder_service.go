@@ -1,9 +1,11 @@
 package service
 
 import (
+	"errors"
 	"gorm.io/gorm"
 	"server/internal/model"
 	"server/internal/model/dto"
+	"time"
 )
 
 type FolderService struct {
@@ -25,6 +27,9 @@ func (s *FolderService) UpdateFolder(id string, name string, parentId *string) (
 			return err
 		}
 
+		if !samePointer(folder.ParentID, parentId) {
+			folder.SortKey = ""
+		}
 		folder.Name = name
 		folder.ParentID = parentId
 
@@ -60,60 +65,179 @@ func (s *FolderService) ListChildrenByParentId(parentID *string) ([]model.Folder
 	return folders, err
 }
 
+// ListAll returns every folder without its contents.
+func (s *FolderService) ListAll() ([]model.Folder, error) {
+	var folders []model.Folder
+	err := s.DB.Order("name, id").Find(&folders).Error
+	return folders, err
+}
+
+// subtreeSQL selects the IDs of a folder and every folder below it. UNION
+// rather than UNION ALL keeps a corrupt parent cycle from looping forever.
+const subtreeSQL = `WITH RECURSIVE subtree(id) AS (
+	SELECT id FROM folders WHERE id = ?
+	UNION
+	SELECT folders.id FROM folders JOIN subtree ON folders.parent_id = subtree.id
+) SELECT id FROM subtree`
+
+// SubtreeIDs returns rootID and the IDs of every folder below it.
+func (s *FolderService) SubtreeIDs(rootID string) ([]string, error) {
+	var ids []string
+	err := s.DB.Raw(subtreeSQL, rootID).Scan(&ids).Error
+	return ids, err
+}
+
+// GetFolderDtoById loads the folder tree under id with one query for the
+// folders and one each for the notes and placements in them.
 func (s *FolderService) GetFolderDtoById(id string) (*dto.FolderResponse, error) {
-	var folder model.Folder
+	var folders []model.Folder
+	if err := s.DB.Where("id IN (?)", gorm.Expr(subtreeSQL, id)).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	if len(folders) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
 
-	err := s.DB.
-		Where("id = ?", id).
-		First(&folder).Error
+	notes, err := s.folderNotes("IN (?)", gorm.Expr(subtreeSQL, id))
 	if err != nil {
 		return nil, err
 	}
+	return buildFolderTree(id, folders, notes), nil
+}
 
-	return s.buildFolderResponseRecursive(folder.ID)
+// folderNote is a note listed in a folder, either in its own folder or
+// through a placement, with what the folder sorts it by.
+type folderNote struct {
+	ID        string
+	Title     string
+	FolderID  string
+	SortKey   string
+	IsPrimary bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
-func (s *FolderService) buildFolderResponseRecursive(folderID string) (*dto.FolderResponse, error) {
-	var folder model.Folder
-	if err := s.DB.Where("id = ?", folderID).First(&folder).Error; err != nil {
+func (n folderNote) sortEntry() sortEntry {
+	return sortEntry{ID: n.ID, Name: n.Title, SortKey: n.SortKey, CreatedAt: n.CreatedAt, UpdatedAt: n.UpdatedAt}
+}
+
+// folderNotes lists the notes whose folder matches cond, e.g. "= ?", both
+// those living there and those placed there.
+func (s *FolderService) folderNotes(cond string, arg any) ([]folderNote, error) {
+	var notes []folderNote
+	if err := s.DB.Model(&model.Note{}).
+		Select("id, title, folder_id, sort_key, created_at, updated_at, ? AS is_primary", true).
+		Where("folder_id "+cond, arg).
+		Scan(&notes).Error; err != nil {
 		return nil, err
 	}
-
-	var children []model.Folder
-	if err := s.DB.Where("parent_id = ?", folderID).Find(&children).Error; err != nil {
+	var placed []folderNote
+	if err := s.DB.Model(&model.Placement{}).
+		Select("notes.id, notes.title, placements.folder_id, placements.sort_key, notes.created_at, notes.updated_at, ? AS is_primary", false).
+		Joins("JOIN notes ON notes.id = placements.note_id").
+		Where("placements.folder_id "+cond, arg).
+		Scan(&placed).Error; err != nil {
 		return nil, err
 	}
+	return append(notes, placed...), nil
+}
 
-	var notes []model.Note
-	if err := s.DB.Select("id", "title").Where("folder_id = ?", folderID).Find(&notes).Error; err != nil {
-		return nil, err
+// buildFolderTree assembles the response for rootID from the flat rows of
+// its subtree, each folder's contents in the folder's sort order.
+func buildFolderTree(rootID string, folders []model.Folder, notes []folderNote) *dto.FolderResponse {
+	children := map[string][]model.Folder{}
+	var root model.Folder
+	for _, f := range folders {
+		if f.ID == rootID {
+			root = f
+		} else if f.ParentID != nil {
+			children[*f.ParentID] = append(children[*f.ParentID], f)
+		}
+	}
+	notesIn := map[string][]folderNote{}
+	for _, n := range notes {
+		notesIn[n.FolderID] = append(notesIn[n.FolderID], n)
 	}
 
-	// Recursively build child folder responses
-	childResponses := make([]dto.FolderResponse, 0, len(children))
-	for _, child := range children {
-		childResponse, err := s.buildFolderResponseRecursive(child.ID)
-		if err != nil {
-			return nil, err
+	var build func(folder model.Folder) dto.FolderResponse
+	build = func(folder model.Folder) dto.FolderResponse {
+		subfolders, listed := children[folder.ID], notesIn[folder.ID]
+		sortEntries(subfolders, folderSortEntry, folder.SortBy, folder.SortDesc)
+		sortEntries(listed, folderNote.sortEntry, folder.SortBy, folder.SortDesc)
+
+		res := dto.FolderResponse{
+			ID:       folder.ID,
+			Name:     folder.Name,
+			ParentID: folder.ParentID,
+			SortBy:   folder.SortBy,
+			SortDesc: folder.SortDesc,
+			Children: make([]dto.FolderResponse, 0, len(subfolders)),
+			Notes:    make([]dto.NoteResponse, 0, len(listed)),
+		}
+		for _, child := range subfolders {
+			res.Children = append(res.Children, build(child))
+		}
+		for _, n := range listed {
+			res.Notes = append(res.Notes, dto.NoteResponse{ID: n.ID, Title: n.Title, Primary: n.IsPrimary})
 		}
-		childResponses = append(childResponses, *childResponse)
+		return res
 	}
+	tree := build(root)
+	return &tree
+}
 
-	noteResponses := make([]dto.NoteResponse, 0, len(notes))
-	for _, note := range notes {
-		noteResponses = append(noteResponses, dto.NoteResponse{
-			ID:    note.ID,
-			Title: note.Title,
-		})
+// folderChild is a subfolder row of FolderChildren before it is sorted.
+type folderChild struct {
+	dto.FolderChild
+	SortKey   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// FolderChildren returns one level of folderID: its subfolders with how many
+// subfolders and notes each holds, and the notes listed in it, in the
+// folder's sort order.
+func (s *FolderService) FolderChildren(folderID string) (*dto.FolderChildren, error) {
+	var folder model.Folder
+	if err := s.DB.First(&folder, "id = ?", folderID).Error; err != nil {
+		return nil, err
+	}
+
+	var children []folderChild
+	if err := s.DB.Model(&model.Folder{}).
+		Select(`folders.id, folders.name, folders.parent_id, folders.sort_key, folders.created_at, folders.updated_at,
+			(SELECT COUNT(*) FROM folders AS sub WHERE sub.parent_id = folders.id) AS child_count,
+			(SELECT COUNT(*) FROM notes WHERE notes.folder_id = folders.id) +
+			(SELECT COUNT(*) FROM placements WHERE placements.folder_id = folders.id) AS note_count`).
+		Where("folders.parent_id = ?", folderID).
+		Scan(&children).Error; err != nil {
+		return nil, err
 	}
+	notes, err := s.folderNotes("= ?", folderID)
+	if err != nil {
+		return nil, err
+	}
+	sortEntries(children, func(c folderChild) sortEntry {
+		return sortEntry{ID: c.ID, Name: c.Name, SortKey: c.SortKey, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt}
+	}, folder.SortBy, folder.SortDesc)
+	sortEntries(notes, folderNote.sortEntry, folder.SortBy, folder.SortDesc)
 
-	return &dto.FolderResponse{
+	res := &dto.FolderChildren{
 		ID:       folder.ID,
 		Name:     folder.Name,
 		ParentID: folder.ParentID,
-		Children: childResponses,
-		Notes:    noteResponses,
-	}, nil
+		SortBy:   folder.SortBy,
+		SortDesc: folder.SortDesc,
+		Children: make([]dto.FolderChild, 0, len(children)),
+		Notes:    make([]dto.NoteResponse, 0, len(notes)),
+	}
+	for _, c := range children {
+		res.Children = append(res.Children, c.FolderChild)
+	}
+	for _, n := range notes {
+		res.Notes = append(res.Notes, dto.NoteResponse{ID: n.ID, Title: n.Title, Primary: n.IsPrimary})
+	}
+	return res, nil
 }
 
 // TODO: delete folder, all children folders, notes in folder, and blocks associated with notes
@@ -123,9 +247,12 @@ func (s *FolderService) DeleteFolderAndContents(folderID string) error {
 	})
 }
 
+// deleteFolderRecursive deletes the deepest folders first, so a note whose
+// primary folder goes moves to a placement that still exists, or is deleted
+// when none is left.
 func deleteFolderRecursive(db *gorm.DB, folderID string, service *NoteService) error {
 	var folder model.Folder
-	if err := db.Preload("ChildrenFolders").Preload("Notes").First(&folder, "id = ?", folderID).Error; err != nil {
+	if err := db.Preload("ChildrenFolders").First(&folder, "id = ?", folderID).Error; err != nil {
 		return err
 	}
 
@@ -136,10 +263,32 @@ func deleteFolderRecursive(db *gorm.DB, folderID string, service *NoteService) e
 		}
 	}
 
-	// Delete notes in this folder
-	for _, note := range folder.Notes {
-		if err := service.DeleteNoteTx(db, note.ID); err != nil {
+	// Notes are loaded after the children, which may have moved notes here
+	var notes []model.Note
+	if err := db.Where("folder_id = ?", folderID).Find(&notes).Error; err != nil {
+		return err
+	}
+	if err := db.Where("folder_id = ?", folderID).Delete(&model.Placement{}).Error; err != nil {
+		return err
+	}
+	for _, note := range notes {
+		var next model.Placement
+		err := db.Where("note_id = ?", note.ID).Order("created_at, folder_id").First(&next).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if err := service.DeleteNoteTx(db, note.ID); err != nil {
+				return err
+			}
+		case err != nil:
 			return err
+		default:
+			if err := db.Model(&model.Note{}).Where("id = ?", note.ID).
+				Updates(map[string]any{"folder_id": next.FolderID, "sort_key": next.SortKey}).Error; err != nil {
+				return err
+			}
+			if err := db.Delete(&next).Error; err != nil {
+				return err
+			}
 		}
 	}
 
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"cmp"
+	"errors"
+	"server/internal/model"
+	"server/internal/order"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotInFolder is returned by Reorder when an ID is not a child or note of
+// the folder, or when a child or note is left out.
+var ErrNotInFolder = errors.New("reorder must list every child or note of the folder exactly once")
+
+// sortEntry is what a folder's sort settings order its children and notes by.
+type sortEntry struct {
+	ID        string
+	Name      string
+	SortKey   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func folderSortEntry(f model.Folder) sortEntry {
+	return sortEntry{ID: f.ID, Name: f.Name, SortKey: f.SortKey, CreatedAt: f.CreatedAt, UpdatedAt: f.UpdatedAt}
+}
+
+// sortEntries orders items by a folder's sort settings. Manual order puts
+// items with a sort key first, by key, and the rest after them oldest first,
+// so new items show up at the end until they are dragged somewhere.
+func sortEntries[T any](items []T, entry func(T) sortEntry, by string, desc bool) {
+	slices.SortStableFunc(items, func(x, y T) int {
+		a, b := entry(x), entry(y)
+		if by == model.SortByManual {
+			if (a.SortKey == "") != (b.SortKey == "") {
+				if a.SortKey == "" {
+					return 1
+				}
+				return -1
+			}
+			return cmp.Or(cmp.Compare(a.SortKey, b.SortKey), a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+		}
+
+		var c int
+		switch by {
+		case model.SortByCreated:
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		case model.SortByUpdated:
+			c = a.UpdatedAt.Compare(b.UpdatedAt)
+		}
+		c = cmp.Or(c, naturalCompare(a.Name, b.Name), cmp.Compare(a.ID, b.ID))
+		if desc {
+			return -c
+		}
+		return c
+	})
+}
+
+// SetSort changes how a folder orders its contents.
+func (s *FolderService) SetSort(id, by string, desc bool) (*model.Folder, error) {
+	var folder model.Folder
+	if err := s.DB.First(&folder, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	folder.SortBy, folder.SortDesc = by, desc
+	if err := s.DB.Model(&folder).Select("sort_by", "sort_desc").Updates(&folder).Error; err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// Reorder switches a folder to manual order and keys its children and notes
+// in the order given, all in one transaction. Either list may be nil to keep
+// that kind's current order; a non-nil list must name all of them.
+func (s *FolderService) Reorder(id string, folderIDs, noteIDs []string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if folderIDs != nil {
+			var current []string
+			if err := tx.Model(&model.Folder{}).Where("parent_id = ?", id).Pluck("id", &current).Error; err != nil {
+				return err
+			}
+			if !samePermutation(current, folderIDs) {
+				return ErrNotInFolder
+			}
+			keys, err := order.KeysBetween("", "", len(folderIDs))
+			if err != nil {
+				return err
+			}
+			for i, childID := range folderIDs {
+				if err := tx.Model(&model.Folder{}).Where("id = ?", childID).UpdateColumn("sort_key", keys[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		if noteIDs != nil {
+			var own, placed []string
+			if err := tx.Model(&model.Note{}).Where("folder_id = ?", id).Pluck("id", &own).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&model.Placement{}).Where("folder_id = ?", id).Pluck("note_id", &placed).Error; err != nil {
+				return err
+			}
+			if !samePermutation(append(own, placed...), noteIDs) {
+				return ErrNotInFolder
+			}
+			keys, err := order.KeysBetween("", "", len(noteIDs))
+			if err != nil {
+				return err
+			}
+			for i, noteID := range noteIDs {
+				// keys only change order, so they leave updated_at alone
+				q := tx.Model(&model.Note{}).Where("id = ?", noteID)
+				if slices.Contains(placed, noteID) {
+					q = tx.Model(&model.Placement{}).Where("note_id = ? AND folder_id = ?", noteID, id)
+				}
+				if err := q.UpdateColumn("sort_key", keys[i]).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		return tx.Model(&model.Folder{}).Where("id = ?", id).UpdateColumn("sort_by", model.SortByManual).Error
+	})
+}
+
+func samePointer(a, b *string) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// samePermutation reports whether ids lists exactly the IDs in current.
+func samePermutation(current, ids []string) bool {
+	if len(current) != len(ids) {
+		return false
+	}
+	seen := make(map[string]bool, len(current))
+	for _, id := range current {
+		seen[id] = true
+	}
+	for _, id := range ids {
+		if !seen[id] {
+			return false
+		}
+		delete(seen, id)
+	}
+	return true
+}
+
+// naturalCompare compares names the way people read them: without regard to case,
+// and with runs of digits compared by value, so "Note 2" sorts before
+// "Note 10". Names equal under those rules fall back to a byte comparison,
+// which keeps the order total.
+func naturalCompare(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			si, sj := i, j
+			for i < len(a) && isDigit(a[i]) {
+				i++
+			}
+			for j < len(b) && isDigit(b[j]) {
+				j++
+			}
+			na, nb := strings.TrimLeft(a[si:i], "0"), strings.TrimLeft(b[sj:j], "0")
+			if len(na) != len(nb) {
+				return cmp.Compare(len(na), len(nb))
+			}
+			if c := strings.Compare(na, nb); c != 0 {
+				return c
+			}
+			continue
+		}
+		ra, wa := utf8.DecodeRuneInString(a[i:])
+		rb, wb := utf8.DecodeRuneInString(b[j:])
+		if c := cmp.Compare(unicode.ToLower(ra), unicode.ToLower(rb)); c != 0 {
+			return c
+		}
+		i, j = i+wa, j+wb
+	}
+	if c := cmp.Compare(len(a)-i, len(b)-j); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
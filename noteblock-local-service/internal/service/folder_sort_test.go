@@ -0,0 +1,20 @@
+package service
+
+import "testing"
+
+func TestNaturalCompare(t *testing.T) {
+	sorted := []string{"a", "Note 1", "note 2", "Note 02b", "Note 10", "Note 10a", "note10", "Zeta", "zeta"}
+	for i := range sorted {
+		for j := range sorted {
+			got, want := naturalCompare(sorted[i], sorted[j]), 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got != want {
+				t.Fatalf("naturalCompare(%q, %q) = %d, want %d", sorted[i], sorted[j], got, want)
+			}
+		}
+	}
+}
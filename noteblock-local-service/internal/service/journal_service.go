@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+	"server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type JournalService struct{ DB *gorm.DB }
+
+// Get returns the note recorded for date, or nil when there is none. An entry
+// whose note has since been deleted is dropped and reported as missing.
+func (s *JournalService) Get(date string) (*model.Note, error) {
+	var entry model.DailyNote
+	err := s.DB.First(&entry, "date = ?", date).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var note model.Note
+	err = s.DB.First(&note, "id = ?", entry.NoteID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, s.DB.Delete(&entry).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &note, nil
+}
+
+// Set records noteID as the entry for date, replacing any earlier entry.
+func (s *JournalService) Set(date, noteID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("date = ? OR note_id = ?", date, noteID).Delete(&model.DailyNote{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.DailyNote{Date: date, NoteID: noteID}).Error
+	})
+}
+
+// DailyNoteEntry is a recorded day joined with its note's title.
+type DailyNoteEntry struct {
+	Date   string
+	NoteID string
+	Title  string
+}
+
+// Between lists the entries from first to last inclusive, by date.
+func (s *JournalService) Between(first, last string) ([]DailyNoteEntry, error) {
+	var entries []DailyNoteEntry
+	err := s.DB.Model(&model.DailyNote{}).
+		Select("daily_notes.date, daily_notes.note_id, notes.title").
+		Joins("JOIN notes ON notes.id = daily_notes.note_id").
+		Where("daily_notes.date BETWEEN ? AND ?", first, last).
+		Order("daily_notes.date").
+		Scan(&entries).Error
+	return entries, err
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"server/internal/model"
+	"slices"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LinkService struct{ DB *gorm.DB }
+
+// Backlink is a link into a note, with the title of the note it is written in.
+type Backlink struct {
+	SourceNoteID  string
+	SourceTitle   string
+	SourceBlockID string
+	Target        string
+	Label         string
+}
+
+// OutgoingLink is a link out of a note, with the title of the note it
+// resolves to, if any.
+type OutgoingLink struct {
+	SourceBlockID string
+	Target        string
+	Label         string
+	TargetNoteID  *string
+	TargetTitle   *string
+}
+
+// LinkPair counts the resolved links from one note to another.
+type LinkPair struct {
+	SourceNoteID string
+	TargetNoteID string
+	Count        int
+}
+
+// UnresolvedTarget is link text no note matches, with the notes that use it.
+type UnresolvedTarget struct {
+	Target  string
+	NoteIDs []string
+}
+
+// ReplaceNoteLinks swaps every link written in noteID for links.
+func (s *LinkService) ReplaceNoteLinks(noteID string, links []model.Link) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("source_note_id = ?", noteID).Delete(&model.Link{}).Error; err != nil {
+			return err
+		}
+		if len(links) == 0 {
+			return nil
+		}
+		return tx.Create(&links).Error
+	})
+}
+
+// ResolveTitle finds the note a title link points at. Titles match without
+// regard to case; when several notes share the title, one in preferFolderID
+// wins, then the oldest.
+func (s *LinkService) ResolveTitle(title, preferFolderID string) (*string, error) {
+	var notes []model.Note
+	err := s.DB.Where("LOWER(title) = LOWER(?)", title).
+		// one clause: gorm drops an order expression that another Order follows
+		Order(clause.OrderBy{Expression: gorm.Expr("folder_id = ? DESC, created_at, id", preferFolderID)}).
+		Limit(1).
+		Find(&notes).Error
+	if err != nil || len(notes) == 0 {
+		return nil, err
+	}
+	return &notes[0].ID, nil
+}
+
+// AttachUnresolved points the unresolved title links matching title at noteID.
+func (s *LinkService) AttachUnresolved(title, noteID string) error {
+	return s.DB.Model(&model.Link{}).
+		Where("target_note_id IS NULL AND by_id = ? AND LOWER(target) = LOWER(?)", false, title).
+		Update("target_note_id", noteID).Error
+}
+
+// TitleLinksTo lists the links that reach noteID by its title.
+func (s *LinkService) TitleLinksTo(noteID string) ([]model.Link, error) {
+	var links []model.Link
+	err := s.DB.Where("target_note_id = ? AND by_id = ?", noteID, false).Find(&links).Error
+	return links, err
+}
+
+// Backlinks lists the links into noteID, grouped by source note.
+func (s *LinkService) Backlinks(noteID string) ([]Backlink, error) {
+	var links []Backlink
+	err := s.DB.Model(&model.Link{}).
+		Select("links.source_note_id, notes.title AS source_title, links.source_block_id, links.target, links.label").
+		Joins("JOIN notes ON notes.id = links.source_note_id").
+		Where("links.target_note_id = ?", noteID).
+		Order("notes.title, links.source_note_id, links.id").
+		Scan(&links).Error
+	return links, err
+}
+
+// Outgoing lists the links written in noteID in the order they were parsed.
+func (s *LinkService) Outgoing(noteID string) ([]OutgoingLink, error) {
+	var links []OutgoingLink
+	err := s.DB.Model(&model.Link{}).
+		Select("links.source_block_id, links.target, links.label, notes.id AS target_note_id, notes.title AS target_title").
+		Joins("LEFT JOIN notes ON notes.id = links.target_note_id").
+		Where("links.source_note_id = ?", noteID).
+		Order("links.id").
+		Scan(&links).Error
+	return links, err
+}
+
+// ResolvedPairs lists every pair of notes joined by at least one link.
+func (s *LinkService) ResolvedPairs() ([]LinkPair, error) {
+	var pairs []LinkPair
+	err := s.DB.Model(&model.Link{}).
+		Select("source_note_id, target_note_id, COUNT(*) AS count").
+		Where("target_note_id IS NOT NULL").
+		Group("source_note_id, target_note_id").
+		Order("source_note_id, target_note_id").
+		Scan(&pairs).Error
+	return pairs, err
+}
+
+// Unresolved lists every link target no note matches, most used first.
+func (s *LinkService) Unresolved() ([]UnresolvedTarget, error) {
+	var links []model.Link
+	if err := s.DB.Where("target_note_id IS NULL").Order("target, source_note_id").Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	// titles match without regard to case, so neither do the groups
+	var targets []UnresolvedTarget
+	index := map[string]int{}
+	for _, l := range links {
+		key := strings.ToLower(l.Target)
+		i, ok := index[key]
+		if !ok {
+			i = len(targets)
+			index[key] = i
+			targets = append(targets, UnresolvedTarget{Target: l.Target})
+		}
+		if !slices.Contains(targets[i].NoteIDs, l.SourceNoteID) {
+			targets[i].NoteIDs = append(targets[i].NoteIDs, l.SourceNoteID)
+		}
+	}
+	sort.SliceStable(targets, func(i, j int) bool { return len(targets[i].NoteIDs) > len(targets[j].NoteIDs) })
+	return targets, nil
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"server/internal/model"
 )
 
@@ -17,11 +18,16 @@ func (s *NoteService) NewNote(title string, folderID string) (*model.Note, error
 
 func (s *NoteService) GetNote(id string) (*model.Note, error) {
 	note := &model.Note{}
-	err := s.DB.Preload("Blocks").First(note, "id = ?", id).Error
+	err := s.DB.Preload("Blocks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("order_key, id")
+	}).First(note, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 
+	for i := range note.Blocks {
+		note.Blocks[i].Index = i
+	}
 	return note, nil
 }
 
@@ -48,6 +54,64 @@ func (s *NoteService) ListNotesByFolderId(folderId *string) ([]model.Note, error
 	return notes, err
 }
 
+// PlacedNotes lists the notes placed in folderID besides their primary folder.
+func (s *NoteService) PlacedNotes(folderID string) ([]model.Note, error) {
+	var notes []model.Note
+	err := s.DB.Where("id IN (?)", s.DB.Model(&model.Placement{}).Select("note_id").Where("folder_id = ?", folderID)).
+		Find(&notes).Error
+	return notes, err
+}
+
+// Placements lists the folders a note is placed in besides its primary
+// folder, oldest first.
+func (s *NoteService) Placements(noteID string) ([]model.Placement, error) {
+	var placements []model.Placement
+	err := s.DB.Preload("Folder").Where("note_id = ?", noteID).Order("created_at, folder_id").Find(&placements).Error
+	return placements, err
+}
+
+// AllPlacements lists every placement in the vault.
+func (s *NoteService) AllPlacements() ([]model.Placement, error) {
+	var placements []model.Placement
+	err := s.DB.Order("created_at, folder_id").Find(&placements).Error
+	return placements, err
+}
+
+// Place adds folderID to a note's placements; placing it twice is a no-op.
+func (s *NoteService) Place(noteID, folderID string) error {
+	return s.DB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.Placement{NoteID: noteID, FolderID: folderID}).Error
+}
+
+// Unplace removes folderID from a note's placements and reports whether it
+// was one.
+func (s *NoteService) Unplace(noteID, folderID string) (bool, error) {
+	res := s.DB.Where("note_id = ? AND folder_id = ?", noteID, folderID).Delete(&model.Placement{})
+	return res.RowsAffected > 0, res.Error
+}
+
+// ListAll returns every note without its blocks.
+func (s *NoteService) ListAll() ([]model.Note, error) {
+	var notes []model.Note
+	err := s.DB.Order("title, id").Find(&notes).Error
+	return notes, err
+}
+
+// SearchNotes matches query case-insensitively against note titles and block contents.
+func (s *NoteService) SearchNotes(query string, limit int) ([]model.Note, error) {
+	var notes []model.Note
+	pattern := "%" + escapeLike(query) + "%"
+
+	err := s.DB.
+		Where("title LIKE ? ESCAPE '\\'", pattern).
+		Or("id IN (?)", s.DB.Model(&model.Block{}).Select("note_id").Where("content LIKE ? ESCAPE '\\'", pattern)).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&notes).Error
+
+	return notes, err
+}
+
 func (s *NoteService) UpdateNoteMetaData(id string, title string, folderId string) (*model.Note, error) {
 	var note model.Note
 	if err := s.DB.Transaction(func(tx *gorm.DB) error {
@@ -56,6 +120,10 @@ func (s *NoteService) UpdateNoteMetaData(id string, title string, folderId strin
 			return err
 		}
 
+		// a manual position only means something in the folder it was set in
+		if note.FolderID != folderId {
+			note.SortKey = ""
+		}
 		note.Title = title
 		note.FolderID = folderId
 
@@ -74,8 +142,20 @@ func (s *NoteService) UpdateNoteContents(id string, title string, md string, fol
 	return nil
 }
 
+// DeleteNoteTx removes a note with its blocks and everything keyed on it.
+// The rows are deleted explicitly rather than trusting ON DELETE CASCADE,
+// which SQLite only honours on connections that enabled foreign keys.
 func (s *NoteService) DeleteNoteTx(tx *gorm.DB, id string) error {
-	if err := tx.Where("note_id = ?", id).Delete(&model.Block{}).Error; err != nil {
+	for _, dependent := range []any{&model.Block{}, &model.NoteTag{}, &model.DailyNote{}, &model.Placement{}} {
+		if err := tx.Where("note_id = ?", id).Delete(dependent).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("source_note_id = ?", id).Delete(&model.Link{}).Error; err != nil {
+		return err
+	}
+	// links into the note stay in their text, so they become unresolved
+	if err := tx.Model(&model.Link{}).Where("target_note_id = ?", id).Update("target_note_id", nil).Error; err != nil {
 		return err
 	}
 	return tx.Where("id = ?", id).Delete(&model.Note{}).Error
@@ -88,11 +168,31 @@ func (s *NoteService) DeleteNote(id string) error {
 		return err
 	}
 	err := s.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("note_id = ?", id).Delete(&model.Block{}).Error; err != nil {
-			return err
-		}
-		return tx.Delete(&note).Error
+		return s.DeleteNoteTx(tx, note.ID)
 	})
 
 	return err
 }
+
+// SetTemplate flags or unflags a note as a template without touching its
+// content or updated_at.
+func (s *NoteService) SetTemplate(id string, isTemplate bool) (*model.Note, error) {
+	var note model.Note
+	if err := s.DB.First(&note, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := s.DB.Model(&note).UpdateColumn("is_template", isTemplate).Error; err != nil {
+		return nil, err
+	}
+	note.IsTemplate = isTemplate
+	return &note, nil
+}
+
+// ListTemplates returns every template note with its blocks in order.
+func (s *NoteService) ListTemplates() ([]model.Note, error) {
+	var notes []model.Note
+	err := s.DB.Preload("Blocks", func(db *gorm.DB) *gorm.DB {
+		return db.Order("order_key, id")
+	}).Where("is_template = ?", true).Order("title, id").Find(&notes).Error
+	return notes, err
+}
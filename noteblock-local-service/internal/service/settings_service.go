@@ -0,0 +1,29 @@
+package service
+
+import (
+	"errors"
+	"server/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SettingsService struct{ DB *gorm.DB }
+
+// Get returns the value stored under key, or "" when it was never set.
+func (s *SettingsService) Get(key string) (string, error) {
+	var setting model.Setting
+	err := s.DB.First(&setting, "key = ?", key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return setting.Value, err
+}
+
+// Set stores value under key; an empty value removes the setting.
+func (s *SettingsService) Set(key, value string) error {
+	if value == "" {
+		return s.DB.Where("key = ?", key).Delete(&model.Setting{}).Error
+	}
+	return s.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&model.Setting{Key: key, Value: value}).Error
+}
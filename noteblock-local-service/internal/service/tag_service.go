@@ -0,0 +1,204 @@
+package service
+
+import (
+	"server/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagService struct{ DB *gorm.DB }
+
+// TagCount is a tag with the number of notes it is assigned to.
+type TagCount struct {
+	ID    string
+	Name  string
+	Count int
+}
+
+// NoteTagName is one of a note's tags with how it was assigned.
+type NoteTagName struct {
+	Name   string
+	Source string
+}
+
+// TaggedNote is a note with the names of all its tags.
+type TaggedNote struct {
+	ID       string
+	Title    string
+	FolderID string
+	Tags     []string
+}
+
+// TagAssignment is a note's tag by name.
+type TagAssignment struct {
+	NoteID string
+	Name   string
+}
+
+// GetByName returns the tag called name, or nil when there is none.
+func (s *TagService) GetByName(name string) (*model.Tag, error) {
+	var tags []model.Tag
+	if err := s.DB.Where("name = ?", name).Limit(1).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return &tags[0], nil
+}
+
+// Ensure returns the tags called names, creating the missing ones.
+func (s *TagService) Ensure(names []string) ([]model.Tag, error) {
+	tags := make([]model.Tag, 0, len(names))
+	for _, name := range names {
+		tag, err := s.GetByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if tag == nil {
+			tag = &model.Tag{Name: name}
+			if err := s.DB.Create(tag).Error; err != nil {
+				return nil, err
+			}
+		}
+		tags = append(tags, *tag)
+	}
+	return tags, nil
+}
+
+// NoteTags lists a note's tags by name.
+func (s *TagService) NoteTags(noteID string) ([]NoteTagName, error) {
+	var tags []NoteTagName
+	err := s.DB.Model(&model.NoteTag{}).
+		Select("tags.name, note_tags.source").
+		Joins("JOIN tags ON tags.id = note_tags.tag_id").
+		Where("note_tags.note_id = ?", noteID).
+		Order("tags.name").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// Assignments lists every tag assignment in the vault.
+func (s *TagService) Assignments() ([]TagAssignment, error) {
+	var assignments []TagAssignment
+	err := s.DB.Model(&model.NoteTag{}).
+		Select("note_tags.note_id, tags.name").
+		Joins("JOIN tags ON tags.id = note_tags.tag_id").
+		Order("tags.name, note_tags.note_id").
+		Scan(&assignments).Error
+	return assignments, err
+}
+
+// Assign gives noteID each of tagIDs from source. An existing assignment is
+// upgraded to manual when source is manual and otherwise left alone.
+func (s *TagService) Assign(noteID string, tagIDs []string, source string) error {
+	for _, tagID := range tagIDs {
+		onConflict := clause.OnConflict{DoNothing: true}
+		if source == model.TagSourceManual {
+			onConflict = clause.OnConflict{DoUpdates: clause.Assignments(map[string]any{"source": source})}
+		}
+		if err := s.DB.Clauses(onConflict).Create(&model.NoteTag{NoteID: noteID, TagID: tagID, Source: source}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Unassign removes noteID's assignments from source, sparing keepTagIDs.
+// An empty keepTagIDs removes them all.
+func (s *TagService) Unassign(noteID, source string, keepTagIDs []string) error {
+	q := s.DB.Where("note_id = ? AND source = ?", noteID, source)
+	if len(keepTagIDs) > 0 {
+		q = q.Where("tag_id NOT IN ?", keepTagIDs)
+	}
+	return q.Delete(&model.NoteTag{}).Error
+}
+
+// UnassignTags removes noteID's assignments of tagIDs from source.
+func (s *TagService) UnassignTags(noteID, source string, tagIDs []string) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	return s.DB.Where("note_id = ? AND source = ? AND tag_id IN ?", noteID, source, tagIDs).
+		Delete(&model.NoteTag{}).Error
+}
+
+// ClearSource removes every assignment from source across the vault.
+func (s *TagService) ClearSource(source string) error {
+	return s.DB.Where("source = ?", source).Delete(&model.NoteTag{}).Error
+}
+
+// NotesWithTag lists the notes that have tagID from source.
+func (s *TagService) NotesWithTag(tagID, source string) ([]string, error) {
+	var noteIDs []string
+	err := s.DB.Model(&model.NoteTag{}).Where("tag_id = ? AND source = ?", tagID, source).Pluck("note_id", &noteIDs).Error
+	return noteIDs, err
+}
+
+// List returns every tag that is assigned to at least one note, with counts.
+func (s *TagService) List() ([]TagCount, error) {
+	var tags []TagCount
+	err := s.DB.Model(&model.Tag{}).
+		Select("tags.id, tags.name, COUNT(notes.id) AS count").
+		Joins("JOIN note_tags ON note_tags.tag_id = tags.id").
+		Joins("JOIN notes ON notes.id = note_tags.note_id").
+		Group("tags.id, tags.name").
+		Order("tags.name").
+		Scan(&tags).Error
+	return tags, err
+}
+
+// Rename renames a tag in place, so every assignment follows it.
+func (s *TagService) Rename(tagID, name string) error {
+	return s.DB.Model(&model.Tag{}).Where("id = ?", tagID).Update("name", name).Error
+}
+
+// Merge moves every assignment of sourceID onto targetID and deletes the
+// source tag. A note that ends up with both keeps the stronger source.
+func (s *TagService) Merge(sourceID, targetID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var assignments []model.NoteTag
+		if err := tx.Where("tag_id = ?", sourceID).Find(&assignments).Error; err != nil {
+			return err
+		}
+		target := &TagService{DB: tx}
+		for _, a := range assignments {
+			if err := target.Assign(a.NoteID, []string{targetID}, a.Source); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("tag_id = ?", sourceID).Delete(&model.NoteTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", sourceID).Delete(&model.Tag{}).Error
+	})
+}
+
+// NotesByTags lists the notes tagged with all of tagIDs, or any of them when
+// all is false, most recently updated first.
+func (s *TagService) NotesByTags(tagIDs []string, all bool, limit int) ([]TaggedNote, error) {
+	matching := s.DB.Model(&model.NoteTag{}).Select("note_id").Where("tag_id IN ?", tagIDs).Group("note_id")
+	if all {
+		matching = matching.Having("COUNT(DISTINCT tag_id) = ?", len(tagIDs))
+	}
+
+	var notes []model.Note
+	if err := s.DB.Where("id IN (?)", matching).Order("updated_at DESC, id").Limit(limit).Find(&notes).Error; err != nil {
+		return nil, err
+	}
+
+	tagged := make([]TaggedNote, 0, len(notes))
+	for _, n := range notes {
+		tags, err := s.NoteTags(n.ID)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(tags))
+		for _, t := range tags {
+			names = append(names, t.Name)
+		}
+		tagged = append(tagged, TaggedNote{ID: n.ID, Title: n.Title, FolderID: n.FolderID, Tags: names})
+	}
+	return tagged, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 )
 
 func EncodeJsonToString(rawMessage *json.RawMessage) (string, error) {
@@ -19,3 +20,10 @@ func DecodeStringToJsonObject(data string) (*json.RawMessage, error) {
 	}
 	return &rawMessage, nil
 }
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// escapeLike makes user input safe to embed in a LIKE pattern using ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
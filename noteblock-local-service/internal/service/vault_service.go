@@ -0,0 +1,124 @@
+package service
+
+import (
+	"server/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// VaultService finds the damage older binaries or a crash may have left in a
+// vault: rows pointing at missing parents, folders cut off from root and
+// block content that is not JSON. Foreign keys prevent most of it today, but
+// SQLite only enforces them on connections that enabled them.
+type VaultService struct{ DB *gorm.DB }
+
+// IntegrityCheck runs SQLite's own check and returns its problems, none when
+// the file is sound.
+func (s *VaultService) IntegrityCheck() ([]string, error) {
+	var rows []string
+	if err := s.DB.Raw("PRAGMA integrity_check").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	if len(rows) == 1 && rows[0] == "ok" {
+		return nil, nil
+	}
+	return rows, nil
+}
+
+// OrphanBlocks lists blocks whose note is gone.
+func (s *VaultService) OrphanBlocks() ([]model.Block, error) {
+	var blocks []model.Block
+	err := s.DB.Where("note_id NOT IN (?)", s.DB.Model(&model.Note{}).Select("id")).
+		Order("note_id, order_key, id").
+		Find(&blocks).Error
+	return blocks, err
+}
+
+// AdoptBlocks hands every block of the missing note fromNoteID to toNoteID,
+// keeping their order keys and so their order.
+func (s *VaultService) AdoptBlocks(fromNoteID, toNoteID string) error {
+	return s.DB.Model(&model.Block{}).Where("note_id = ?", fromNoteID).Update("note_id", toNoteID).Error
+}
+
+// OrphanNotes lists notes whose folder is gone.
+func (s *VaultService) OrphanNotes() ([]model.Note, error) {
+	var notes []model.Note
+	err := s.DB.Where("folder_id NOT IN (?)", s.DB.Model(&model.Folder{}).Select("id")).
+		Order("title, id").
+		Find(&notes).Error
+	return notes, err
+}
+
+// OrphanFolders lists folders other than root whose parent is missing or gone.
+func (s *VaultService) OrphanFolders() ([]model.Folder, error) {
+	var folders []model.Folder
+	err := s.DB.Where("id <> ?", "root").
+		Where("parent_id IS NULL OR parent_id NOT IN (?)", s.DB.Model(&model.Folder{}).Select("id")).
+		Order("name, id").
+		Find(&folders).Error
+	return folders, err
+}
+
+// UnreachableFolders lists folders that root's subtree does not contain.
+// Once orphans are ruled out, those are folders in or below a parent cycle.
+func (s *VaultService) UnreachableFolders() ([]model.Folder, error) {
+	var folders []model.Folder
+	err := s.DB.Where("id NOT IN (?)", gorm.Expr(subtreeSQL, "root")).
+		Order("id").
+		Find(&folders).Error
+	return folders, err
+}
+
+// DanglingRow is a row keyed on a note or folder that no longer exists.
+type DanglingRow struct {
+	Table string
+	Key   string
+}
+
+// dangling describes, per derived table, which rows point at a missing note
+// or folder and how to name such a row in a report.
+var dangling = []struct{ table, key, where string }{
+	{"note_tags", "note_id || '/' || tag_id", "note_id NOT IN (SELECT id FROM notes) OR tag_id NOT IN (SELECT id FROM tags)"},
+	{"daily_notes", "date", "note_id NOT IN (SELECT id FROM notes)"},
+	{"placements", "note_id || '/' || folder_id", "note_id NOT IN (SELECT id FROM notes) OR folder_id NOT IN (SELECT id FROM folders)"},
+	{"links", "CAST(id AS TEXT)", "source_note_id NOT IN (SELECT id FROM notes)"},
+}
+
+// DanglingRows lists the derived rows that point at missing notes or folders.
+func (s *VaultService) DanglingRows() ([]DanglingRow, error) {
+	var rows []DanglingRow
+	for _, d := range dangling {
+		var keys []string
+		if err := s.DB.Raw("SELECT " + d.key + " FROM " + d.table + " WHERE " + d.where).Scan(&keys).Error; err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			rows = append(rows, DanglingRow{Table: d.table, Key: key})
+		}
+	}
+	return rows, nil
+}
+
+// DeleteDanglingRows deletes what DanglingRows finds.
+func (s *VaultService) DeleteDanglingRows() error {
+	for _, d := range dangling {
+		if err := s.DB.Exec("DELETE FROM " + d.table + " WHERE " + d.where).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachBlock calls fn with every block, a batch at a time, so checking content
+// never holds the whole vault in memory.
+func (s *VaultService) EachBlock(fn func(model.Block) error) error {
+	var batch []model.Block
+	return s.DB.Order("id").FindInBatches(&batch, 500, func(*gorm.DB, int) error {
+		for _, b := range batch {
+			if err := fn(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,233 @@
+// Package client is a Go client for the noteblock local IPC protocol. It works
+// over any io.ReadWriter, such as the stdio pipes of a spawned noteblock-server
+// or a connection to its unix socket, and multiplexes concurrent calls by ID.
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultTimeout bounds calls whose context has no deadline, matching Electron.
+const DefaultTimeout = 15 * time.Second
+
+// ErrClosed is returned by calls made after the connection has gone away.
+var ErrClosed = errors.New("noteblock client: connection closed")
+
+// Error is an error reported by the server.
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("noteblock %s: %s", e.Code, e.Message)
+}
+
+// Event is a notification pushed by the server, e.g. event.changed.
+type Event struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type request struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params,omitempty"`
+}
+
+// message is anything the server writes: a response carries an ID, a
+// notification carries a method instead.
+type message struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type Client struct {
+	// Timeout applies to calls whose context has no deadline. Zero means DefaultTimeout.
+	Timeout time.Duration
+
+	w      io.Writer
+	closer io.Closer
+	nextID atomic.Uint64
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan message
+	subs    map[string]map[chan Event]struct{}
+	err     error
+	done    chan struct{}
+}
+
+// New starts reading responses from rw. If rw is also an io.Closer, Close closes it.
+func New(rw io.ReadWriter) *Client {
+	c := &Client{
+		w:       rw,
+		pending: map[string]chan message{},
+		subs:    map[string]map[chan Event]struct{}{},
+		done:    make(chan struct{}),
+	}
+	if closer, ok := rw.(io.Closer); ok {
+		c.closer = closer
+	}
+	go c.readLoop(rw)
+	return c
+}
+
+// Close shuts the connection down and fails any calls still in flight.
+func (c *Client) Close() error {
+	if c.closer != nil {
+		return c.closer.Close()
+	}
+	c.shutdown(ErrClosed)
+	return nil
+}
+
+// Done is closed once the connection is gone.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Call sends method with params and decodes the result into out, which may be nil.
+func (c *Client) Call(ctx context.Context, method string, params, out any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		timeout := c.Timeout
+		if timeout == 0 {
+			timeout = DefaultTimeout
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	id := strconv.FormatUint(c.nextID.Add(1), 10)
+	ch := make(chan message, 1)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	line, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	_, err = c.w.Write(append(line, '\n'))
+	c.writeMu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if out == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, out)
+	case <-ctx.Done():
+		return fmt.Errorf("noteblock %s: %w", method, ctx.Err())
+	case <-c.done:
+		return c.err
+	}
+}
+
+// Subscribe delivers notifications named method until cancel is called or the
+// connection closes. Slow subscribers miss events rather than stall the reader.
+func (c *Client) Subscribe(method string) (events <-chan Event, cancel func()) {
+	ch := make(chan Event, 64)
+
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		close(ch)
+		return ch, func() {}
+	}
+	if c.subs[method] == nil {
+		c.subs[method] = map[chan Event]struct{}{}
+	}
+	c.subs[method][ch] = struct{}{}
+	c.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			if _, ok := c.subs[method][ch]; ok {
+				delete(c.subs[method], ch)
+				close(ch)
+			}
+		})
+	}
+}
+
+func (c *Client) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 20*1024*1024)
+
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+
+		c.mu.Lock()
+		if msg.ID == "" && msg.Method != "" {
+			for ch := range c.subs[msg.Method] {
+				select {
+				case ch <- Event{Method: msg.Method, Params: msg.Params}:
+				default:
+				}
+			}
+		} else if ch, ok := c.pending[msg.ID]; ok {
+			ch <- msg
+		}
+		c.mu.Unlock()
+	}
+
+	err := scanner.Err()
+	if err == nil {
+		err = ErrClosed
+	}
+	c.shutdown(err)
+}
+
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+	for _, subs := range c.subs {
+		for ch := range subs {
+			close(ch)
+		}
+	}
+	c.subs = map[string]map[chan Event]struct{}{}
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"server/internal/command"
+	localdb "server/internal/db"
+	"server/internal/ipc"
+)
+
+type pipeConn struct {
+	io.Reader
+	io.Writer
+	closers []io.Closer
+}
+
+func (p *pipeConn) Close() error {
+	for _, c := range p.closers {
+		_ = c.Close()
+	}
+	return nil
+}
+
+func setupTestClient(t *testing.T) *Client {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tmpDir, "client_test.sqlite")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open test sqlite db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql db handle: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = sqlDB.Close()
+	})
+	if err := localdb.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate test schema: %v", err)
+	}
+	t.Setenv("NOTE_DB_PATH", tmpDir)
+
+	toServer, clientOut := io.Pipe()
+	clientIn, fromServer := io.Pipe()
+	srv := ipc.NewServer(command.New(db))
+	go func() {
+		_ = srv.Run(toServer, fromServer)
+		_ = fromServer.Close()
+	}()
+
+	c := New(&pipeConn{Reader: clientIn, Writer: clientOut, closers: []io.Closer{clientOut, clientIn}})
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func TestClient_TypedCRUD(t *testing.T) {
+	c := setupTestClient(t)
+	ctx := context.Background()
+
+	info, err := c.Initialize(ctx, "client-test", "")
+	if err != nil {
+		t.Fatalf("initialize failed: %v", err)
+	}
+	if info.ProtocolVersion != ProtocolVersion {
+		t.Fatalf("expected protocol %s, got %s", ProtocolVersion, info.ProtocolVersion)
+	}
+
+	folder, err := c.CreateFolder(ctx, "Projects", "")
+	if err != nil {
+		t.Fatalf("create folder failed: %v", err)
+	}
+	note, err := c.CreateNote(ctx, "Plan", folder.ID)
+	if err != nil {
+		t.Fatalf("create note failed: %v", err)
+	}
+	block, err := c.CreateBlock(ctx, note.ID, "text", 0, map[string]string{"text": "hello"})
+	if err != nil {
+		t.Fatalf("create block failed: %v", err)
+	}
+	if _, err := c.UpdateBlock(ctx, note.ID, block.ID, "text", map[string]string{"text": "world"}); err != nil {
+		t.Fatalf("update block failed: %v", err)
+	}
+
+	got, err := c.GetNote(ctx, note.ID)
+	if err != nil {
+		t.Fatalf("get note failed: %v", err)
+	}
+	if len(got.Blocks) != 1 || string(got.Blocks[0].Content) != `{"text":"world"}` {
+		t.Fatalf("unexpected note blocks: %+v", got.Blocks)
+	}
+
+	tree, err := c.GetFolderTree(ctx, "root")
+	if err != nil {
+		t.Fatalf("get folder tree failed: %v", err)
+	}
+	if len(tree.Children) != 1 || len(tree.Children[0].Notes) != 1 {
+		t.Fatalf("unexpected folder tree: %+v", tree)
+	}
+
+	_, err = c.GetNote(ctx, "missing")
+	var rpcErr *Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != "NOT_FOUND" {
+		t.Fatalf("expected NOT_FOUND error, got %v", err)
+	}
+}
+
+func TestClient_ConcurrentCallsAndEvents(t *testing.T) {
+	c := setupTestClient(t)
+	ctx := context.Background()
+
+	events, cancel := c.Subscribe("event.changed")
+	defer cancel()
+
+	const n = 10
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := c.CreateNote(ctx, fmt.Sprintf("Concurrent %d", i), "")
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent create failed: %v", err)
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		select {
+		case <-events:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected %d change events, got %d", n, i)
+		}
+	}
+}
+
+func TestClient_CloseFailsPendingCalls(t *testing.T) {
+	c := setupTestClient(t)
+	_ = c.Close()
+
+	select {
+	case <-c.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client did not shut down after Close")
+	}
+	if _, err := c.GetNote(context.Background(), "x"); err == nil {
+		t.Fatal("expected call on closed client to fail")
+	}
+}
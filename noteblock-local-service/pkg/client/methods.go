@@ -0,0 +1,688 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+
+	"server/internal/model/dto"
+)
+
+// The read methods return the same dto types the server builds.
+type (
+	NoteDTO        = dto.NoteDTO
+	BlockDTO       = dto.BlockDTO
+	FolderResponse = dto.FolderResponse
+	NoteResponse   = dto.NoteResponse
+	AssetInfo      = dto.AssetInfo
+	AssetRef       = dto.AssetRef
+	AssetEntry     = dto.AssetEntry
+	ChangeDTO      = dto.ChangeDTO
+	TemplateDTO    = dto.TemplateDTO
+	JournalConfig  = dto.JournalConfig
+	JournalEntry   = dto.JournalEntryDTO
+	TagDTO         = dto.TagDTO
+	NoteTagDTO     = dto.NoteTagDTO
+	TaggedNoteDTO  = dto.TaggedNoteDTO
+	TagConfig      = dto.TagConfig
+	BacklinkDTO    = dto.BacklinkDTO
+	LinkDTO        = dto.LinkDTO
+	UnresolvedLink = dto.UnresolvedLinkDTO
+	Graph          = dto.Graph
+	GraphNode      = dto.GraphNode
+	GraphEdge      = dto.GraphEdge
+	EmbedDTO       = dto.EmbedDTO
+	BlockReference = dto.BlockReferenceDTO
+	Placement      = dto.PlacementDTO
+	FolderChildren = dto.FolderChildren
+	FolderChild    = dto.FolderChild
+	VaultCheck     = dto.VaultCheckDTO
+	VaultFinding   = dto.VaultFinding
+	Backup         = dto.BackupDTO
+	BackupConfig   = dto.BackupConfig
+	BackupRestore  = dto.BackupRestoreDTO
+)
+
+// ProtocolVersion is the protocol this client is written against.
+const ProtocolVersion = "1.17"
+
+type ServerInfo struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+}
+
+type InitializeResult struct {
+	ProtocolVersion string     `json:"protocol_version"`
+	Server          ServerInfo `json:"server"`
+	SchemaVersion   int        `json:"schema_version"`
+	Methods         []string   `json:"methods"`
+	Capabilities    []string   `json:"capabilities"`
+}
+
+type Folder struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentID *string `json:"parent_id"`
+}
+
+type Note struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	FolderID string `json:"folder_id"`
+}
+
+type Block struct {
+	ID       string `json:"id"`
+	NoteID   string `json:"note_id"`
+	Type     string `json:"type"`
+	Index    int    `json:"index"`
+	OrderKey string `json:"order_key"`
+}
+
+type BlockOrder struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+}
+
+// UpdateNoteParams leaves nil fields unchanged.
+type UpdateNoteParams struct {
+	ID       string        `json:"id"`
+	Title    *string       `json:"title,omitempty"`
+	FolderID *string       `json:"folder_id,omitempty"`
+	Blocks   *[]BlockOrder `json:"blocks,omitempty"`
+}
+
+// UpdateFolderParams leaves nil fields unchanged.
+type UpdateFolderParams struct {
+	CurrentID string  `json:"current_id"`
+	Name      *string `json:"name,omitempty"`
+	ParentID  *string `json:"parent_id,omitempty"`
+}
+
+type idParams struct {
+	ID string `json:"id"`
+}
+
+// Initialize negotiates the protocol version. token is only needed on the unix socket.
+func (c *Client) Initialize(ctx context.Context, clientName, token string) (*InitializeResult, error) {
+	var out InitializeResult
+	err := c.Call(ctx, "initialize", map[string]string{
+		"protocol_version": ProtocolVersion,
+		"client_name":      clientName,
+		"token":            token,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateFolder creates name under parentID, or under the root folder if parentID is empty.
+func (c *Client) CreateFolder(ctx context.Context, name, parentID string) (*Folder, error) {
+	params := map[string]any{"name": name}
+	if parentID != "" {
+		params["parent_id"] = parentID
+	}
+	var out Folder
+	if err := c.Call(ctx, "folder.create", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetFolderTree returns the folder with its notes and every descendant folder.
+func (c *Client) GetFolderTree(ctx context.Context, id string) (*FolderResponse, error) {
+	var out FolderResponse
+	if err := c.Call(ctx, "folder.get", idParams{ID: id}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FolderChildren returns one level of the folder tree with child and note
+// counts, for expanding folders lazily.
+func (c *Client) FolderChildren(ctx context.Context, id string) (*FolderChildren, error) {
+	var out FolderChildren
+	if err := c.Call(ctx, "folder.children", idParams{ID: id}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetFolderSort sets how a folder orders its contents: "name", "created",
+// "updated" or "manual". desc reverses all but manual order.
+func (c *Client) SetFolderSort(ctx context.Context, id, sortBy string, desc bool) (*FolderChildren, error) {
+	var out FolderChildren
+	if err := c.Call(ctx, "folder.sort", map[string]any{"id": id, "sort_by": sortBy, "sort_desc": desc}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReorderFolder puts a folder's children and notes in manual order. A nil
+// list keeps that kind's order; otherwise it must name all of them.
+func (c *Client) ReorderFolder(ctx context.Context, id string, folderIDs, noteIDs []string) (*FolderChildren, error) {
+	var out FolderChildren
+	err := c.Call(ctx, "folder.reorder", map[string]any{"id": id, "folder_ids": folderIDs, "note_ids": noteIDs}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// MoveItems moves folders and notes into targetID in one transaction. Moving
+// a folder into its own subtree, or moving root, fails with INVALID_MOVE.
+func (c *Client) MoveItems(ctx context.Context, targetID string, folderIDs, noteIDs []string) (*FolderChildren, error) {
+	var out FolderChildren
+	err := c.Call(ctx, "folder.move", map[string]any{"target_id": targetID, "folder_ids": folderIDs, "note_ids": noteIDs}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) UpdateFolder(ctx context.Context, params UpdateFolderParams) (*Folder, error) {
+	var out Folder
+	if err := c.Call(ctx, "folder.update", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DuplicateFolder deep-copies a folder into parentID, or next to the original
+// when parentID is empty, and returns the new subtree.
+func (c *Client) DuplicateFolder(ctx context.Context, id, parentID string) (*FolderResponse, error) {
+	params := map[string]any{"id": id}
+	if parentID != "" {
+		params["parent_id"] = parentID
+	}
+	var out FolderResponse
+	if err := c.Call(ctx, "folder.duplicate", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) DeleteFolder(ctx context.Context, id string) error {
+	return c.Call(ctx, "folder.delete", idParams{ID: id}, nil)
+}
+
+// CreateNote creates a note titled title in folderID, or in the root folder if folderID is empty.
+func (c *Client) CreateNote(ctx context.Context, title, folderID string) (*Note, error) {
+	params := map[string]any{"title": title}
+	if folderID != "" {
+		params["folder_id"] = folderID
+	}
+	var out Note
+	if err := c.Call(ctx, "note.create", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNote returns the note with its blocks in order.
+func (c *Client) GetNote(ctx context.Context, id string) (*NoteDTO, error) {
+	var out NoteDTO
+	if err := c.Call(ctx, "note.get", idParams{ID: id}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) UpdateNote(ctx context.Context, params UpdateNoteParams) (*Note, error) {
+	var out Note
+	if err := c.Call(ctx, "note.update", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DuplicateNote copies a note with its blocks into folderID, or next to the
+// original when folderID is empty.
+func (c *Client) DuplicateNote(ctx context.Context, id, folderID string) (*NoteDTO, error) {
+	params := map[string]any{"id": id}
+	if folderID != "" {
+		params["folder_id"] = folderID
+	}
+	var out NoteDTO
+	if err := c.Call(ctx, "note.duplicate", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetTemplate marks a note as a template or turns it back into a plain note.
+func (c *Client) SetTemplate(ctx context.Context, id string, isTemplate bool) error {
+	return c.Call(ctx, "template.set", map[string]any{"id": id, "is_template": isTemplate}, nil)
+}
+
+func (c *Client) ListTemplates(ctx context.Context) ([]TemplateDTO, error) {
+	var out struct {
+		Templates []TemplateDTO `json:"templates"`
+	}
+	if err := c.Call(ctx, "template.list", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Templates, nil
+}
+
+// NoteFromTemplateParams leaves empty fields to the server's defaults.
+type NoteFromTemplateParams struct {
+	TemplateID string            `json:"template_id"`
+	FolderID   string            `json:"folder_id,omitempty"`
+	Title      string            `json:"title,omitempty"`
+	Variables  map[string]string `json:"variables,omitempty"`
+}
+
+func (c *Client) CreateNoteFromTemplate(ctx context.Context, params NoteFromTemplateParams) (*NoteDTO, error) {
+	var out NoteDTO
+	if err := c.Call(ctx, "note.createFromTemplate", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// NotePlacements lists the folders a note appears in, its primary folder first.
+func (c *Client) NotePlacements(ctx context.Context, noteID string) ([]Placement, error) {
+	return c.placements(ctx, "note.placements", map[string]string{"id": noteID})
+}
+
+// PlaceNote lists a note in folderID as well; it keeps its primary folder.
+func (c *Client) PlaceNote(ctx context.Context, noteID, folderID string) ([]Placement, error) {
+	return c.placements(ctx, "note.place", map[string]string{"id": noteID, "folder_id": folderID})
+}
+
+// UnplaceNote removes a note from a folder it was placed in.
+func (c *Client) UnplaceNote(ctx context.Context, noteID, folderID string) ([]Placement, error) {
+	return c.placements(ctx, "note.unplace", map[string]string{"id": noteID, "folder_id": folderID})
+}
+
+func (c *Client) placements(ctx context.Context, method string, params map[string]string) ([]Placement, error) {
+	var out struct {
+		Placements []Placement `json:"placements"`
+	}
+	if err := c.Call(ctx, method, params, &out); err != nil {
+		return nil, err
+	}
+	return out.Placements, nil
+}
+
+func (c *Client) Backlinks(ctx context.Context, noteID string) ([]BacklinkDTO, error) {
+	var out struct {
+		Links []BacklinkDTO `json:"links"`
+	}
+	if err := c.Call(ctx, "note.backlinks", idParams{ID: noteID}, &out); err != nil {
+		return nil, err
+	}
+	return out.Links, nil
+}
+
+func (c *Client) OutgoingLinks(ctx context.Context, noteID string) ([]LinkDTO, error) {
+	var out struct {
+		Links []LinkDTO `json:"links"`
+	}
+	if err := c.Call(ctx, "note.outgoingLinks", idParams{ID: noteID}, &out); err != nil {
+		return nil, err
+	}
+	return out.Links, nil
+}
+
+// UnresolvedLinks lists link text no note matches; creating a note with that
+// title resolves the links.
+func (c *Client) UnresolvedLinks(ctx context.Context) ([]UnresolvedLink, error) {
+	var out struct {
+		Links []UnresolvedLink `json:"links"`
+	}
+	if err := c.Call(ctx, "link.unresolved", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Links, nil
+}
+
+// GraphParams selects the graph; the zero value is the whole vault.
+type GraphParams struct {
+	NoteID         string   `json:"note_id,omitempty"`
+	Depth          int      `json:"depth,omitempty"`
+	FolderID       string   `json:"folder_id,omitempty"`
+	Tags           []string `json:"tags,omitempty"`
+	HideOrphans    bool     `json:"hide_orphans,omitempty"`
+	IncludeTags    bool     `json:"include_tags,omitempty"`
+	IncludeFolders bool     `json:"include_folders,omitempty"`
+}
+
+func (c *Client) GetGraph(ctx context.Context, params GraphParams) (*Graph, error) {
+	var out Graph
+	if err := c.Call(ctx, "graph.get", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// SetNoteTags replaces a note's manual tags and returns all of its tags.
+func (c *Client) SetNoteTags(ctx context.Context, noteID string, tags []string) ([]NoteTagDTO, error) {
+	return c.noteTags(ctx, "note.tags.set", noteID, tags)
+}
+
+func (c *Client) AddNoteTags(ctx context.Context, noteID string, tags []string) ([]NoteTagDTO, error) {
+	return c.noteTags(ctx, "note.tags.add", noteID, tags)
+}
+
+func (c *Client) RemoveNoteTags(ctx context.Context, noteID string, tags []string) ([]NoteTagDTO, error) {
+	return c.noteTags(ctx, "note.tags.remove", noteID, tags)
+}
+
+func (c *Client) noteTags(ctx context.Context, method, noteID string, tags []string) ([]NoteTagDTO, error) {
+	var out struct {
+		Tags []NoteTagDTO `json:"tags"`
+	}
+	if err := c.Call(ctx, method, map[string]any{"id": noteID, "tags": tags}, &out); err != nil {
+		return nil, err
+	}
+	return out.Tags, nil
+}
+
+func (c *Client) ListTags(ctx context.Context) ([]TagDTO, error) {
+	var out struct {
+		Tags []TagDTO `json:"tags"`
+	}
+	if err := c.Call(ctx, "tag.list", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Tags, nil
+}
+
+func (c *Client) RenameTag(ctx context.Context, name, newName string) (*TagDTO, error) {
+	var out TagDTO
+	if err := c.Call(ctx, "tag.rename", map[string]any{"name": name, "new_name": newName}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) MergeTags(ctx context.Context, sources []string, target string) (*TagDTO, error) {
+	var out TagDTO
+	if err := c.Call(ctx, "tag.merge", map[string]any{"sources": sources, "target": target}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListNotesByTags matches notes with every tag, or with any of them when matchAny is set.
+func (c *Client) ListNotesByTags(ctx context.Context, tags []string, matchAny bool) ([]TaggedNoteDTO, error) {
+	mode := "all"
+	if matchAny {
+		mode = "any"
+	}
+	var out struct {
+		Notes []TaggedNoteDTO `json:"notes"`
+	}
+	if err := c.Call(ctx, "note.listByTags", map[string]any{"tags": tags, "mode": mode}, &out); err != nil {
+		return nil, err
+	}
+	return out.Notes, nil
+}
+
+func (c *Client) ConfigureTags(ctx context.Context, cfg TagConfig) (*TagConfig, error) {
+	var out TagConfig
+	if err := c.Call(ctx, "tag.configure", cfg, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JournalDay is a daily note and whether this call created it.
+type JournalDay struct {
+	Date    string  `json:"date"`
+	Created bool    `json:"created"`
+	Note    NoteDTO `json:"note"`
+}
+
+// JournalToday finds or creates today's note; timezone is an IANA name, or
+// empty for the server's local zone.
+func (c *Client) JournalToday(ctx context.Context, timezone string) (*JournalDay, error) {
+	params := map[string]any{}
+	if timezone != "" {
+		params["timezone"] = timezone
+	}
+	var out JournalDay
+	if err := c.Call(ctx, "journal.today", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JournalGet finds or creates the note for date, formatted 2006-01-02.
+func (c *Client) JournalGet(ctx context.Context, date string) (*JournalDay, error) {
+	var out JournalDay
+	if err := c.Call(ctx, "journal.get", map[string]any{"date": date}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// JournalRange lists the entries of month, formatted 2006-01.
+func (c *Client) JournalRange(ctx context.Context, month string) ([]JournalEntry, error) {
+	var out struct {
+		Entries []JournalEntry `json:"entries"`
+	}
+	if err := c.Call(ctx, "journal.range", map[string]any{"month": month}, &out); err != nil {
+		return nil, err
+	}
+	return out.Entries, nil
+}
+
+// ConfigureJournal replaces the daily note settings; empty fields restore the default.
+func (c *Client) ConfigureJournal(ctx context.Context, cfg JournalConfig) (*JournalConfig, error) {
+	var out JournalConfig
+	if err := c.Call(ctx, "journal.configure", cfg, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) DeleteNote(ctx context.Context, id string) error {
+	return c.Call(ctx, "note.delete", idParams{ID: id}, nil)
+}
+
+// CreateBlock inserts a block at index; content is marshalled as the block's JSON content.
+func (c *Client) CreateBlock(ctx context.Context, noteID, blockType string, index int, content any) (*Block, error) {
+	var out Block
+	err := c.Call(ctx, "block.create", map[string]any{
+		"note_id": noteID,
+		"type":    blockType,
+		"index":   index,
+		"content": content,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) UpdateBlock(ctx context.Context, noteID, blockID, blockType string, content any) (*Block, error) {
+	var out Block
+	err := c.Call(ctx, "block.update", map[string]any{
+		"note_id":  noteID,
+		"block_id": blockID,
+		"type":     blockType,
+		"content":  content,
+	}, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PlaceBlockParams positions a moved or copied block. TargetNoteID defaults to
+// the block's own note; with neither neighbour the block goes to the end.
+type PlaceBlockParams struct {
+	NoteID       string `json:"note_id"`
+	BlockID      string `json:"block_id"`
+	TargetNoteID string `json:"target_note_id,omitempty"`
+	AfterID      string `json:"after_id,omitempty"`
+	BeforeID     string `json:"before_id,omitempty"`
+}
+
+func (c *Client) MoveBlock(ctx context.Context, params PlaceBlockParams) (*Block, error) {
+	var out Block
+	if err := c.Call(ctx, "block.move", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CopyBlock duplicates a block and returns the copy.
+func (c *Client) CopyBlock(ctx context.Context, params PlaceBlockParams) (*Block, error) {
+	var out Block
+	if err := c.Call(ctx, "block.copy", params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListChanges returns change log entries after since, and the sequence
+// number to pass as since next time.
+func (c *Client) ListChanges(ctx context.Context, since uint64) ([]ChangeDTO, uint64, error) {
+	var out struct {
+		Changes []ChangeDTO `json:"changes"`
+		LastSeq uint64      `json:"last_seq"`
+	}
+	if err := c.Call(ctx, "changes.list", map[string]uint64{"since": since}, &out); err != nil {
+		return nil, 0, err
+	}
+	return out.Changes, out.LastSeq, nil
+}
+
+func (c *Client) DeleteBlock(ctx context.Context, noteID, blockID string) error {
+	return c.Call(ctx, "block.delete", map[string]string{
+		"note_id":  noteID,
+		"block_id": blockID,
+	}, nil)
+}
+
+// BlockReferences lists the embed blocks that transclude blockID.
+func (c *Client) BlockReferences(ctx context.Context, blockID string) ([]BlockReference, error) {
+	var out struct {
+		References []BlockReference `json:"references"`
+	}
+	if err := c.Call(ctx, "block.references", map[string]string{"block_id": blockID}, &out); err != nil {
+		return nil, err
+	}
+	return out.References, nil
+}
+
+// UploadImage stores data as an image asset and returns its URL.
+// CheckVault looks for orphaned, cyclic and corrupt rows; with repair set it
+// fixes what it finds.
+func (c *Client) CheckVault(ctx context.Context, repair bool) (*VaultCheck, error) {
+	var out VaultCheck
+	if err := c.Call(ctx, "vault.check", map[string]bool{"repair": repair}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CreateBackup takes a verified backup of the vault and its images.
+func (c *Client) CreateBackup(ctx context.Context) (*Backup, error) {
+	var out Backup
+	if err := c.Call(ctx, "backup.create", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ListBackups(ctx context.Context) ([]Backup, error) {
+	var out struct {
+		Backups []Backup `json:"backups"`
+	}
+	if err := c.Call(ctx, "backup.list", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Backups, nil
+}
+
+// RestoreBackup replaces the live vault with backup id. The result names the
+// backup taken of the vault just before, which undoes the restore.
+func (c *Client) RestoreBackup(ctx context.Context, id string) (*BackupRestore, error) {
+	var out BackupRestore
+	if err := c.Call(ctx, "backup.restore", map[string]string{"id": id}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) BackupConfig(ctx context.Context) (*BackupConfig, error) {
+	var out BackupConfig
+	if err := c.Call(ctx, "backup.config", nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) ConfigureBackups(ctx context.Context, cfg BackupConfig) (*BackupConfig, error) {
+	var out BackupConfig
+	if err := c.Call(ctx, "backup.configure", cfg, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *Client) UploadImage(ctx context.Context, filename string, data []byte) (string, error) {
+	var out struct {
+		URL string `json:"url"`
+	}
+	err := c.Call(ctx, "asset.uploadImage", map[string]string{
+		"filename":    filename,
+		"data_base64": base64.StdEncoding.EncodeToString(data),
+	}, &out)
+	return out.URL, err
+}
+
+// GetImage returns the file name and bytes behind a noteblock-image:/// URL,
+// fetching large files chunk by chunk.
+func (c *Client) GetImage(ctx context.Context, url string) (string, []byte, error) {
+	var (
+		name string
+		data []byte
+	)
+	for {
+		var out struct {
+			Filename   string `json:"filename"`
+			EOF        bool   `json:"eof"`
+			DataBase64 string `json:"data_base64"`
+		}
+		params := map[string]any{"url": url, "offset": len(data)}
+		if err := c.Call(ctx, "asset.get", params, &out); err != nil {
+			return "", nil, err
+		}
+		chunk, err := base64.StdEncoding.DecodeString(out.DataBase64)
+		if err != nil {
+			return "", nil, err
+		}
+		name = out.Filename
+		data = append(data, chunk...)
+		if out.EOF || len(chunk) == 0 {
+			return name, data, nil
+		}
+	}
+}
+
+func (c *Client) StatAsset(ctx context.Context, url string) (*AssetInfo, error) {
+	var out AssetInfo
+	if err := c.Call(ctx, "asset.stat", map[string]string{"url": url}, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAssets returns every uploaded asset with the blocks that reference it.
+func (c *Client) ListAssets(ctx context.Context) ([]AssetEntry, error) {
+	var out struct {
+		Assets []AssetEntry `json:"assets"`
+	}
+	if err := c.Call(ctx, "asset.list", nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Assets, nil
+}
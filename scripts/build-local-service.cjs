@@ -17,4 +17,20 @@ if (result.error) {
   process.exit(1)
 }
 
-process.exit(result.status ?? 1)
+if (result.status !== 0) {
+  process.exit(result.status ?? 1)
+}
+
+// keep the renderer's IPC typings in lockstep with the handlers just built
+const gen = spawnSync("go", ["run", "./cmd/ipcgen", "-out", path.join("..", "client", "src", "types", "local-ipc.generated.d.ts")], {
+  cwd: serviceDir,
+  stdio: "inherit",
+  shell: false,
+})
+
+if (gen.error) {
+  console.error("failed to generate local ipc typings:", gen.error.message)
+  process.exit(1)
+}
+
+process.exit(gen.status ?? 1)